@@ -0,0 +1,1415 @@
+package main
+
+import (
+  "bytes"
+  "context"
+  "encoding/json"
+  "errors"
+  "fmt"
+  "log/slog"
+  "net/http"
+  "strings"
+  "testing"
+  "time"
+
+  "github.com/golang-jwt/jwt/v5"
+  "go.opentelemetry.io/otel"
+  "go.opentelemetry.io/otel/propagation"
+  sdktrace "go.opentelemetry.io/otel/sdk/trace"
+  "go.opentelemetry.io/otel/sdk/trace/tracetest"
+  "sarah-project-backend/client"
+  "sarah-project-backend/config"
+  "sarah-project-backend/dto"
+  "sarah-project-backend/logging"
+  "sarah-project-backend/openapi"
+)
+
+func TestAdminLogin(t *testing.T) {
+  s := newTestServer(t)
+
+  tests := []struct {
+    name       string
+    method     string
+    body       string
+    wantStatus int
+    golden     string
+  }{
+    {
+      name:       "valid credentials",
+      method:     http.MethodPost,
+      body:       `{"username":"admin","password":"` + testAdminPassword + `"}`,
+      wantStatus: http.StatusOK,
+      golden:     "admin_login_ok",
+    },
+    {
+      name:       "extra UI fields are tolerated",
+      method:     http.MethodPost,
+      body:       `{"username":"admin","password":"` + testAdminPassword + `","remember_me":true}`,
+      wantStatus: http.StatusOK,
+    },
+    {
+      name:       "wrong password",
+      method:     http.MethodPost,
+      body:       `{"username":"admin","password":"nope"}`,
+      wantStatus: http.StatusUnauthorized,
+      golden:     "admin_login_invalid_credentials",
+    },
+    {
+      name:       "unknown user",
+      method:     http.MethodPost,
+      body:       `{"username":"ghost","password":"nope"}`,
+      wantStatus: http.StatusUnauthorized,
+    },
+    {
+      name:       "missing fields",
+      method:     http.MethodPost,
+      body:       `{}`,
+      wantStatus: http.StatusBadRequest,
+      golden:     "admin_login_missing_fields",
+    },
+    {
+      name:       "malformed JSON",
+      method:     http.MethodPost,
+      body:       `{"username":`,
+      wantStatus: http.StatusBadRequest,
+    },
+    {
+      name:       "wrong method",
+      method:     http.MethodGet,
+      wantStatus: http.StatusMethodNotAllowed,
+      golden:     "method_not_allowed",
+    },
+  }
+
+  for _, tc := range tests {
+    t.Run(tc.name, func(t *testing.T) {
+      resp := s.do(apiRequest{method: tc.method, path: "/admin/login", body: tc.body})
+      if resp.status != tc.wantStatus {
+        t.Fatalf("status = %d, want %d: %s", resp.status, tc.wantStatus, resp.body)
+      }
+      if tc.golden != "" {
+        assertGolden(t, tc.golden, resp.body)
+      }
+    })
+  }
+}
+
+func TestCreateOrder(t *testing.T) {
+  s := newTestServer(t)
+
+  tests := []struct {
+    name       string
+    merchant   *testMerchant
+    header     map[string]string
+    body       string
+    wantStatus int
+    golden     string
+  }{
+    {
+      name:       "valid order",
+      merchant:   &merchantAcme,
+      body:       validOrderBody("tx-valid"),
+      wantStatus: http.StatusCreated,
+      golden:     "create_order_ok",
+    },
+    {
+      name:       "missing api key",
+      body:       validOrderBody("tx-no-key"),
+      wantStatus: http.StatusUnauthorized,
+      golden:     "unauthorized",
+    },
+    {
+      name:       "api key of another merchant",
+      merchant:   &testMerchant{Name: merchantGlobex.Name, APIKey: merchantAcme.APIKey},
+      body:       validOrderBody("tx-wrong-merchant"),
+      wantStatus: http.StatusUnauthorized,
+    },
+    {
+      name:       "revoked api key",
+      merchant:   &merchantRevoked,
+      body:       validOrderBody("tx-revoked"),
+      wantStatus: http.StatusUnauthorized,
+    },
+    {
+      name:       "empty object reports every required field",
+      merchant:   &merchantAcme,
+      body:       `{}`,
+      wantStatus: http.StatusBadRequest,
+      golden:     "create_order_required_fields",
+    },
+    {
+      name:     "unsupported network and country",
+      merchant: &merchantAcme,
+      body: `{
+        "transaction_network": "Solana", "transaction_asset": "USDT", "txid": "tx", "amount": 10,
+        "email": "payer@example.com", "beneficiary_name": "Jane Doe", "bank_country": "Atlantis",
+        "bank_name": "Bank", "iban": "1234567", "swift": "000312345"
+      }`,
+      wantStatus: http.StatusBadRequest,
+      golden:     "create_order_unsupported",
+    },
+    {
+      name:     "invalid canadian routing",
+      merchant: &merchantAcme,
+      body: `{
+        "transaction_network": "TRON", "transaction_asset": "USDT", "txid": "tx", "amount": 10,
+        "email": "payer@example.com", "beneficiary_name": "Jane Doe", "bank_country": "Canada",
+        "bank_name": "Bank", "iban": "1234567", "swift": "12"
+      }`,
+      wantStatus: http.StatusBadRequest,
+      golden:     "create_order_invalid_routing",
+    },
+    {
+      name:       "unknown field",
+      merchant:   &merchantAcme,
+      body:       `{"transaction_network":"TRON","priority":"high"}`,
+      wantStatus: http.StatusBadRequest,
+      golden:     "create_order_unknown_field",
+    },
+    {
+      name:       "wrong content type",
+      merchant:   &merchantAcme,
+      header:     map[string]string{"Content-Type": "text/plain"},
+      body:       validOrderBody("tx-text"),
+      wantStatus: http.StatusUnsupportedMediaType,
+    },
+    {
+      name:       "missing content type",
+      merchant:   &merchantAcme,
+      header:     map[string]string{"Content-Type": ""},
+      body:       validOrderBody("tx-no-type"),
+      wantStatus: http.StatusUnsupportedMediaType,
+      golden:     "unsupported_media_type",
+    },
+  }
+
+  for _, tc := range tests {
+    t.Run(tc.name, func(t *testing.T) {
+      resp := s.do(apiRequest{
+        method:   http.MethodPost,
+        path:     "/customer/orders",
+        body:     tc.body,
+        merchant: tc.merchant,
+        header:   tc.header,
+      })
+      if resp.status != tc.wantStatus {
+        t.Fatalf("status = %d, want %d: %s", resp.status, tc.wantStatus, resp.body)
+      }
+      if tc.golden != "" {
+        assertGolden(t, tc.golden, resp.body)
+      }
+    })
+  }
+
+  t.Run("created order is readable by its merchant only", func(t *testing.T) {
+    id := s.createOrder(merchantAcme, "tx-readback")
+    path := fmt.Sprintf("/customer/orders/%d", id)
+
+    resp := s.do(apiRequest{method: http.MethodGet, path: path, merchant: &merchantAcme})
+    if resp.status != http.StatusOK {
+      t.Fatalf("owner: status = %d: %s", resp.status, resp.body)
+    }
+    assertGolden(t, "customer_order_detail", resp.body)
+
+    resp = s.do(apiRequest{method: http.MethodGet, path: path, merchant: &merchantGlobex})
+    if resp.status != http.StatusNotFound {
+      t.Fatalf("other merchant: status = %d, want 404: %s", resp.status, resp.body)
+    }
+  })
+}
+
+func TestCreateOrderIdempotency(t *testing.T) {
+  s := newTestServer(t)
+
+  create := func(m testMerchant, key, txid string) (apiResponse, int64) {
+    t.Helper()
+    resp := s.do(apiRequest{
+      method:   http.MethodPost,
+      path:     "/customer/orders",
+      body:     validOrderBody(txid),
+      merchant: &m,
+      header:   map[string]string{"Idempotency-Key": key},
+    })
+    var out struct {
+      ID int64 `json:"id"`
+    }
+    json.Unmarshal(resp.body, &out)
+    return resp, out.ID
+  }
+
+  first, id := create(merchantAcme, "order-1", "tx-idem")
+  if first.status != http.StatusCreated || first.header.Get("Idempotent-Replayed") != "" {
+    t.Fatalf("first: status = %d, replayed %q: %s", first.status, first.header.Get("Idempotent-Replayed"), first.body)
+  }
+
+  retry, retryID := create(merchantAcme, "order-1", "tx-idem")
+  if retry.status != http.StatusCreated || retryID != id || retry.header.Get("Idempotent-Replayed") != "true" {
+    t.Fatalf("retry: status = %d, id = %d (want %d), replayed %q", retry.status, retryID, id, retry.header.Get("Idempotent-Replayed"))
+  }
+
+  // Keys are scoped to the merchant.
+  if resp, otherID := create(merchantGlobex, "order-1", "tx-idem"); resp.status != http.StatusCreated || otherID == id {
+    t.Fatalf("other merchant: status = %d, id = %d", resp.status, otherID)
+  }
+
+  resp, _ := create(merchantAcme, "order-1", "tx-different")
+  if resp.status != http.StatusConflict {
+    t.Fatalf("reused key: status = %d, want 409: %s", resp.status, resp.body)
+  }
+
+  resp, _ = create(merchantAcme, strings.Repeat("k", 129), "tx-long-key")
+  if resp.status != http.StatusBadRequest {
+    t.Fatalf("long key: status = %d, want 400: %s", resp.status, resp.body)
+  }
+
+  total, _, err := s.store.ListMerchantOrders(context.Background(), merchantAcme.Name, 1, 10)
+  if err != nil || total != 1 {
+    t.Fatalf("acme orders = %d, %v; want 1", total, err)
+  }
+}
+
+// TestClient runs the Go SDK against the real handlers.
+func TestClient(t *testing.T) {
+  s := newTestServer(t)
+  ctx := context.Background()
+  c, err := client.New(client.Config{BaseURL: s.url, Merchant: merchantAcme.Name, APIKey: merchantAcme.APIKey})
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  var req client.CreateOrderRequest
+  if err := json.Unmarshal([]byte(validOrderBody("tx-sdk")), &req); err != nil {
+    t.Fatal(err)
+  }
+  req.IdempotencyKey = "sdk-1"
+  created, err := c.CreateOrder(ctx, req)
+  if err != nil {
+    t.Fatal(err)
+  }
+  replayed, err := c.CreateOrder(ctx, req)
+  if err != nil || !replayed.Replayed || replayed.ID != created.ID {
+    t.Fatalf("replay = %+v, %v; want order %d replayed", replayed, err, created.ID)
+  }
+
+  order, err := c.GetOrder(ctx, created.ID)
+  if err != nil {
+    t.Fatal(err)
+  }
+  if order.TXID != "tx-sdk" || order.Status != client.StatusProcessing || order.ReferenceNote == nil {
+    t.Fatalf("order = %+v", order)
+  }
+  if _, err := c.GetOrder(ctx, created.ID+100); !client.IsNotFound(err) {
+    t.Fatalf("missing order: err = %v", err)
+  }
+
+  for i := 0; i < 4; i++ {
+    s.createOrder(merchantAcme, fmt.Sprintf("tx-sdk-%d", i))
+  }
+  var count int
+  for it := c.Orders(ctx, 2); it.Next(); {
+    count++
+  }
+  if count != 5 {
+    t.Fatalf("iterated %d orders, want 5", count)
+  }
+
+  bad, err := client.New(client.Config{BaseURL: s.url, Merchant: merchantAcme.Name, APIKey: "wrong"})
+  if err != nil {
+    t.Fatal(err)
+  }
+  var apiErr *client.Error
+  if _, err := bad.ListOrders(ctx, client.ListOptions{}); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized || apiErr.RequestID == "" {
+    t.Fatalf("wrong key: err = %v", err)
+  }
+}
+
+func TestListCustomerOrdersPagination(t *testing.T) {
+  s := newTestServer(t)
+  for i := 1; i <= 5; i++ {
+    s.createOrder(merchantAcme, fmt.Sprintf("acme-%d", i))
+  }
+  for i := 1; i <= 2; i++ {
+    s.createOrder(merchantGlobex, fmt.Sprintf("globex-%d", i))
+  }
+
+  tests := []struct {
+    name         string
+    merchant     testMerchant
+    query        string
+    wantStatus   int
+    wantTotal    int64
+    wantPage     int
+    wantPageSize int
+    wantTXIDs    []string
+    golden       string
+  }{
+    {
+      name:         "defaults",
+      merchant:     merchantAcme,
+      wantStatus:   http.StatusOK,
+      wantTotal:    5,
+      wantPage:     1,
+      wantPageSize: 20,
+      wantTXIDs:    []string{"acme-5", "acme-4", "acme-3", "acme-2", "acme-1"},
+    },
+    {
+      name:         "first page",
+      merchant:     merchantAcme,
+      query:        "?page=1&page_size=2",
+      wantStatus:   http.StatusOK,
+      wantTotal:    5,
+      wantPage:     1,
+      wantPageSize: 2,
+      wantTXIDs:    []string{"acme-5", "acme-4"},
+      golden:       "customer_orders_page_1",
+    },
+    {
+      name:         "last partial page",
+      merchant:     merchantAcme,
+      query:        "?page=3&page_size=2",
+      wantStatus:   http.StatusOK,
+      wantTotal:    5,
+      wantPage:     3,
+      wantPageSize: 2,
+      wantTXIDs:    []string{"acme-1"},
+    },
+    {
+      name:         "past the end",
+      merchant:     merchantAcme,
+      query:        "?page=4&page_size=2",
+      wantStatus:   http.StatusOK,
+      wantTotal:    5,
+      wantPage:     4,
+      wantPageSize: 2,
+      wantTXIDs:    []string{},
+    },
+    {
+      name:         "page size is capped",
+      merchant:     merchantAcme,
+      query:        "?page_size=500",
+      wantStatus:   http.StatusOK,
+      wantTotal:    5,
+      wantPage:     1,
+      wantPageSize: 100,
+      wantTXIDs:    []string{"acme-5", "acme-4", "acme-3", "acme-2", "acme-1"},
+    },
+    {
+      name:         "merchants only see their own orders",
+      merchant:     merchantGlobex,
+      wantStatus:   http.StatusOK,
+      wantTotal:    2,
+      wantPage:     1,
+      wantPageSize: 20,
+      wantTXIDs:    []string{"globex-2", "globex-1"},
+    },
+    {
+      name:       "invalid page",
+      merchant:   merchantAcme,
+      query:      "?page=0",
+      wantStatus: http.StatusBadRequest,
+      golden:     "customer_orders_invalid_page",
+    },
+    {
+      name:       "non-numeric page size",
+      merchant:   merchantAcme,
+      query:      "?page_size=lots",
+      wantStatus: http.StatusBadRequest,
+    },
+  }
+
+  for _, tc := range tests {
+    t.Run(tc.name, func(t *testing.T) {
+      m := tc.merchant
+      resp := s.do(apiRequest{method: http.MethodGet, path: "/customer/orders" + tc.query, merchant: &m})
+      if resp.status != tc.wantStatus {
+        t.Fatalf("status = %d, want %d: %s", resp.status, tc.wantStatus, resp.body)
+      }
+      if tc.golden != "" {
+        assertGolden(t, tc.golden, resp.body)
+      }
+      if resp.status != http.StatusOK {
+        return
+      }
+
+      var out struct {
+        Total    int64 `json:"total"`
+        Page     int   `json:"page"`
+        PageSize int   `json:"page_size"`
+        Orders   []struct {
+          TXID string `json:"txid"`
+        } `json:"orders"`
+      }
+      if err := json.Unmarshal(resp.body, &out); err != nil {
+        t.Fatal(err)
+      }
+      if out.Total != tc.wantTotal || out.Page != tc.wantPage || out.PageSize != tc.wantPageSize {
+        t.Errorf("total/page/page_size = %d/%d/%d, want %d/%d/%d",
+          out.Total, out.Page, out.PageSize, tc.wantTotal, tc.wantPage, tc.wantPageSize)
+      }
+      got := make([]string, 0, len(out.Orders))
+      for _, o := range out.Orders {
+        got = append(got, o.TXID)
+      }
+      if fmt.Sprint(got) != fmt.Sprint(tc.wantTXIDs) {
+        t.Errorf("txids = %v, want %v", got, tc.wantTXIDs)
+      }
+    })
+  }
+}
+
+func TestAdminUpdateOrderStatus(t *testing.T) {
+  s := newTestServer(t)
+  token := s.adminToken()
+  id := s.createOrder(merchantAcme, "tx-status")
+
+  tests := []struct {
+    name       string
+    body       string
+    header     map[string]string
+    wantStatus int
+    wantStored string
+    golden     string
+  }{
+    {
+      name:       "move to funds received",
+      body:       fmt.Sprintf(`{"id":%d,"status":"Funds Received"}`, id),
+      wantStatus: http.StatusOK,
+      wantStored: dto.StatusFundsReceived,
+      golden:     "admin_update_status_ok",
+    },
+    {
+      name:       "legacy spelling is accepted",
+      body:       fmt.Sprintf(`{"id":%d,"status":"Summitted"}`, id),
+      wantStatus: http.StatusOK,
+      wantStored: dto.StatusSubmitted,
+    },
+    {
+      name:       "legacy clients see the legacy spelling",
+      body:       fmt.Sprintf(`{"id":%d,"status":"Submitted"}`, id),
+      header:     map[string]string{"X-API-Version": "1"},
+      wantStatus: http.StatusOK,
+      wantStored: dto.StatusSubmitted,
+      golden:     "admin_update_status_legacy",
+    },
+    {
+      name:       "clients without a version see the current spelling",
+      body:       fmt.Sprintf(`{"id":%d,"status":"Submitted"}`, id),
+      wantStatus: http.StatusOK,
+      wantStored: dto.StatusSubmitted,
+      golden:     "admin_update_status_current",
+    },
+    {
+      name:       "unknown version",
+      body:       fmt.Sprintf(`{"id":%d,"status":"Paid"}`, id),
+      header:     map[string]string{"X-API-Version": "3"},
+      wantStatus: http.StatusBadRequest,
+      wantStored: dto.StatusSubmitted,
+      golden:     "unsupported_api_version",
+    },
+    {
+      name:       "unknown status",
+      body:       fmt.Sprintf(`{"id":%d,"status":"Shipped"}`, id),
+      wantStatus: http.StatusBadRequest,
+      wantStored: dto.StatusSubmitted,
+      golden:     "admin_update_status_invalid",
+    },
+    {
+      name:       "missing order",
+      body:       `{"id":999999,"status":"Paid"}`,
+      wantStatus: http.StatusNotFound,
+      wantStored: dto.StatusSubmitted,
+      golden:     "admin_update_status_not_found",
+    },
+  }
+
+  for _, tc := range tests {
+    t.Run(tc.name, func(t *testing.T) {
+      resp := s.do(apiRequest{
+        method: http.MethodPost,
+        path:   "/admin/order/status",
+        body:   tc.body,
+        token:  token,
+        header: tc.header,
+      })
+      if resp.status != tc.wantStatus {
+        t.Fatalf("status = %d, want %d: %s", resp.status, tc.wantStatus, resp.body)
+      }
+      if tc.golden != "" {
+        assertGolden(t, tc.golden, resp.body)
+      }
+
+      resp = s.do(apiRequest{method: http.MethodGet, path: fmt.Sprintf("/admin/order?id=%d", id), token: token})
+      var out struct {
+        Order struct {
+          Status string `json:"status"`
+        } `json:"order"`
+      }
+      if err := json.Unmarshal(resp.body, &out); err != nil {
+        t.Fatal(err)
+      }
+      if out.Order.Status != tc.wantStored {
+        t.Errorf("stored status = %q, want %q", out.Order.Status, tc.wantStored)
+      }
+    })
+  }
+}
+
+// withPayouts configures the originator details payout files need.
+func withPayouts(cfg *config.Config) {
+  cfg.Payout = config.Payout{
+    OriginatorName:            "Sarah Payouts Ltd",
+    NACHAImmediateDestination: "021000021",
+    NACHAImmediateOrigin:      "1234567890",
+    NACHACompanyID:            "1234567890",
+    NACHAODFIRouting:          "021000021",
+    CPA005OriginatorID:        "SARAHPAY01",
+    CPA005DataCentre:          "00120",
+    CPA005ReturnInstitution:   "003-12345",
+    CPA005ReturnAccount:       "1234567",
+  }
+}
+
+func TestAdminPayouts(t *testing.T) {
+  s := newTestServer(t, withPayouts)
+  token := s.adminToken()
+
+  createPayout := func(body string) apiResponse {
+    t.Helper()
+    return s.do(apiRequest{method: http.MethodPost, path: "/admin/payouts", body: body, token: token})
+  }
+  fundedOrder := func(txid string) int64 {
+    t.Helper()
+    id := s.createOrder(merchantAcme, txid)
+    s.setStatus(id, dto.StatusFundsReceived)
+    return id
+  }
+
+  t.Run("batch submits its orders", func(t *testing.T) {
+    first, second := fundedOrder("tx-payout-1"), fundedOrder("tx-payout-2")
+    resp := createPayout(fmt.Sprintf(`{"order_ids":[%d,%d],"format":"cpa005"}`, first, second))
+    if resp.status != http.StatusCreated {
+      t.Fatalf("status = %d: %s", resp.status, resp.body)
+    }
+    var batch struct {
+      BatchID     string  `json:"batch_id"`
+      FileName    string  `json:"file_name"`
+      OrderCount  int     `json:"order_count"`
+      TotalAmount float64 `json:"total_amount"`
+      Content     string  `json:"content"`
+    }
+    if err := json.Unmarshal(resp.body, &batch); err != nil {
+      t.Fatal(err)
+    }
+    if batch.BatchID == "" || batch.OrderCount != 2 || batch.TotalAmount != 2501 || batch.FileName != batch.BatchID+".cpa" {
+      t.Fatalf("batch = %+v", batch)
+    }
+
+    for _, id := range []int64{first, second} {
+      order, err := s.store.GetOrder(context.Background(), id)
+      if err != nil || order.Status != dto.StatusSubmitted {
+        t.Errorf("order %d: status %q, %v; want Submitted", id, order.Status, err)
+      }
+      var batchID string
+      if err := s.db.QueryRow(`SELECT batch_id FROM payout_batch_orders WHERE order_id = ?`, id).Scan(&batchID); err != nil || batchID != batch.BatchID {
+        t.Errorf("order %d: batch %q, %v; want %q", id, batchID, err, batch.BatchID)
+      }
+    }
+
+    resp = s.do(apiRequest{method: http.MethodGet, path: "/admin/payouts/" + batch.BatchID + "/file", token: token})
+    if resp.status != http.StatusOK || string(resp.body) != batch.Content {
+      t.Fatalf("download: status = %d, body matches = %v", resp.status, string(resp.body) == batch.Content)
+    }
+    if got := resp.header.Get("Content-Disposition"); got != `attachment; filename="`+batch.FileName+`"` {
+      t.Errorf("Content-Disposition = %q", got)
+    }
+
+    resp = s.do(apiRequest{method: http.MethodGet, path: "/admin/payouts/PBUNKNOWN/file", token: token})
+    if resp.status != http.StatusNotFound {
+      t.Errorf("unknown batch: status = %d, want 404", resp.status)
+    }
+  })
+
+  t.Run("order not in Funds Received", func(t *testing.T) {
+    funded := fundedOrder("tx-payout-funded")
+    processing := s.createOrder(merchantAcme, "tx-payout-processing")
+    resp := createPayout(fmt.Sprintf(`{"order_ids":[%d,%d],"format":"cpa005"}`, funded, processing))
+    if resp.status != http.StatusConflict {
+      t.Fatalf("status = %d, want 409: %s", resp.status, resp.body)
+    }
+    if order, _ := s.store.GetOrder(context.Background(), funded); order.Status != dto.StatusFundsReceived {
+      t.Errorf("funded order moved to %q by a failed batch", order.Status)
+    }
+  })
+
+  t.Run("bad routing number", func(t *testing.T) {
+    // A BIC is a valid swift for US orders but NACHA needs an ABA number.
+    body := strings.NewReplacer(`"Canada"`, `"United States"`, `"000312345"`, `"CHASUS33"`).Replace(validOrderBody("tx-payout-us"))
+    resp := s.do(apiRequest{method: http.MethodPost, path: "/customer/orders", body: body, merchant: &merchantAcme})
+    if resp.status != http.StatusCreated {
+      t.Fatalf("create US order: status = %d: %s", resp.status, resp.body)
+    }
+    var created struct {
+      ID int64 `json:"id"`
+    }
+    json.Unmarshal(resp.body, &created)
+    s.setStatus(created.ID, dto.StatusFundsReceived)
+
+    resp = createPayout(fmt.Sprintf(`{"order_ids":[%d],"format":"nacha"}`, created.ID))
+    if resp.status != http.StatusUnprocessableEntity || !strings.Contains(string(resp.body), "ABA routing number") {
+      t.Fatalf("status = %d, want 422: %s", resp.status, resp.body)
+    }
+    if order, _ := s.store.GetOrder(context.Background(), created.ID); order.Status != dto.StatusFundsReceived {
+      t.Errorf("order moved to %q by a failed batch", order.Status)
+    }
+  })
+}
+
+func TestRouting(t *testing.T) {
+  s := newTestServer(t)
+  token := s.adminToken()
+  id := s.createOrder(merchantAcme, "tx-routing")
+
+  t.Run("status is updated through the order resource", func(t *testing.T) {
+    resp := s.do(apiRequest{method: http.MethodPatch, path: fmt.Sprintf("/admin/orders/%d/status", id), body: `{"status":"Paid"}`, token: token})
+    if resp.status != http.StatusOK {
+      t.Fatalf("status = %d: %s", resp.status, resp.body)
+    }
+    resp = s.do(apiRequest{method: http.MethodGet, path: fmt.Sprintf("/admin/orders/%d", id), token: token})
+    if resp.status != http.StatusOK || !strings.Contains(string(resp.body), `"status":"Paid"`) {
+      t.Fatalf("detail = %d: %s", resp.status, resp.body)
+    }
+    if resp.header.Get("Deprecation") != "" {
+      t.Errorf("new route is marked deprecated")
+    }
+  })
+
+  t.Run("invalid path id", func(t *testing.T) {
+    resp := s.do(apiRequest{method: http.MethodGet, path: "/admin/orders/abc", token: token})
+    if resp.status != http.StatusBadRequest {
+      t.Fatalf("status = %d, want 400: %s", resp.status, resp.body)
+    }
+  })
+
+  t.Run("unsupported method", func(t *testing.T) {
+    resp := s.do(apiRequest{method: http.MethodDelete, path: fmt.Sprintf("/admin/orders/%d", id), token: token})
+    if resp.status != http.StatusMethodNotAllowed {
+      t.Fatalf("status = %d, want 405: %s", resp.status, resp.body)
+    }
+    if got := resp.header.Get("Allow"); got != "GET, HEAD, OPTIONS" {
+      t.Errorf("Allow = %q", got)
+    }
+    assertGolden(t, "method_not_allowed", resp.body)
+  })
+
+  t.Run("options lists methods", func(t *testing.T) {
+    resp := s.do(apiRequest{method: http.MethodOptions, path: "/customer/orders"})
+    if resp.status != http.StatusNoContent {
+      t.Fatalf("status = %d, want 204", resp.status)
+    }
+    if got := resp.header.Get("Allow"); got != "GET, HEAD, POST, OPTIONS" {
+      t.Errorf("Allow = %q", got)
+    }
+  })
+
+  t.Run("head is served by get routes", func(t *testing.T) {
+    resp := s.do(apiRequest{method: http.MethodHead, path: "/health"})
+    if resp.status != http.StatusOK {
+      t.Fatalf("status = %d, want 200", resp.status)
+    }
+  })
+}
+
+// sameBody compares response bodies, ignoring volatile JSON fields such as
+// request_id.
+func sameBody(a, b []byte) bool {
+  na, errA := normalizeJSON(a)
+  nb, errB := normalizeJSON(b)
+  if errA != nil || errB != nil {
+    return bytes.Equal(a, b)
+  }
+  return bytes.Equal(na, nb)
+}
+
+// TestLegacyRoutes runs each deprecated alias next to the route that
+// replaced it. Both must behave the same; only the alias carries the
+// deprecation headers.
+func TestLegacyRoutes(t *testing.T) {
+  s := newTestServer(t, withPayouts)
+  token := s.adminToken()
+  id := s.createOrder(merchantAcme, "tx-legacy")
+  funded := s.createOrder(merchantAcme, "tx-legacy-payout")
+  s.setStatus(funded, dto.StatusFundsReceived)
+
+  resp := s.do(apiRequest{method: http.MethodPost, path: "/admin/payouts", body: fmt.Sprintf(`{"order_ids":[%d],"format":"cpa005"}`, funded), token: token})
+  var batch struct {
+    BatchID string `json:"batch_id"`
+  }
+  if resp.status != http.StatusCreated || json.Unmarshal(resp.body, &batch) != nil {
+    t.Fatalf("create payout batch: status = %d: %s", resp.status, resp.body)
+  }
+
+  idempotent := map[string]string{"Idempotency-Key": "legacy-create"}
+  tests := []struct {
+    name      string
+    legacy    apiRequest
+    successor apiRequest
+    link      string
+    // golden is set when the alias kept its own response shape.
+    golden string
+  }{
+    {
+      name:      "ready for processing",
+      legacy:    apiRequest{method: http.MethodGet, path: "/admin/ready-processing?page_size=10", token: token},
+      successor: apiRequest{method: http.MethodGet, path: "/admin/orders?status=Processing&page_size=10", token: token},
+      link:      "/admin/orders?status=Processing",
+      golden:    "legacy_ready_processing",
+    },
+    {
+      name:      "recent orders",
+      legacy:    apiRequest{method: http.MethodGet, path: "/admin/recent-orders?page=1&page_size=3", token: token},
+      successor: apiRequest{method: http.MethodGet, path: "/admin/orders?page=1&page_size=3", token: token},
+      link:      "/admin/orders",
+    },
+    {
+      name:      "admin order detail",
+      legacy:    apiRequest{method: http.MethodGet, path: fmt.Sprintf("/admin/order?id=%d", id), token: token},
+      successor: apiRequest{method: http.MethodGet, path: fmt.Sprintf("/admin/orders/%d", id), token: token},
+      link:      fmt.Sprintf("/admin/orders/%d", id),
+    },
+    {
+      name:      "admin order status",
+      legacy:    apiRequest{method: http.MethodPost, path: "/admin/order/status", body: fmt.Sprintf(`{"id":%d,"status":"Funds Received"}`, id), token: token},
+      successor: apiRequest{method: http.MethodPatch, path: fmt.Sprintf("/admin/orders/%d/status", id), body: `{"status":"Funds Received"}`, token: token},
+      link:      "/admin/orders/{id}/status",
+    },
+    {
+      name:      "payout file",
+      legacy:    apiRequest{method: http.MethodGet, path: "/admin/payouts/file?batch_id=" + batch.BatchID, token: token},
+      successor: apiRequest{method: http.MethodGet, path: "/admin/payouts/" + batch.BatchID + "/file", token: token},
+      link:      "/admin/payouts/" + batch.BatchID + "/file",
+    },
+    {
+      // The successor replays the order the alias created.
+      name:      "create order",
+      legacy:    apiRequest{method: http.MethodPost, path: "/customer/createOrder", body: validOrderBody("tx-legacy-create"), merchant: &merchantAcme, header: idempotent},
+      successor: apiRequest{method: http.MethodPost, path: "/customer/orders", body: validOrderBody("tx-legacy-create"), merchant: &merchantAcme, header: idempotent},
+      link:      "/customer/orders",
+    },
+    {
+      name:      "create order validation",
+      legacy:    apiRequest{method: http.MethodPost, path: "/customer/createOrder", body: `{"txid":"tx-invalid"}`, merchant: &merchantAcme},
+      successor: apiRequest{method: http.MethodPost, path: "/customer/orders", body: `{"txid":"tx-invalid"}`, merchant: &merchantAcme},
+      link:      "/customer/orders",
+    },
+    {
+      name:      "customer order detail",
+      legacy:    apiRequest{method: http.MethodGet, path: fmt.Sprintf("/customer/order?id=%d", id), merchant: &merchantAcme},
+      successor: apiRequest{method: http.MethodGet, path: fmt.Sprintf("/customer/orders/%d", id), merchant: &merchantAcme},
+      link:      fmt.Sprintf("/customer/orders/%d", id),
+    },
+    {
+      name:      "customer order of another merchant",
+      legacy:    apiRequest{method: http.MethodGet, path: fmt.Sprintf("/customer/order?id=%d", id), merchant: &merchantGlobex},
+      successor: apiRequest{method: http.MethodGet, path: fmt.Sprintf("/customer/orders/%d", id), merchant: &merchantGlobex},
+      link:      fmt.Sprintf("/customer/orders/%d", id),
+    },
+  }
+
+  for _, tc := range tests {
+    t.Run(tc.name, func(t *testing.T) {
+      legacy := s.do(tc.legacy)
+      successor := s.do(tc.successor)
+
+      if legacy.status != successor.status {
+        t.Errorf("status = %d, successor %d", legacy.status, successor.status)
+      }
+      if tc.golden != "" {
+        assertGolden(t, tc.golden, legacy.body)
+      } else if !sameBody(legacy.body, successor.body) {
+        t.Errorf("body differs from successor:\n%s\n%s", legacy.body, successor.body)
+      }
+
+      if got := legacy.header.Get("Deprecation"); got != "true" {
+        t.Errorf("Deprecation = %q, want true", got)
+      }
+      if got, want := legacy.header.Get("Link"), "<"+tc.link+`>; rel="successor-version"`; got != want {
+        t.Errorf("Link = %q, want %q", got, want)
+      }
+      if successor.header.Get("Deprecation") != "" || successor.header.Get("Link") != "" {
+        t.Errorf("successor is marked deprecated")
+      }
+    })
+  }
+}
+
+func TestAdminDashboard(t *testing.T) {
+  s := newTestServer(t)
+  token := s.adminToken()
+
+  statuses := []string{
+    dto.StatusProcessing,
+    dto.StatusProcessing,
+    dto.StatusFundsReceived,
+    dto.StatusFailed,
+    dto.StatusSubmitted,
+    dto.StatusPaid,
+  }
+  now := time.Now()
+  for i, status := range statuses {
+    id := s.createOrder(merchantAcme, fmt.Sprintf("dash-%d", i+1))
+    if status != dto.StatusProcessing {
+      s.setStatus(id, status)
+    }
+    s.setTimestamps(id, now.Add(time.Duration(i-len(statuses)+1)*time.Second))
+  }
+
+  tests := []struct {
+    name    string
+    path    string
+    version string
+    golden  string
+  }{
+    {"stats", "/admin/stats", "", "admin_stats"},
+    {"ready for processing", "/admin/ready-processing?page_size=10", "", "admin_ready_processing"},
+    {"recent orders", "/admin/recent-orders?page=1&page_size=3", "", "admin_recent_orders"},
+    {"orders", "/admin/orders?page=1&page_size=3", "", "admin_recent_orders"},
+    {"orders for version 2", "/admin/orders?page=1&page_size=3", "2", "admin_recent_orders"},
+    {"orders for legacy clients", "/admin/orders?page=1&page_size=3", "1", "admin_recent_orders_legacy"},
+    {"orders by status", "/admin/orders?status=Processing&page_size=10", "", "admin_orders_processing"},
+    {"catalogue", "/admin/catalogue", "", "admin_catalogue"},
+  }
+
+  for _, tc := range tests {
+    t.Run(tc.name, func(t *testing.T) {
+      var header map[string]string
+      if tc.version != "" {
+        header = map[string]string{"X-API-Version": tc.version}
+      }
+      resp := s.do(apiRequest{method: http.MethodGet, path: tc.path, token: token, header: header})
+      if resp.status != http.StatusOK {
+        t.Fatalf("status = %d: %s", resp.status, resp.body)
+      }
+      assertGolden(t, tc.golden, resp.body)
+    })
+  }
+
+  t.Run("orders report when they were received", func(t *testing.T) {
+    resp := s.do(apiRequest{method: http.MethodGet, path: "/admin/orders?page_size=10", token: token})
+    var out struct {
+      Items []struct {
+        OrderID      int64     `json:"order_id"`
+        TimeReceived time.Time `json:"time_received"`
+      } `json:"items"`
+    }
+    if err := json.Unmarshal(resp.body, &out); err != nil || len(out.Items) != len(statuses) {
+      t.Fatalf("status = %d, err = %v: %s", resp.status, err, resp.body)
+    }
+    for _, item := range out.Items {
+      order, err := s.store.GetOrder(context.Background(), item.OrderID)
+      if err != nil {
+        t.Fatal(err)
+      }
+      if item.TimeReceived.IsZero() || !item.TimeReceived.Equal(order.CreatedAt) {
+        t.Errorf("order %d: time_received = %v, want %v", item.OrderID, item.TimeReceived, order.CreatedAt)
+      }
+    }
+  })
+}
+
+func TestAdminAuthorization(t *testing.T) {
+  s := newTestServer(t)
+
+  foreign := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+    "sub":  "1",
+    "role": "admin",
+    "exp":  time.Now().Add(time.Hour).Unix(),
+  })
+  foreignToken, err := foreign.SignedString([]byte("some-other-secret"))
+  if err != nil {
+    t.Fatal(err)
+  }
+  customer := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+    "sub":  "1",
+    "role": "customer",
+    "exp":  time.Now().Add(time.Hour).Unix(),
+  })
+  customerToken, err := customer.SignedString([]byte(testJWTSecret))
+  if err != nil {
+    t.Fatal(err)
+  }
+  expired := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+    "sub":  "1",
+    "role": "admin",
+    "exp":  time.Now().Add(-time.Minute).Unix(),
+  })
+  expiredToken, err := expired.SignedString([]byte(testJWTSecret))
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  endpoints := []struct {
+    method string
+    path   string
+    body   string
+  }{
+    {http.MethodGet, "/admin/stats", ""},
+    {http.MethodGet, "/admin/orders", ""},
+    {http.MethodGet, "/admin/orders/1", ""},
+    {http.MethodPatch, "/admin/orders/1/status", `{"status":"Paid"}`},
+    {http.MethodPost, "/admin/payouts", `{"order_ids":[1],"format":"nacha"}`},
+    {http.MethodGet, "/admin/payouts/PB1/file", ""},
+    {http.MethodGet, "/admin/ready-processing", ""},
+    {http.MethodGet, "/admin/recent-orders", ""},
+    {http.MethodGet, "/admin/order?id=1", ""},
+    {http.MethodPost, "/admin/order/status", `{"id":1,"status":"Paid"}`},
+    {http.MethodGet, "/admin/payouts/file?batch_id=PB1", ""},
+    {http.MethodGet, "/admin/catalogue", ""},
+    {http.MethodPost, "/admin/catalogue/assets", `{"network":"TRON","asset":"USDT","decimals":6}`},
+    {http.MethodPost, "/admin/catalogue/countries", `{"name":"Canada","iso_code":"CA","currency":"CAD"}`},
+    {http.MethodDelete, "/admin/merchants/acme/api-key", ""},
+  }
+  // Missing or bad credentials are 401; a valid token whose role lacks the
+  // permission is 403.
+  credentials := []struct {
+    name       string
+    token      string
+    merchant   *testMerchant
+    wantStatus int
+    challenge  string
+    golden     string
+  }{
+    {name: "no credentials", wantStatus: http.StatusUnauthorized, challenge: `Bearer realm="admin"`, golden: "unauthorized"},
+    {name: "malformed token", token: "not-a-jwt", wantStatus: http.StatusUnauthorized, challenge: `Bearer realm="admin", error="invalid_token"`, golden: "unauthorized"},
+    {name: "token signed with another secret", token: foreignToken, wantStatus: http.StatusUnauthorized, challenge: `Bearer realm="admin", error="invalid_token"`, golden: "unauthorized"},
+    {name: "expired token", token: expiredToken, wantStatus: http.StatusUnauthorized, challenge: `Bearer realm="admin", error="invalid_token"`, golden: "unauthorized"},
+    {name: "non-admin role", token: customerToken, wantStatus: http.StatusForbidden, challenge: `Bearer realm="admin", error="insufficient_scope"`, golden: "forbidden"},
+    {name: "merchant api key", merchant: &merchantAcme, wantStatus: http.StatusUnauthorized, challenge: `Bearer realm="admin"`, golden: "unauthorized"},
+  }
+
+  for _, ep := range endpoints {
+    for _, cred := range credentials {
+      t.Run(ep.method+" "+ep.path+"/"+cred.name, func(t *testing.T) {
+        resp := s.do(apiRequest{method: ep.method, path: ep.path, body: ep.body, token: cred.token, merchant: cred.merchant})
+        if resp.status != cred.wantStatus {
+          t.Fatalf("status = %d, want %d: %s", resp.status, cred.wantStatus, resp.body)
+        }
+        if got := resp.header.Get("WWW-Authenticate"); got != cred.challenge {
+          t.Errorf("WWW-Authenticate = %q, want %q", got, cred.challenge)
+        }
+        assertGolden(t, cred.golden, resp.body)
+      })
+    }
+  }
+}
+
+func TestCustomerAuthorization(t *testing.T) {
+  s := newTestServer(t)
+  token := s.adminToken()
+
+  credentials := []struct {
+    name     string
+    token    string
+    merchant *testMerchant
+  }{
+    {name: "no credentials"},
+    {name: "unknown key", merchant: &testMerchant{Name: merchantAcme.Name, APIKey: "wrong"}},
+    {name: "revoked key", merchant: &merchantRevoked},
+    {name: "admin token", token: token},
+  }
+  for _, cred := range credentials {
+    t.Run(cred.name, func(t *testing.T) {
+      resp := s.do(apiRequest{method: http.MethodGet, path: "/customer/orders", token: cred.token, merchant: cred.merchant})
+      if resp.status != http.StatusUnauthorized {
+        t.Fatalf("status = %d, want 401: %s", resp.status, resp.body)
+      }
+      if got := resp.header.Get("WWW-Authenticate"); got != `APIKey realm="customer"` {
+        t.Errorf("WWW-Authenticate = %q", got)
+      }
+      assertGolden(t, "unauthorized", resp.body)
+    })
+  }
+}
+
+func TestDeactivateAPIKey(t *testing.T) {
+  s := newTestServer(t)
+  token := s.adminToken()
+
+  // Warm the key cache so deactivation has to invalidate it.
+  for i := 0; i < 2; i++ {
+    if resp := s.do(apiRequest{method: http.MethodGet, path: "/customer/orders", merchant: &merchantAcme}); resp.status != http.StatusOK {
+      t.Fatalf("before deactivation: status = %d: %s", resp.status, resp.body)
+    }
+  }
+
+  resp := s.do(apiRequest{method: http.MethodDelete, path: "/admin/merchants/acme/api-key", token: token})
+  if resp.status != http.StatusNoContent {
+    t.Fatalf("deactivate: status = %d: %s", resp.status, resp.body)
+  }
+  resp = s.do(apiRequest{method: http.MethodGet, path: "/customer/orders", merchant: &merchantAcme})
+  if resp.status != http.StatusUnauthorized {
+    t.Fatalf("after deactivation: status = %d, want 401: %s", resp.status, resp.body)
+  }
+  if resp := s.do(apiRequest{method: http.MethodGet, path: "/customer/orders", merchant: &merchantGlobex}); resp.status != http.StatusOK {
+    t.Fatalf("other merchant: status = %d: %s", resp.status, resp.body)
+  }
+
+  resp = s.do(apiRequest{method: http.MethodDelete, path: "/admin/merchants/acme/api-key", token: token})
+  if resp.status != http.StatusNotFound {
+    t.Fatalf("deactivate twice: status = %d, want 404: %s", resp.status, resp.body)
+  }
+}
+
+func TestHealthAndReady(t *testing.T) {
+  s := newTestServer(t)
+
+  resp := s.do(apiRequest{method: http.MethodGet, path: "/health"})
+  if resp.status != http.StatusOK {
+    t.Fatalf("health status = %d: %s", resp.status, resp.body)
+  }
+
+  resp = s.do(apiRequest{method: http.MethodGet, path: "/ready"})
+  if resp.status != http.StatusOK {
+    t.Fatalf("ready status = %d: %s", resp.status, resp.body)
+  }
+  var ready struct {
+    Status string            `json:"status"`
+    Checks map[string]string `json:"checks"`
+    Pool   map[string]int64  `json:"database_pool"`
+  }
+  if err := json.Unmarshal(resp.body, &ready); err != nil {
+    t.Fatal(err)
+  }
+  if ready.Status != "ready" || ready.Checks["database"] != "ok" || ready.Checks["migrations"] != "ok" {
+    t.Errorf("ready = %s", resp.body)
+  }
+  if ready.Pool["max_open_connections"] != 1 {
+    t.Errorf("pool max_open_connections = %d, want 1 for SQLite", ready.Pool["max_open_connections"])
+  }
+
+  // Rolling back the latest migration leaves the schema behind the binary.
+  if _, err := s.db.Exec(`DELETE FROM schema_migrations WHERE version = (SELECT MAX(version) FROM schema_migrations)`); err != nil {
+    t.Fatal(err)
+  }
+  resp = s.do(apiRequest{method: http.MethodGet, path: "/ready"})
+  if resp.status != http.StatusServiceUnavailable {
+    t.Fatalf("ready with pending migration: status = %d: %s", resp.status, resp.body)
+  }
+  if err := json.Unmarshal(resp.body, &ready); err != nil {
+    t.Fatal(err)
+  }
+  if ready.Status != "not_ready" || ready.Checks["migrations"] != "1 pending" {
+    t.Errorf("ready with pending migration = %s", resp.body)
+  }
+
+  s.db.Close()
+  resp = s.do(apiRequest{method: http.MethodGet, path: "/ready"})
+  if resp.status != http.StatusServiceUnavailable {
+    t.Fatalf("ready with closed database: status = %d: %s", resp.status, resp.body)
+  }
+  if err := json.Unmarshal(resp.body, &ready); err != nil {
+    t.Fatal(err)
+  }
+  if ready.Checks["database"] != "unavailable" {
+    t.Errorf("ready with closed database = %s", resp.body)
+  }
+}
+
+func TestMetrics(t *testing.T) {
+  s := newTestServer(t)
+  token := s.adminToken()
+
+  id := s.createOrder(merchantAcme, "tx-metrics")
+  s.do(apiRequest{method: http.MethodPost, path: "/admin/order/status", body: fmt.Sprintf(`{"id":%d,"status":"Paid"}`, id), token: token})
+  s.do(apiRequest{method: http.MethodGet, path: "/customer/orders", merchant: &merchantRevoked})
+  s.do(apiRequest{method: http.MethodGet, path: "/admin/stats", token: "not-a-jwt"})
+  s.do(apiRequest{method: http.MethodGet, path: "/no-such-route"})
+
+  resp := s.do(apiRequest{method: http.MethodGet, path: "/metrics"})
+  if resp.status != http.StatusOK {
+    t.Fatalf("metrics status = %d", resp.status)
+  }
+  body := string(resp.body)
+  for _, want := range []string{
+    `sarah_http_requests_total{method="POST",route="/customer/orders",status="201"}`,
+    `sarah_http_requests_total{method="GET",route="/admin/stats",status="401"}`,
+    `sarah_http_requests_total{method="GET",route="unmatched",status="404"}`,
+    `sarah_http_request_duration_seconds_bucket{method="POST",route="/admin/login",le="+Inf"}`,
+    `sarah_orders_created_total{asset="USDT",network="TRON"}`,
+    `sarah_order_status_transitions_total{from="Processing",to="Paid"}`,
+    `sarah_auth_failures_total{method="api_key",reason="invalid"}`,
+    `sarah_auth_failures_total{method="jwt",reason="invalid"}`,
+    `go_sql_max_open_connections{db_name="primary"} 1`,
+  } {
+    if !strings.Contains(body, want) {
+      t.Errorf("metrics missing %s", want)
+    }
+  }
+}
+
+func TestRequestID(t *testing.T) {
+  s := newTestServer(t)
+
+  var logs bytes.Buffer
+  logger, err := logging.New(&logs, "json", "info")
+  if err != nil {
+    t.Fatal(err)
+  }
+  prev := slog.Default()
+  t.Cleanup(func() { slog.SetDefault(prev) })
+  slog.SetDefault(logger)
+
+  resp := s.do(apiRequest{method: http.MethodGet, path: "/customer/order?id=999", merchant: &merchantAcme, header: map[string]string{"X-Request-ID": "support-123"}})
+  if resp.status != http.StatusNotFound {
+    t.Fatalf("status = %d: %s", resp.status, resp.body)
+  }
+  if got := resp.header.Get("X-Request-ID"); got != "support-123" {
+    t.Errorf("X-Request-ID = %q, want caller's ID echoed", got)
+  }
+  var errBody struct {
+    RequestID string `json:"request_id"`
+  }
+  if err := json.Unmarshal(resp.body, &errBody); err != nil {
+    t.Fatal(err)
+  }
+  if errBody.RequestID != "support-123" {
+    t.Errorf("error body request_id = %q", errBody.RequestID)
+  }
+
+  var access map[string]any
+  if err := json.Unmarshal(logs.Bytes(), &access); err != nil {
+    t.Fatalf("access log %q: %v", logs.String(), err)
+  }
+  if access["request_id"] != "support-123" || access["route"] != "/customer/order" || access["status"] != float64(404) || access["merchant"] != merchantAcme.Name {
+    t.Errorf("access log = %v", access)
+  }
+
+  resp = s.do(apiRequest{method: http.MethodGet, path: "/health", header: map[string]string{"X-Request-ID": "bad id\twith spaces"}})
+  if got := resp.header.Get("X-Request-ID"); got == "" || got == "bad id\twith spaces" {
+    t.Errorf("X-Request-ID = %q, want a generated ID", got)
+  }
+}
+
+func TestTracing(t *testing.T) {
+  spans := tracetest.NewInMemoryExporter()
+  provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(spans))
+  prevPropagator := otel.GetTextMapPropagator()
+  t.Cleanup(func() {
+    _ = provider.Shutdown(context.Background())
+    otel.SetTextMapPropagator(prevPropagator)
+  })
+  otel.SetTracerProvider(provider)
+  otel.SetTextMapPropagator(propagation.TraceContext{})
+
+  // The server is built after the provider is installed so its database
+  // driver records spans too.
+  s := newTestServer(t)
+  spans.Reset()
+
+  const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+  resp := s.do(apiRequest{method: http.MethodGet, path: "/customer/orders", merchant: &merchantAcme, header: map[string]string{
+    "traceparent": "00-" + traceID + "-00f067aa0ba902b7-01",
+  }})
+  if resp.status != http.StatusOK {
+    t.Fatalf("status = %d: %s", resp.status, resp.body)
+  }
+
+  names := map[string]bool{}
+  for _, span := range spans.GetSpans() {
+    if got := span.SpanContext.TraceID().String(); got != traceID {
+      t.Errorf("span %q trace ID = %s, want the caller's %s", span.Name, got, traceID)
+    }
+    names[span.Name] = true
+  }
+  for _, want := range []string{"GET /customer/orders", "authenticateCustomer", "sql.conn.query"} {
+    if !names[want] {
+      t.Errorf("missing span %q, got %v", want, names)
+    }
+  }
+}
+
+func TestRateLimit(t *testing.T) {
+  for _, backend := range []string{"memory", "database"} {
+    t.Run(backend, func(t *testing.T) {
+      s := newTestServer(t, func(cfg *config.Config) {
+        cfg.RateLimit.Store = backend
+        cfg.RateLimit.Merchant = config.RateLimitRule{PerMinute: 60, Burst: 2}
+        cfg.RateLimit.Login = config.RateLimitRule{PerMinute: 60, Burst: 2}
+      })
+      if _, err := s.db.Exec(`INSERT INTO merchant_rate_limits (merchant_name, requests_per_minute, burst) VALUES (?, 60, 4)`, merchantGlobex.Name); err != nil {
+        t.Fatal(err)
+      }
+
+      listOrders := func(m testMerchant) apiResponse {
+        return s.do(apiRequest{method: http.MethodGet, path: "/customer/orders", merchant: &m})
+      }
+      for i, wantRemaining := range []string{"1", "0"} {
+        resp := listOrders(merchantAcme)
+        if resp.status != http.StatusOK {
+          t.Fatalf("request %d: status = %d: %s", i, resp.status, resp.body)
+        }
+        if got := resp.header.Get("X-RateLimit-Limit"); got != "2" {
+          t.Errorf("request %d: X-RateLimit-Limit = %q, want 2", i, got)
+        }
+        if got := resp.header.Get("X-RateLimit-Remaining"); got != wantRemaining {
+          t.Errorf("request %d: X-RateLimit-Remaining = %q, want %s", i, got, wantRemaining)
+        }
+      }
+      resp := listOrders(merchantAcme)
+      if resp.status != http.StatusTooManyRequests {
+        t.Fatalf("over limit: status = %d: %s", resp.status, resp.body)
+      }
+      if got := resp.header.Get("Retry-After"); got != "1" {
+        t.Errorf("Retry-After = %q, want 1", got)
+      }
+      assertGolden(t, "rate_limited", resp.body)
+
+      // Guessing keys for a merchant does not use up the merchant's bucket.
+      wrongKey := merchantAcme
+      wrongKey.APIKey = "guess"
+      if resp := listOrders(wrongKey); resp.status != http.StatusUnauthorized {
+        t.Errorf("wrong key: status = %d, want 401", resp.status)
+      }
+
+      // Globex has a larger quota stored in the database.
+      for i := 0; i < 4; i++ {
+        if resp := listOrders(merchantGlobex); resp.status != http.StatusOK {
+          t.Fatalf("globex request %d: status = %d: %s", i, resp.status, resp.body)
+        }
+      }
+      if resp := listOrders(merchantGlobex); resp.status != http.StatusTooManyRequests {
+        t.Errorf("globex over quota: status = %d", resp.status)
+      }
+
+      login := apiRequest{method: http.MethodPost, path: "/admin/login", body: `{"username":"admin","password":"wrong"}`}
+      for i := 0; i < 2; i++ {
+        if resp := s.do(login); resp.status != http.StatusUnauthorized {
+          t.Fatalf("login %d: status = %d: %s", i, resp.status, resp.body)
+        }
+      }
+      if resp := s.do(login); resp.status != http.StatusTooManyRequests {
+        t.Errorf("login over limit: status = %d", resp.status)
+      }
+    })
+  }
+}
+
+func TestCORS(t *testing.T) {
+  s := newTestServer(t, func(cfg *config.Config) {
+    cfg.CORS.AdminOrigins = []string{"https://admin.example.com"}
+    cfg.CORS.MerchantOrigins = []string{"https://portal.example.com/"}
+  })
+
+  preflight := func(path, origin, method string) apiResponse {
+    return s.do(apiRequest{method: http.MethodOptions, path: path, header: map[string]string{
+      "Origin":                        origin,
+      "Access-Control-Request-Method": method,
+    }})
+  }
+
+  resp := preflight("/admin/stats", "https://admin.example.com", http.MethodGet)
+  if resp.status != http.StatusNoContent {
+    t.Fatalf("admin preflight status = %d", resp.status)
+  }
+  for header, want := range map[string]string{
+    "Access-Control-Allow-Origin":  "https://admin.example.com",
+    "Access-Control-Allow-Methods": "GET, HEAD, OPTIONS",
+    "Access-Control-Max-Age":       "600",
+  } {
+    if got := resp.header.Get(header); got != want {
+      t.Errorf("admin preflight %s = %q, want %q", header, got, want)
+    }
+  }
+
+  resp = preflight("/customer/orders", "https://portal.example.com", http.MethodPost)
+  if got := resp.header.Get("Access-Control-Allow-Methods"); got != "GET, HEAD, POST, OPTIONS" {
+    t.Errorf("merchant preflight Allow-Methods = %q", got)
+  }
+
+  // Each origin list only covers its own routes.
+  for _, tc := range []struct{ path, origin string }{
+    {"/admin/stats", "https://portal.example.com"},
+    {"/customer/orders", "https://admin.example.com"},
+    {"/customer/orders", "https://evil.example.com"},
+  } {
+    resp := preflight(tc.path, tc.origin, http.MethodGet)
+    if got := resp.header.Get("Access-Control-Allow-Origin"); got != "" {
+      t.Errorf("preflight %s from %s: Access-Control-Allow-Origin = %q, want none", tc.path, tc.origin, got)
+    }
+  }
+
+  // Actual requests carry CORS and security headers, errors included.
+  resp = s.do(apiRequest{method: http.MethodGet, path: "/admin/stats", header: map[string]string{"Origin": "https://admin.example.com"}})
+  if resp.status != http.StatusUnauthorized {
+    t.Fatalf("admin stats status = %d", resp.status)
+  }
+  for header, want := range map[string]string{
+    "Access-Control-Allow-Origin":   "https://admin.example.com",
+    "Access-Control-Expose-Headers": "X-Request-ID, Retry-After, X-RateLimit-Limit, X-RateLimit-Remaining, X-RateLimit-Reset, Idempotent-Replayed",
+    "X-Content-Type-Options":        "nosniff",
+    "Strict-Transport-Security":     "max-age=31536000",
+    "Content-Security-Policy":       "default-src 'none'; frame-ancestors 'none'",
+  } {
+    if got := resp.header.Get(header); got != want {
+      t.Errorf("admin stats %s = %q, want %q", header, got, want)
+    }
+  }
+}
+
+// TestOpenAPI fails when a route is added, removed or changes method
+// without the same change to openapi.json.
+func TestOpenAPI(t *testing.T) {
+  s := newTestServer(t)
+
+  resp := s.do(apiRequest{method: http.MethodGet, path: "/openapi.json"})
+  if resp.status != http.StatusOK || resp.header.Get("Content-Type") != "application/json" {
+    t.Fatalf("openapi.json: status = %d, content type %q", resp.status, resp.header.Get("Content-Type"))
+  }
+  if !bytes.Equal(resp.body, openapi.Spec) {
+    t.Error("served document differs from openapi.Spec")
+  }
+  var doc struct {
+    OpenAPI string                    `json:"openapi"`
+    Paths   map[string]map[string]any `json:"paths"`
+  }
+  if err := json.Unmarshal(resp.body, &doc); err != nil {
+    t.Fatal(err)
+  }
+  if doc.OpenAPI != "3.1.0" {
+    t.Errorf("openapi = %q, want 3.1.0", doc.OpenAPI)
+  }
+
+  documented := map[string]bool{}
+  for path, ops := range doc.Paths {
+    for method := range ops {
+      documented[strings.ToUpper(method)+" "+path] = true
+    }
+  }
+  registered := map[string]bool{}
+  for _, pattern := range s.app.Routes() {
+    // Go's {name...} wildcard is a plain path parameter in OpenAPI.
+    registered[strings.ReplaceAll(pattern, "...}", "}")] = true
+  }
+  for route := range registered {
+    if !documented[route] {
+      t.Errorf("%s is served but not documented", route)
+    }
+  }
+  for route := range documented {
+    if !registered[route] {
+      t.Errorf("%s is documented but not served", route)
+    }
+  }
+
+  resp = s.do(apiRequest{method: http.MethodGet, path: "/docs/"})
+  if resp.status != http.StatusOK || !strings.Contains(string(resp.body), "swagger-ui-bundle.js") {
+    t.Fatalf("docs page: status = %d: %.200s", resp.status, resp.body)
+  }
+  if csp := resp.header.Get("Content-Security-Policy"); !strings.Contains(csp, "script-src 'self'") {
+    t.Errorf("docs page Content-Security-Policy = %q", csp)
+  }
+  for _, asset := range []string{"/docs/init.js", "/docs/swagger-ui-bundle.js", "/docs/swagger-ui.css"} {
+    if resp := s.do(apiRequest{method: http.MethodGet, path: asset}); resp.status != http.StatusOK {
+      t.Errorf("%s: status = %d", asset, resp.status)
+    }
+  }
+}
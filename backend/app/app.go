@@ -0,0 +1,241 @@
+// Package app wires the database, store and HTTP routes into a server that
+// can be run from main or embedded in tests.
+package app
+
+import (
+  "context"
+  "database/sql"
+  "errors"
+  "fmt"
+  "log/slog"
+  "net/http"
+  "os"
+  "os/signal"
+  "sync"
+  "syscall"
+  "time"
+
+  "sarah-project-backend/config"
+  "sarah-project-backend/database"
+  "sarah-project-backend/metrics"
+  "sarah-project-backend/migrations"
+  "sarah-project-backend/security"
+  "sarah-project-backend/store"
+)
+
+// App is a configured API server.
+type App struct {
+  cfg     config.Config
+  db      *sql.DB
+  ownsDB  bool
+  replica *sql.DB
+  // unregister removes the pool metrics registered for db and replica.
+  unregister []func()
+  migrator   *migrations.Migrator
+  store      store.Store
+  keys       *security.KeySet
+  server     *http.Server
+  // patterns lists the registered routes as "METHOD /path".
+  patterns []string
+
+  workerCtx   context.Context
+  stopWorkers context.CancelFunc
+  workers     sync.WaitGroup
+  closeOnce   sync.Once
+}
+
+// New connects to the configured database, applies pending migrations and
+// builds the HTTP server. cfg must already be validated. Call Run to serve,
+// or Close to release resources without serving.
+func New(cfg config.Config) (*App, error) {
+  db, err := database.Open(cfg.Database.Options())
+  if err != nil {
+    return nil, err
+  }
+  a, err := NewWithDB(cfg, db)
+  if err != nil {
+    db.Close()
+    return nil, err
+  }
+  a.ownsDB = true
+  return a, nil
+}
+
+// NewWithDB is like New but uses an already open db, which must match
+// cfg.Database.Driver. The caller keeps ownership of db and must close it.
+func NewWithDB(cfg config.Config, db *sql.DB) (*App, error) {
+  driver, err := database.ParseDriver(cfg.Database.Driver)
+  if err != nil {
+    return nil, err
+  }
+  keys, err := cfg.JWT.KeySet()
+  if err != nil {
+    return nil, err
+  }
+  migrator, err := migrations.New(db, driver)
+  if err != nil {
+    return nil, err
+  }
+  if err := migrate(migrator); err != nil {
+    return nil, err
+  }
+
+  st := store.New(db, driver)
+  st.SetTimeouts(store.Timeouts{
+    Query:  cfg.Database.QueryTimeout,
+    Auth:   cfg.Database.AuthQueryTimeout,
+    Payout: cfg.Database.PayoutTimeout,
+  })
+
+  replica, err := database.OpenReplica(cfg.Database.Options())
+  if err != nil {
+    return nil, err
+  }
+  if replica != nil {
+    st.SetReplica(replica)
+  }
+
+  unregister, err := registerPoolMetrics(db, replica)
+  if err != nil {
+    if replica != nil {
+      replica.Close()
+    }
+    return nil, err
+  }
+
+  workerCtx, stopWorkers := context.WithCancel(context.Background())
+  a := &App{
+    unregister:  unregister,
+    cfg:         cfg,
+    db:          db,
+    replica:     replica,
+    migrator:    migrator,
+    store:       st,
+    keys:        keys,
+    workerCtx:   workerCtx,
+    stopWorkers: stopWorkers,
+  }
+  a.server = &http.Server{
+    Addr:         cfg.HTTP.Addr,
+    Handler:      a.routes(),
+    ReadTimeout:  cfg.HTTP.ReadTimeout,
+    WriteTimeout: cfg.HTTP.WriteTimeout,
+    IdleTimeout:  cfg.HTTP.IdleTimeout,
+  }
+  if cfg.RateLimit.Store == "database" {
+    a.Go(a.pruneRateLimitBuckets)
+  }
+  return a, nil
+}
+
+// Handler returns the API handler, e.g. for use with httptest.
+func (a *App) Handler() http.Handler {
+  return a.server.Handler
+}
+
+// Routes returns the registered routes as "METHOD /path" patterns.
+func (a *App) Routes() []string {
+  return a.patterns
+}
+
+// Store returns the store backing the API.
+func (a *App) Store() store.Store {
+  return a.store
+}
+
+// Go runs fn in a background worker. The worker's context is cancelled on
+// shutdown and Close waits for fn to return.
+func (a *App) Go(fn func(ctx context.Context)) {
+  a.workers.Add(1)
+  go func() {
+    defer a.workers.Done()
+    fn(a.workerCtx)
+  }()
+}
+
+// Run serves HTTP until ctx is cancelled or the process receives SIGINT or
+// SIGTERM. It then stops accepting connections, waits up to the configured
+// shutdown timeout for in-flight requests, stops background workers and
+// closes the database.
+func (a *App) Run(ctx context.Context) error {
+  defer a.Close()
+
+  ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+  defer stop()
+
+  serveErr := make(chan error, 1)
+  go func() {
+    slog.Info("API listening", "addr", a.cfg.HTTP.Addr)
+    serveErr <- a.server.ListenAndServe()
+  }()
+
+  select {
+  case err := <-serveErr:
+    if errors.Is(err, http.ErrServerClosed) {
+      return nil
+    }
+    return err
+  case <-ctx.Done():
+  }
+  // Restore default signal handling so a second signal kills the process.
+  stop()
+
+  slog.Info("shutting down, waiting for in-flight requests", "timeout", a.cfg.HTTP.ShutdownTimeout.String())
+  shutdownCtx, cancel := context.WithTimeout(context.Background(), a.cfg.HTTP.ShutdownTimeout)
+  defer cancel()
+  if err := a.server.Shutdown(shutdownCtx); err != nil {
+    return fmt.Errorf("shutdown: %w", err)
+  }
+  return nil
+}
+
+// Close stops background workers, closes the read replica and closes the
+// database if New opened it. It is safe to call more than once.
+func (a *App) Close() error {
+  var err error
+  a.closeOnce.Do(func() {
+    a.stopWorkers()
+    a.workers.Wait()
+    for _, unregister := range a.unregister {
+      unregister()
+    }
+    if a.replica != nil {
+      err = a.replica.Close()
+    }
+    if a.ownsDB {
+      err = errors.Join(err, a.db.Close())
+    }
+  })
+  return err
+}
+
+// registerPoolMetrics exports connection pool statistics for the primary
+// and, when configured, the replica.
+func registerPoolMetrics(db *sql.DB, replica *sql.DB) ([]func(), error) {
+  unregister, err := metrics.RegisterDB(db, "primary")
+  if err != nil {
+    return nil, err
+  }
+  unregisters := []func(){unregister}
+  if replica != nil {
+    unregister, err := metrics.RegisterDB(replica, "replica")
+    if err != nil {
+      unregisters[0]()
+      return nil, err
+    }
+    unregisters = append(unregisters, unregister)
+  }
+  return unregisters, nil
+}
+
+// migrate applies pending schema migrations at startup.
+func migrate(migrator *migrations.Migrator) error {
+  ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+  defer cancel()
+
+  applied, err := migrator.Up(ctx, 0)
+  for _, m := range applied {
+    slog.Info("applied migration", "version", m.Version, "name", m.Name)
+  }
+  return err
+}
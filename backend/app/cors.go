@@ -0,0 +1,87 @@
+package app
+
+import (
+  "net/http"
+  "strconv"
+  "strings"
+
+  "sarah-project-backend/config"
+)
+
+const (
+  corsAllowHeaders  = "Content-Type, Authorization, X-API-Key, X-Merchant-Name, X-API-Version, X-Request-ID, Idempotency-Key"
+  corsExposeHeaders = "X-Request-ID, Retry-After, X-RateLimit-Limit, X-RateLimit-Remaining, X-RateLimit-Reset, Idempotent-Replayed"
+)
+
+// corsPolicy answers cross-origin requests from an allow-list of origins.
+// Requests from other origins get no CORS headers, so browsers block them.
+type corsPolicy struct {
+  anyOrigin   bool
+  origins     map[string]bool
+  credentials bool
+  maxAge      string
+}
+
+func newCORSPolicy(origins []string, cfg config.CORS) *corsPolicy {
+  p := &corsPolicy{
+    origins:     make(map[string]bool, len(origins)),
+    credentials: cfg.AllowCredentials,
+    maxAge:      strconv.Itoa(int(cfg.MaxAge.Seconds())),
+  }
+  for _, origin := range origins {
+    if origin == "*" {
+      p.anyOrigin = true
+      continue
+    }
+    p.origins[strings.ToLower(strings.TrimSuffix(origin, "/"))] = true
+  }
+  return p
+}
+
+// wrap adds the CORS response headers for allowed origins to next.
+func (p *corsPolicy) wrap(next http.Handler) http.Handler {
+  if p == nil {
+    return next
+  }
+  return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    p.headers(w, r)
+    next.ServeHTTP(w, r)
+  })
+}
+
+// headers sets the CORS response headers and reports whether the request's
+// origin is allowed.
+func (p *corsPolicy) headers(w http.ResponseWriter, r *http.Request) bool {
+  h := w.Header()
+  h.Add("Vary", "Origin")
+  origin := r.Header.Get("Origin")
+  if origin == "" || !(p.anyOrigin || p.origins[strings.ToLower(origin)]) {
+    return false
+  }
+  if p.anyOrigin {
+    h.Set("Access-Control-Allow-Origin", "*")
+  } else {
+    h.Set("Access-Control-Allow-Origin", origin)
+  }
+  if p.credentials {
+    h.Set("Access-Control-Allow-Credentials", "true")
+  }
+  h.Set("Access-Control-Expose-Headers", corsExposeHeaders)
+  return true
+}
+
+// preflight answers an OPTIONS request for a path serving methods. The
+// caller has already called headers; allowed is its result.
+func (p *corsPolicy) preflight(w http.ResponseWriter, r *http.Request, allowed bool, methods string) {
+  if r.Header.Get("Access-Control-Request-Method") == "" {
+    return
+  }
+  h := w.Header()
+  h.Add("Vary", "Access-Control-Request-Method")
+  h.Add("Vary", "Access-Control-Request-Headers")
+  if allowed {
+    h.Set("Access-Control-Allow-Methods", methods)
+    h.Set("Access-Control-Allow-Headers", corsAllowHeaders)
+    h.Set("Access-Control-Max-Age", p.maxAge)
+  }
+}
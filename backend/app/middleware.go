@@ -0,0 +1,189 @@
+package app
+
+import (
+  "crypto/rand"
+  "encoding/hex"
+  "log/slog"
+  "net/http"
+  "strconv"
+  "strings"
+  "time"
+
+  "go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+  "go.opentelemetry.io/otel/attribute"
+  semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+  "go.opentelemetry.io/otel/trace"
+
+  "sarah-project-backend/config"
+  "sarah-project-backend/logging"
+  "sarah-project-backend/metrics"
+)
+
+// maxRequestIDLen bounds caller-supplied request IDs so they cannot bloat
+// logs.
+const maxRequestIDLen = 128
+
+// withRequestID reuses the caller's X-Request-ID when it looks sane and
+// generates one otherwise. The ID is echoed in the response and attached to
+// the request context for logging.
+func withRequestID(next http.Handler) http.Handler {
+  return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    id := r.Header.Get(logging.RequestIDHeader)
+    if !validRequestID(id) {
+      id = newRequestID()
+    }
+    w.Header().Set(logging.RequestIDHeader, id)
+    trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("request.id", id))
+    next.ServeHTTP(w, r.WithContext(logging.WithRequestID(r.Context(), id)))
+  })
+}
+
+// validRequestID accepts IDs made of letters, digits and -._: so they are
+// safe to log and echo.
+func validRequestID(id string) bool {
+  if id == "" || len(id) > maxRequestIDLen {
+    return false
+  }
+  for _, c := range id {
+    switch {
+    case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+    case c == '-', c == '.', c == '_', c == ':':
+    default:
+      return false
+    }
+  }
+  return true
+}
+
+func newRequestID() string {
+  buf := make([]byte, 16)
+  _, _ = rand.Read(buf)
+  return hex.EncodeToString(buf)
+}
+
+// withSecurityHeaders sets headers that harden every response. The API only
+// serves JSON and files, so the default policy forbids loading or framing
+// anything.
+func withSecurityHeaders(cfg config.HTTP, next http.Handler) http.Handler {
+  hsts := ""
+  if cfg.HSTSMaxAge > 0 {
+    hsts = "max-age=" + strconv.Itoa(int(cfg.HSTSMaxAge.Seconds()))
+  }
+  return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    h := w.Header()
+    h.Set("X-Content-Type-Options", "nosniff")
+    h.Set("X-Frame-Options", "DENY")
+    h.Set("Referrer-Policy", "no-referrer")
+    if hsts != "" {
+      h.Set("Strict-Transport-Security", hsts)
+    }
+    if cfg.ContentSecurityPolicy != "" {
+      h.Set("Content-Security-Policy", cfg.ContentSecurityPolicy)
+    }
+    next.ServeHTTP(w, r)
+  })
+}
+
+// untracedPaths are probe and scrape endpoints that would only add noise to
+// traces.
+var untracedPaths = map[string]bool{
+  "/health":  true,
+  "/ready":   true,
+  "/metrics": true,
+}
+
+// traced starts a server span for each request, continuing the caller's W3C
+// trace context. Spans are named after the matched route.
+func traced(mux *http.ServeMux, next http.Handler) http.Handler {
+  return otelhttp.NewHandler(next, "http.server",
+    otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+      return r.Method + " " + matchedRoute(mux, r)
+    }),
+    otelhttp.WithFilter(func(r *http.Request) bool {
+      return !untracedPaths[r.URL.Path]
+    }),
+  )
+}
+
+// matchedRoute returns the path of the mux pattern that matches r, without
+// its method, or "unmatched".
+func matchedRoute(mux *http.ServeMux, r *http.Request) string {
+  if _, pattern := mux.Handler(r); pattern != "" {
+    if _, path, ok := strings.Cut(pattern, " "); ok {
+      return path
+    }
+    return pattern
+  }
+  return "unmatched"
+}
+
+// instrument records request counts and latencies labelled with the mux
+// pattern that matched, so IDs in query strings do not create new series,
+// and writes an access log line for each request.
+func instrument(mux *http.ServeMux) http.Handler {
+  return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    route := matchedRoute(mux, r)
+    method := metricMethod(r.Method)
+    trace.SpanFromContext(r.Context()).SetAttributes(semconv.HTTPRoute(route))
+
+    rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+    start := time.Now()
+    mux.ServeHTTP(rec, r)
+
+    elapsed := time.Since(start)
+    metrics.HTTPRequests.WithLabelValues(route, method, strconv.Itoa(rec.status)).Inc()
+    metrics.HTTPDuration.WithLabelValues(route, method).Observe(elapsed.Seconds())
+
+    attrs := []slog.Attr{
+      slog.String("method", r.Method),
+      slog.String("route", route),
+      slog.String("path", r.URL.Path),
+      slog.Int("status", rec.status),
+      slog.Float64("latency_ms", float64(elapsed.Microseconds())/1000),
+    }
+    merchant, adminID := logging.Principal(r.Context())
+    if merchant != "" {
+      attrs = append(attrs, slog.String("merchant", merchant))
+    }
+    if adminID != "" {
+      attrs = append(attrs, slog.String("admin_id", adminID))
+    }
+    slog.LogAttrs(r.Context(), slog.LevelInfo, "request", attrs...)
+  })
+}
+
+// metricMethod folds non-standard methods into one label value.
+func metricMethod(method string) string {
+  switch method {
+  case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+    http.MethodPatch, http.MethodDelete, http.MethodOptions:
+    return method
+  default:
+    return "OTHER"
+  }
+}
+
+// statusRecorder captures the status code written by a handler.
+type statusRecorder struct {
+  http.ResponseWriter
+  status      int
+  wroteHeader bool
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+  if !rec.wroteHeader {
+    rec.status = status
+    rec.wroteHeader = true
+  }
+  rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *statusRecorder) Write(b []byte) (int, error) {
+  rec.wroteHeader = true
+  return rec.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (rec *statusRecorder) Unwrap() http.ResponseWriter {
+  return rec.ResponseWriter
+}
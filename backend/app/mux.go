@@ -0,0 +1,94 @@
+package app
+
+import (
+  "net/http"
+  "net/url"
+  "strings"
+
+  "sarah-project-backend/handler"
+)
+
+// router registers "METHOD /path" routes on a ServeMux and answers every
+// other method on those paths itself: OPTIONS with the Allow header (and
+// CORS preflight headers), anything else with a JSON 405.
+type router struct {
+  mux      *http.ServeMux
+  patterns []string
+  paths    []string
+  // methods and cors are keyed by path.
+  methods map[string][]string
+  cors    map[string]*corsPolicy
+}
+
+func newRouter() *router {
+  return &router{
+    mux:     http.NewServeMux(),
+    methods: make(map[string][]string),
+    cors:    make(map[string]*corsPolicy),
+  }
+}
+
+// handle registers h for pattern, which must be "METHOD /path". cors may be
+// nil for routes that are not called from browsers. Requests for an unknown
+// X-API-Version are rejected before they reach h.
+func (rt *router) handle(pattern string, cors *corsPolicy, h http.Handler) {
+  method, path, _ := strings.Cut(pattern, " ")
+  if _, ok := rt.methods[path]; !ok {
+    rt.paths = append(rt.paths, path)
+    rt.cors[path] = cors
+  }
+  rt.patterns = append(rt.patterns, pattern)
+  rt.methods[path] = append(rt.methods[path], method)
+  rt.mux.Handle(pattern, cors.wrap(handler.APIVersion(h)))
+}
+
+// finish registers the OPTIONS and 405 fallbacks and returns the mux.
+func (rt *router) finish() *http.ServeMux {
+  for _, path := range rt.paths {
+    allow := allowHeader(rt.methods[path])
+    cors := rt.cors[path]
+    notAllowed := handler.MethodNotAllowed(allow)
+    rt.mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+      allowed := cors != nil && cors.headers(w, r)
+      if r.Method != http.MethodOptions {
+        notAllowed(w, r)
+        return
+      }
+      w.Header().Set("Allow", allow)
+      if cors != nil {
+        cors.preflight(w, r, allowed, allow)
+      }
+      w.WriteHeader(http.StatusNoContent)
+    })
+  }
+  return rt.mux
+}
+
+// allowHeader lists methods for the Allow header. GET routes also serve
+// HEAD, and every path answers OPTIONS.
+func allowHeader(methods []string) string {
+  var out []string
+  for _, m := range methods {
+    out = append(out, m)
+    if m == http.MethodGet {
+      out = append(out, http.MethodHead)
+    }
+  }
+  return strings.Join(append(out, http.MethodOptions), ", ")
+}
+
+// deprecated marks a legacy route, pointing clients at successor. {name}
+// placeholders in successor are filled from the request's query string.
+func deprecated(successor string, next http.Handler) http.Handler {
+  return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    link := successor
+    for name, values := range r.URL.Query() {
+      if len(values) > 0 && values[0] != "" {
+        link = strings.ReplaceAll(link, "{"+name+"}", url.PathEscape(values[0]))
+      }
+    }
+    w.Header().Set("Deprecation", "true")
+    w.Header().Set("Link", "<"+link+`>; rel="successor-version"`)
+    next.ServeHTTP(w, r)
+  })
+}
@@ -0,0 +1,52 @@
+package app
+
+import (
+  "context"
+  "log/slog"
+  "time"
+
+  "sarah-project-backend/handler"
+  "sarah-project-backend/ratelimit"
+)
+
+const (
+  // bucketPruneInterval is how often idle database buckets are deleted.
+  bucketPruneInterval = 10 * time.Minute
+  // bucketRetention keeps buckets long enough to refill under any sane
+  // quota; a pruned bucket starts full.
+  bucketRetention = 24 * time.Hour
+)
+
+// rateLimiter builds the limiter for the configured bucket store.
+func (a *App) rateLimiter() *handler.RateLimiter {
+  cfg := a.cfg.RateLimit
+  buckets := ratelimit.Store(ratelimit.NewMemory())
+  if cfg.Store == "database" {
+    buckets = ratelimit.StoreFunc(a.store.TakeRateLimitToken)
+  }
+  return handler.NewRateLimiter(buckets, a.store, handler.RateLimitConfig{
+    Merchant:          ratelimit.Limit(cfg.Merchant),
+    IP:                ratelimit.Limit(cfg.IP),
+    Login:             ratelimit.Limit(cfg.Login),
+    TrustForwardedFor: cfg.TrustForwardedFor,
+  })
+}
+
+// pruneRateLimitBuckets deletes idle database buckets until ctx is done.
+func (a *App) pruneRateLimitBuckets(ctx context.Context) {
+  ticker := time.NewTicker(bucketPruneInterval)
+  defer ticker.Stop()
+  for {
+    select {
+    case <-ctx.Done():
+      return
+    case <-ticker.C:
+    }
+    n, err := a.store.PruneRateLimitBuckets(ctx, time.Now().Add(-bucketRetention))
+    if err != nil {
+      slog.ErrorContext(ctx, "prune rate limit buckets failed", "error", err)
+      continue
+    }
+    slog.DebugContext(ctx, "pruned rate limit buckets", "deleted", n)
+  }
+}
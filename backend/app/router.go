@@ -0,0 +1,91 @@
+package app
+
+import (
+  "context"
+  "errors"
+  "fmt"
+  "log/slog"
+  "net/http"
+
+  "sarah-project-backend/handler"
+  "sarah-project-backend/keycache"
+  "sarah-project-backend/metrics"
+  "sarah-project-backend/openapi"
+  "sarah-project-backend/payout"
+)
+
+// routes registers every API route on a new mux.
+func (a *App) routes() http.Handler {
+  st, cfg := a.store, a.cfg
+  jwtConfig := handler.AuthConfig{
+    Keys:      a.keys,
+    JWTIssuer: cfg.JWT.Issuer,
+    JWTTTL:    cfg.JWT.TTL,
+  }
+  pages := handler.PageConfig{
+    DefaultSize: cfg.Pagination.DefaultPageSize,
+    MaxSize:     cfg.Pagination.MaxPageSize,
+  }
+  payoutConfig := payout.Config(cfg.Payout)
+  limit := a.rateLimiter()
+
+  admin := newCORSPolicy(cfg.CORS.AdminOrigins, cfg.CORS)
+  merchant := newCORSPolicy(cfg.CORS.MerchantOrigins, cfg.CORS)
+
+  merchants := keycache.New(st, keycache.Config(cfg.APIKeyCache))
+  auth := handler.NewAuthenticator(a.keys, merchants)
+
+  rt := newRouter()
+  rt.handle("POST /admin/login", admin, limit.Login(handler.AdminLogin(st, jwtConfig)))
+  rt.handle("GET /admin/stats", admin, auth.Admin(handler.PermOrdersRead, handler.AdminStats(st)))
+  rt.handle("GET /admin/orders", admin, auth.Admin(handler.PermOrdersRead, handler.AdminListOrders(st, pages)))
+  rt.handle("GET /admin/orders/{id}", admin, auth.Admin(handler.PermOrdersRead, handler.AdminOrderDetail(st)))
+  rt.handle("PATCH /admin/orders/{id}/status", admin, auth.Admin(handler.PermOrdersWrite, handler.AdminUpdateOrderStatus(st)))
+  rt.handle("POST /admin/payouts", admin, auth.Admin(handler.PermPayoutsWrite, handler.AdminCreatePayoutBatch(st, payoutConfig)))
+  rt.handle("GET /admin/payouts/{batch_id}/file", admin, auth.Admin(handler.PermPayoutsRead, handler.AdminPayoutFile(st)))
+  rt.handle("GET /admin/catalogue", admin, auth.Admin(handler.PermCatalogueRead, handler.AdminCatalogue(st)))
+  rt.handle("POST /admin/catalogue/assets", admin, auth.Admin(handler.PermCatalogueWrite, handler.AdminUpsertCatalogueAsset(st)))
+  rt.handle("POST /admin/catalogue/countries", admin, auth.Admin(handler.PermCatalogueWrite, handler.AdminUpsertCatalogueCountry(st)))
+  rt.handle("DELETE /admin/merchants/{merchant}/api-key", admin, auth.Admin(handler.PermMerchantsWrite, handler.AdminDeactivateAPIKey(merchants)))
+  rt.handle("GET /customer/orders", merchant, limit.Customer(auth.Merchant(handler.PermMerchantOrders, handler.ListCustomerOrders(st, pages))))
+  rt.handle("POST /customer/orders", merchant, limit.Customer(auth.Merchant(handler.PermMerchantOrders, handler.CreateOrder(st, st))))
+  rt.handle("GET /customer/orders/{id}", merchant, limit.Customer(auth.Merchant(handler.PermMerchantOrders, handler.GetCustomerOrder(st))))
+  rt.handle("GET /.well-known/jwks.json", nil, handler.JWKS(jwtConfig))
+  rt.handle("GET /health", nil, handler.Health())
+  rt.handle("GET /ready", nil, handler.Ready(a.readyChecks(), a.db.Stats))
+  rt.handle("GET /metrics", nil, metrics.Handler())
+  rt.handle("GET /openapi.json", nil, openapi.Handler())
+  rt.handle("GET /docs/{path...}", nil, openapi.UI("/docs/"))
+
+  // Legacy routes from before the resource layout. They keep working but
+  // advertise their replacement.
+  rt.handle("GET /admin/ready-processing", admin, deprecated("/admin/orders?status=Processing", auth.Admin(handler.PermOrdersRead, handler.AdminReadyProcessing(st, pages))))
+  rt.handle("GET /admin/recent-orders", admin, deprecated("/admin/orders", auth.Admin(handler.PermOrdersRead, handler.AdminListOrders(st, pages))))
+  rt.handle("GET /admin/order", admin, deprecated("/admin/orders/{id}", auth.Admin(handler.PermOrdersRead, handler.AdminOrderDetail(st))))
+  rt.handle("POST /admin/order/status", admin, deprecated("/admin/orders/{id}/status", auth.Admin(handler.PermOrdersWrite, handler.AdminUpdateOrderStatus(st))))
+  rt.handle("GET /admin/payouts/file", admin, deprecated("/admin/payouts/{batch_id}/file", auth.Admin(handler.PermPayoutsRead, handler.AdminPayoutFile(st))))
+  rt.handle("POST /customer/createOrder", merchant, deprecated("/customer/orders", limit.Customer(auth.Merchant(handler.PermMerchantOrders, handler.CreateOrder(st, st)))))
+  rt.handle("GET /customer/order", merchant, deprecated("/customer/orders/{id}", limit.Customer(auth.Merchant(handler.PermMerchantOrders, handler.GetCustomerOrder(st)))))
+  mux := rt.finish()
+  a.patterns = rt.patterns
+
+  return traced(mux, withRequestID(withSecurityHeaders(cfg.HTTP, instrument(mux))))
+}
+
+// readyChecks verifies the database answers and its schema is current.
+func (a *App) readyChecks() []handler.ReadyCheck {
+  return []handler.ReadyCheck{
+    {Name: "database", Check: a.db.PingContext},
+    {Name: "migrations", Public: true, Check: func(ctx context.Context) error {
+      pending, err := a.migrator.Pending(ctx)
+      if err != nil {
+        slog.ErrorContext(ctx, "readiness check failed", "check", "migrations", "error", err)
+        return errors.New("status unavailable")
+      }
+      if pending > 0 {
+        return fmt.Errorf("%d pending", pending)
+      }
+      return nil
+    }},
+  }
+}
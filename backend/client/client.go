@@ -0,0 +1,272 @@
+// Package client is the Go SDK for the merchant API under /customer. It
+// sends the merchant credentials with every request, retries failed calls
+// with backoff, and makes order creation safe to retry with idempotency
+// keys.
+//
+// Tests can run a Client against clienttest.Server instead of the real
+// service.
+package client
+
+import (
+  "bytes"
+  "context"
+  "encoding/json"
+  "errors"
+  "fmt"
+  "io"
+  "math/rand/v2"
+  "net/http"
+  "net/url"
+  "strconv"
+  "strings"
+  "time"
+)
+
+// APIVersion is sent in X-API-Version so order statuses use the spellings
+// in this package.
+const APIVersion = "2"
+
+// Defaults applied by New to unset Config fields.
+const (
+  DefaultRetries    = 3
+  DefaultMinBackoff = 250 * time.Millisecond
+  DefaultMaxBackoff = 5 * time.Second
+  DefaultTimeout    = 30 * time.Second
+)
+
+// Config configures a Client. BaseURL, Merchant and APIKey are required.
+type Config struct {
+  // BaseURL is the service root, e.g. https://api.example.com.
+  BaseURL string
+  // Merchant and APIKey are sent as X-Merchant-Name and X-API-Key.
+  Merchant string
+  APIKey   string
+
+  // HTTPClient sends the requests. It defaults to a client with
+  // DefaultTimeout per attempt.
+  HTTPClient *http.Client
+  // Retries is how many times a failed call is repeated. 0 uses
+  // DefaultRetries; a negative value disables retries.
+  Retries int
+  // MinBackoff and MaxBackoff bound the jittered exponential delay between
+  // attempts. A Retry-After header from the server takes precedence.
+  MinBackoff time.Duration
+  MaxBackoff time.Duration
+  // UserAgent is sent with every request when set.
+  UserAgent string
+}
+
+// Client calls the merchant API. It is safe for concurrent use.
+type Client struct {
+  base *url.URL
+  cfg  Config
+  // sleep waits between attempts; tests replace it.
+  sleep func(ctx context.Context, d time.Duration) error
+}
+
+// New returns a client for cfg.
+func New(cfg Config) (*Client, error) {
+  if cfg.BaseURL == "" || cfg.Merchant == "" || cfg.APIKey == "" {
+    return nil, errors.New("client: BaseURL, Merchant and APIKey are required")
+  }
+  base, err := url.Parse(strings.TrimSuffix(cfg.BaseURL, "/"))
+  if err != nil {
+    return nil, fmt.Errorf("client: BaseURL: %w", err)
+  }
+  if base.Scheme != "http" && base.Scheme != "https" {
+    return nil, fmt.Errorf("client: BaseURL %q must be http or https", cfg.BaseURL)
+  }
+  if cfg.HTTPClient == nil {
+    cfg.HTTPClient = &http.Client{Timeout: DefaultTimeout}
+  }
+  if cfg.Retries == 0 {
+    cfg.Retries = DefaultRetries
+  }
+  if cfg.MinBackoff <= 0 {
+    cfg.MinBackoff = DefaultMinBackoff
+  }
+  if cfg.MaxBackoff < cfg.MinBackoff {
+    cfg.MaxBackoff = max(DefaultMaxBackoff, cfg.MinBackoff)
+  }
+  return &Client{base: base, cfg: cfg, sleep: sleep}, nil
+}
+
+// Error is a response the API rejected. Code is the machine-readable "code"
+// field, e.g. "validation_failed" or "not_found".
+type Error struct {
+  StatusCode int          `json:"-"`
+  Code       string       `json:"code"`
+  Message    string       `json:"message"`
+  Details    []FieldError `json:"details"`
+  RequestID  string       `json:"request_id"`
+  // RetryAfter is the server's Retry-After delay, if it sent one.
+  RetryAfter time.Duration `json:"-"`
+}
+
+// FieldError describes one invalid request field.
+type FieldError struct {
+  Field   string `json:"field"`
+  Code    string `json:"code"`
+  Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+  msg := fmt.Sprintf("client: %d %s: %s", e.StatusCode, e.Code, e.Message)
+  if e.RequestID != "" {
+    msg += " (request " + e.RequestID + ")"
+  }
+  return msg
+}
+
+// IsNotFound reports whether err is a 404 from the API.
+func IsNotFound(err error) bool {
+  var apiErr *Error
+  return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// request is one API call. Every call the SDK makes is safe to repeat:
+// reads by nature, order creation through its idempotency key.
+type request struct {
+  method string
+  path   string
+  query  url.Values
+  header http.Header
+  body   any
+}
+
+// do sends req, retrying transport errors, 429 and 5xx responses, and
+// decodes a 2xx body into out.
+func (c *Client) do(ctx context.Context, req request, out any) (http.Header, error) {
+  var body []byte
+  if req.body != nil {
+    var err error
+    if body, err = json.Marshal(req.body); err != nil {
+      return nil, fmt.Errorf("client: encode request: %w", err)
+    }
+  }
+
+  retries := max(c.cfg.Retries, 0)
+  for attempt := 0; ; attempt++ {
+    header, err := c.send(ctx, req, body, out)
+    if err == nil || attempt >= retries || !retryable(ctx, err) {
+      return header, err
+    }
+    delay := c.backoff(attempt)
+    var apiErr *Error
+    if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+      delay = apiErr.RetryAfter
+    }
+    if err := c.sleep(ctx, delay); err != nil {
+      return nil, err
+    }
+  }
+}
+
+func (c *Client) send(ctx context.Context, req request, body []byte, out any) (http.Header, error) {
+  u := *c.base
+  u.Path += req.path
+  u.RawQuery = req.query.Encode()
+
+  var reader io.Reader
+  if body != nil {
+    reader = bytes.NewReader(body)
+  }
+  httpReq, err := http.NewRequestWithContext(ctx, req.method, u.String(), reader)
+  if err != nil {
+    return nil, fmt.Errorf("client: %w", err)
+  }
+  for k, v := range req.header {
+    httpReq.Header[k] = v
+  }
+  httpReq.Header.Set("Accept", "application/json")
+  httpReq.Header.Set("X-API-Version", APIVersion)
+  httpReq.Header.Set("X-Merchant-Name", c.cfg.Merchant)
+  httpReq.Header.Set("X-API-Key", c.cfg.APIKey)
+  if body != nil {
+    httpReq.Header.Set("Content-Type", "application/json")
+  }
+  if c.cfg.UserAgent != "" {
+    httpReq.Header.Set("User-Agent", c.cfg.UserAgent)
+  }
+
+  resp, err := c.cfg.HTTPClient.Do(httpReq)
+  if err != nil {
+    return nil, err
+  }
+  defer resp.Body.Close()
+  data, err := io.ReadAll(resp.Body)
+  if err != nil {
+    return nil, err
+  }
+
+  if resp.StatusCode < 200 || resp.StatusCode > 299 {
+    return resp.Header, decodeError(resp, data)
+  }
+  if out != nil {
+    if err := json.Unmarshal(data, out); err != nil {
+      return resp.Header, fmt.Errorf("client: decode %s %s response: %w", req.method, req.path, err)
+    }
+  }
+  return resp.Header, nil
+}
+
+func decodeError(resp *http.Response, data []byte) error {
+  apiErr := &Error{StatusCode: resp.StatusCode}
+  if json.Unmarshal(data, apiErr) != nil || apiErr.Code == "" {
+    // Proxies and load balancers answer with their own bodies.
+    apiErr.Code = "http_" + strconv.Itoa(resp.StatusCode)
+    apiErr.Message = strings.TrimSpace(string(data))
+    if apiErr.Message == "" {
+      apiErr.Message = http.StatusText(resp.StatusCode)
+    }
+  }
+  if apiErr.RequestID == "" {
+    apiErr.RequestID = resp.Header.Get("X-Request-ID")
+  }
+  if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+    apiErr.RetryAfter = time.Duration(secs) * time.Second
+  }
+  return apiErr
+}
+
+// retryable reports whether a failed attempt may succeed if repeated.
+func retryable(ctx context.Context, err error) bool {
+  if ctx.Err() != nil {
+    return false
+  }
+  var apiErr *Error
+  if errors.As(err, &apiErr) {
+    switch apiErr.StatusCode {
+    case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+      http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+      return true
+    }
+    return false
+  }
+  // Anything else failed before a response arrived: a refused connection,
+  // a reset or a timeout.
+  var urlErr *url.Error
+  return errors.As(err, &urlErr)
+}
+
+// backoff returns the delay before retry attempt+1: exponential from
+// MinBackoff, capped at MaxBackoff, with the upper half jittered.
+func (c *Client) backoff(attempt int) time.Duration {
+  d := c.cfg.MinBackoff
+  for i := 0; i < attempt && d < c.cfg.MaxBackoff; i++ {
+    d *= 2
+  }
+  d = min(d, c.cfg.MaxBackoff)
+  return d/2 + rand.N(d/2+1)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+  t := time.NewTimer(d)
+  defer t.Stop()
+  select {
+  case <-ctx.Done():
+    return ctx.Err()
+  case <-t.C:
+    return nil
+  }
+}
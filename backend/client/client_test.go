@@ -0,0 +1,194 @@
+package client_test
+
+import (
+  "context"
+  "errors"
+  "fmt"
+  "net/http"
+  "strings"
+  "testing"
+  "time"
+
+  "sarah-project-backend/client"
+  "sarah-project-backend/client/clienttest"
+)
+
+func newClient(t *testing.T) (*client.Client, *clienttest.Server) {
+  t.Helper()
+  srv := clienttest.NewServer()
+  t.Cleanup(srv.Close)
+  c, err := client.New(srv.Config())
+  if err != nil {
+    t.Fatal(err)
+  }
+  return c, srv
+}
+
+func order(txid string) client.CreateOrderRequest {
+  amount := 100.0
+  return client.CreateOrderRequest{
+    TransactionNetwork: "TRON",
+    TransactionAsset:   "USDT",
+    TXID:               txid,
+    Amount:             &amount,
+    Email:              "payer@example.com",
+    BeneficiaryName:    "Jane Doe",
+    BankCountry:        "Canada",
+    BankName:           "Royal Bank of Canada",
+    IBAN:               "1234567",
+    SWIFT:              "000312345",
+  }
+}
+
+func TestOrders(t *testing.T) {
+  ctx := context.Background()
+  c, _ := newClient(t)
+
+  created, err := c.CreateOrder(ctx, order("tx-1"))
+  if err != nil {
+    t.Fatal(err)
+  }
+  got, err := c.GetOrder(ctx, created.ID)
+  if err != nil {
+    t.Fatal(err)
+  }
+  if got.TXID != "tx-1" || got.Status != client.StatusProcessing || *got.Amount != 100 {
+    t.Fatalf("order = %+v", got)
+  }
+
+  if _, err := c.GetOrder(ctx, 999); !client.IsNotFound(err) {
+    t.Fatalf("missing order: err = %v, want not found", err)
+  }
+
+  _, err = c.CreateOrder(ctx, client.CreateOrderRequest{TXID: "tx-2"})
+  var apiErr *client.Error
+  if !errors.As(err, &apiErr) || apiErr.Code != "validation_failed" || len(apiErr.Details) == 0 {
+    t.Fatalf("invalid order: err = %#v", err)
+  }
+}
+
+func TestRetries(t *testing.T) {
+  ctx := context.Background()
+  c, srv := newClient(t)
+
+  srv.Fail(http.StatusServiceUnavailable, 2)
+  if _, err := c.CreateOrder(ctx, order("tx-retry")); err != nil {
+    t.Fatal(err)
+  }
+  if srv.Requests() != 3 || len(srv.Orders()) != 1 {
+    t.Fatalf("requests = %d, orders = %d; want 3 and 1", srv.Requests(), len(srv.Orders()))
+  }
+
+  // Client errors are not retried.
+  before := srv.Requests()
+  srv.Fail(http.StatusBadRequest, 1)
+  if _, err := c.ListOrders(ctx, client.ListOptions{}); err == nil {
+    t.Fatal("injected 400: no error")
+  }
+  if srv.Requests() != before+1 {
+    t.Fatalf("400 was retried %d times", srv.Requests()-before-1)
+  }
+
+  // Retries give up after Config.Retries.
+  cfg := srv.Config()
+  cfg.Retries = 1
+  c, err := client.New(cfg)
+  if err != nil {
+    t.Fatal(err)
+  }
+  before = srv.Requests()
+  srv.Fail(http.StatusTooManyRequests, 5)
+  _, err = c.GetOrder(ctx, 1)
+  var apiErr *client.Error
+  if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests {
+    t.Fatalf("err = %v, want 429", err)
+  }
+  if srv.Requests() != before+2 {
+    t.Fatalf("requests = %d, want 2", srv.Requests()-before)
+  }
+}
+
+func TestIdempotencyKey(t *testing.T) {
+  ctx := context.Background()
+  c, srv := newClient(t)
+
+  req := order("tx-idem")
+  req.IdempotencyKey = "invoice-42"
+  first, err := c.CreateOrder(ctx, req)
+  if err != nil {
+    t.Fatal(err)
+  }
+  second, err := c.CreateOrder(ctx, req)
+  if err != nil {
+    t.Fatal(err)
+  }
+  if first.Replayed || !second.Replayed || second.ID != first.ID || len(srv.Orders()) != 1 {
+    t.Fatalf("first = %+v, second = %+v, orders = %d", first, second, len(srv.Orders()))
+  }
+
+  req.TXID = "tx-other"
+  _, err = c.CreateOrder(ctx, req)
+  var apiErr *client.Error
+  if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusConflict {
+    t.Fatalf("reused key: err = %v, want 409", err)
+  }
+}
+
+func TestOrderIterator(t *testing.T) {
+  ctx := context.Background()
+  c, _ := newClient(t)
+  for i := 1; i <= 7; i++ {
+    if _, err := c.CreateOrder(ctx, order(fmt.Sprintf("tx-%d", i))); err != nil {
+      t.Fatal(err)
+    }
+  }
+
+  var ids []int64
+  it := c.Orders(ctx, 3)
+  for it.Next() {
+    ids = append(ids, it.Order().ID)
+    // A new order shifts the remaining pages but is not yielded, and no
+    // order is yielded twice.
+    if len(ids) == 2 {
+      if _, err := c.CreateOrder(ctx, order("tx-late")); err != nil {
+        t.Fatal(err)
+      }
+    }
+  }
+  if err := it.Err(); err != nil {
+    t.Fatal(err)
+  }
+  if fmt.Sprint(ids) != "[7 6 5 4 3 2 1]" {
+    t.Fatalf("ids = %v", ids)
+  }
+}
+
+func TestWebhookSignature(t *testing.T) {
+  payload := []byte(`{"order_id":1}`)
+  now := time.Now()
+  header := client.SignWebhook("secret", payload, now)
+
+  if err := client.VerifyWebhook("secret", header, payload, time.Minute); err != nil {
+    t.Fatalf("valid signature: %v", err)
+  }
+  if err := client.VerifyWebhook("other", header, payload, time.Minute); !errors.Is(err, client.ErrWebhookSignature) {
+    t.Fatalf("wrong secret: %v", err)
+  }
+  if err := client.VerifyWebhook("secret", header, []byte(`{"order_id":2}`), time.Minute); !errors.Is(err, client.ErrWebhookSignature) {
+    t.Fatalf("tampered payload: %v", err)
+  }
+  if err := client.VerifyWebhook("secret", "v1=abc", payload, time.Minute); !errors.Is(err, client.ErrWebhookSignature) {
+    t.Fatalf("no timestamp: %v", err)
+  }
+
+  old := client.SignWebhook("secret", payload, now.Add(-time.Hour))
+  if err := client.VerifyWebhook("secret", old, payload, time.Minute); !errors.Is(err, client.ErrWebhookExpired) {
+    t.Fatalf("old signature: %v", err)
+  }
+
+  // While a secret is rotated the sender signs with both.
+  rotated := client.SignWebhook("old", payload, now) + ",v1=" + strings.SplitN(header, "v1=", 2)[1]
+  if err := client.VerifyWebhook("secret", rotated, payload, time.Minute); err != nil {
+    t.Fatalf("rotated signature: %v", err)
+  }
+}
@@ -0,0 +1,322 @@
+// Package clienttest provides an in-memory fake of the merchant API for
+// testing code that uses package client.
+//
+//	srv := clienttest.NewServer()
+//	defer srv.Close()
+//	c, _ := client.New(srv.Config())
+//
+// The fake checks credentials, requires the fields the real service
+// requires, honours Idempotency-Key and paginates like the real service.
+// It does not check values against the asset and country catalogue.
+package clienttest
+
+import (
+  "encoding/json"
+  "fmt"
+  "net/http"
+  "net/http/httptest"
+  "sort"
+  "strconv"
+  "strings"
+  "sync"
+  "time"
+
+  "sarah-project-backend/client"
+)
+
+// Credentials accepted by a new Server.
+const (
+  Merchant = "test-merchant"
+  APIKey   = "test-api-key"
+)
+
+const (
+  defaultPageSize = 20
+  maxPageSize     = 100
+)
+
+// Server is a running fake. It is safe for concurrent use.
+type Server struct {
+  // URL is the base URL to pass to client.Config.
+  URL string
+
+  srv *httptest.Server
+
+  mu       sync.Mutex
+  orders   []stored
+  nextID   int64
+  failures []int
+  requests int
+}
+
+type stored struct {
+  order client.Order
+  key   string
+  req   client.CreateOrderRequest
+}
+
+type errorBody struct {
+  Code    string              `json:"code"`
+  Message string              `json:"message"`
+  Details []client.FieldError `json:"details"`
+  Error   string              `json:"error"`
+}
+
+// NewServer starts a fake with no orders. Close it when done.
+func NewServer() *Server {
+  s := &Server{}
+  mux := http.NewServeMux()
+  mux.HandleFunc("POST /customer/orders", s.authenticated(s.createOrder))
+  mux.HandleFunc("GET /customer/orders", s.authenticated(s.listOrders))
+  mux.HandleFunc("GET /customer/orders/{id}", s.authenticated(s.getOrder))
+  s.srv = httptest.NewServer(mux)
+  s.URL = s.srv.URL
+  return s
+}
+
+// Close shuts the server down.
+func (s *Server) Close() {
+  s.srv.Close()
+}
+
+// Config returns a client configuration for the server's credentials with
+// backoff short enough for tests.
+func (s *Server) Config() client.Config {
+  return client.Config{
+    BaseURL:    s.URL,
+    Merchant:   Merchant,
+    APIKey:     APIKey,
+    MinBackoff: time.Millisecond,
+    MaxBackoff: 10 * time.Millisecond,
+  }
+}
+
+// Fail answers the next n requests with status and an error body before
+// they reach the API, e.g. 503 to exercise retries.
+func (s *Server) Fail(status int, n int) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+  for i := 0; i < n; i++ {
+    s.failures = append(s.failures, status)
+  }
+}
+
+// Requests returns how many requests the server has received, including
+// failed ones.
+func (s *Server) Requests() int {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+  return s.requests
+}
+
+// SetStatus moves an order to status, as the payout team would.
+func (s *Server) SetStatus(id int64, status string) error {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+  for i := range s.orders {
+    if s.orders[i].order.ID == id {
+      s.orders[i].order.Status = status
+      return nil
+    }
+  }
+  return fmt.Errorf("clienttest: no order %d", id)
+}
+
+// Orders returns every order, oldest first.
+func (s *Server) Orders() []client.Order {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+  out := make([]client.Order, 0, len(s.orders))
+  for _, o := range s.orders {
+    out = append(out, o.order)
+  }
+  return out
+}
+
+func (s *Server) authenticated(next http.HandlerFunc) http.HandlerFunc {
+  return func(w http.ResponseWriter, r *http.Request) {
+    s.mu.Lock()
+    s.requests++
+    var fail int
+    if len(s.failures) > 0 {
+      fail, s.failures = s.failures[0], s.failures[1:]
+    }
+    s.mu.Unlock()
+
+    if fail != 0 {
+      writeError(w, fail, "injected_failure", http.StatusText(fail), nil)
+      return
+    }
+    if r.Header.Get("X-Merchant-Name") != Merchant || r.Header.Get("X-API-Key") != APIKey {
+      w.Header().Set("WWW-Authenticate", `APIKey realm="customer"`)
+      writeError(w, http.StatusUnauthorized, "unauthorized", "unauthorized", nil)
+      return
+    }
+    next(w, r)
+  }
+}
+
+func (s *Server) createOrder(w http.ResponseWriter, r *http.Request) {
+  key := strings.TrimSpace(r.Header.Get("Idempotency-Key"))
+  if len(key) > 128 {
+    writeError(w, http.StatusBadRequest, "validation_failed", "Idempotency-Key must be at most 128 characters", nil)
+    return
+  }
+  var req client.CreateOrderRequest
+  dec := json.NewDecoder(r.Body)
+  dec.DisallowUnknownFields()
+  if err := dec.Decode(&req); err != nil {
+    writeError(w, http.StatusBadRequest, "invalid_json", "invalid JSON body", nil)
+    return
+  }
+
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  if key != "" {
+    for _, o := range s.orders {
+      if o.key != key {
+        continue
+      }
+      if !sameRequest(o.req, req) {
+        writeError(w, http.StatusConflict, "conflict", "Idempotency-Key was already used for a different order", nil)
+        return
+      }
+      w.Header().Set("Idempotent-Replayed", "true")
+      writeJSON(w, http.StatusCreated, map[string]int64{"id": o.order.ID})
+      return
+    }
+  }
+  if details := validate(req); len(details) > 0 {
+    writeError(w, http.StatusBadRequest, "validation_failed", details[0].Message, details)
+    return
+  }
+
+  s.nextID++
+  s.orders = append(s.orders, stored{
+    key: key,
+    req: req,
+    order: client.Order{
+      ID:                 s.nextID,
+      TransactionNetwork: req.TransactionNetwork,
+      TransactionAsset:   req.TransactionAsset,
+      TXID:               req.TXID,
+      Amount:             req.Amount,
+      Email:              req.Email,
+      BeneficiaryName:    req.BeneficiaryName,
+      BankCountry:        req.BankCountry,
+      BankName:           req.BankName,
+      IBAN:               req.IBAN,
+      SWIFT:              req.SWIFT,
+      ReferenceNote:      req.ReferenceNote,
+      Status:             client.StatusProcessing,
+      CreatedAt:          time.Now().UTC().Truncate(time.Second),
+    },
+  })
+  writeJSON(w, http.StatusCreated, map[string]int64{"id": s.nextID})
+}
+
+func (s *Server) listOrders(w http.ResponseWriter, r *http.Request) {
+  page, ok := positiveQuery(w, r, "page", 1)
+  if !ok {
+    return
+  }
+  pageSize, ok := positiveQuery(w, r, "page_size", defaultPageSize)
+  if !ok {
+    return
+  }
+  pageSize = min(pageSize, maxPageSize)
+
+  s.mu.Lock()
+  orders := make([]client.Order, 0, len(s.orders))
+  for _, o := range s.orders {
+    orders = append(orders, o.order)
+  }
+  s.mu.Unlock()
+  sort.Slice(orders, func(i, j int) bool { return orders[i].ID > orders[j].ID })
+
+  start := min((page-1)*pageSize, len(orders))
+  end := min(start+pageSize, len(orders))
+  writeJSON(w, http.StatusOK, client.OrderList{
+    Total:    int64(len(orders)),
+    Page:     page,
+    PageSize: pageSize,
+    Orders:   orders[start:end],
+  })
+}
+
+func (s *Server) getOrder(w http.ResponseWriter, r *http.Request) {
+  id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+  if err != nil || id <= 0 {
+    writeError(w, http.StatusBadRequest, "validation_failed", "invalid id", []client.FieldError{{Field: "id", Code: "invalid_value", Message: "invalid id"}})
+    return
+  }
+  s.mu.Lock()
+  defer s.mu.Unlock()
+  for _, o := range s.orders {
+    if o.order.ID == id {
+      writeJSON(w, http.StatusOK, map[string]client.Order{"order": o.order})
+      return
+    }
+  }
+  writeError(w, http.StatusNotFound, "not_found", "order not found", nil)
+}
+
+func validate(req client.CreateOrderRequest) []client.FieldError {
+  var details []client.FieldError
+  for _, f := range []struct{ field, value string }{
+    {"transaction_network", req.TransactionNetwork},
+    {"transaction_asset", req.TransactionAsset},
+    {"txid", req.TXID},
+    {"email", req.Email},
+    {"beneficiary_name", req.BeneficiaryName},
+    {"bank_country", req.BankCountry},
+    {"bank_name", req.BankName},
+    {"iban", req.IBAN},
+    {"swift", req.SWIFT},
+  } {
+    if f.value == "" {
+      details = append(details, client.FieldError{Field: f.field, Code: "required", Message: f.field + " is required"})
+    }
+  }
+  if req.Amount != nil && *req.Amount < 0 {
+    details = append(details, client.FieldError{Field: "amount", Code: "out_of_range", Message: "amount must be non-negative"})
+  }
+  if req.Email != "" && !strings.Contains(req.Email, "@") {
+    details = append(details, client.FieldError{Field: "email", Code: "invalid_format", Message: "invalid email"})
+  }
+  return details
+}
+
+func sameRequest(a, b client.CreateOrderRequest) bool {
+  a.IdempotencyKey, b.IdempotencyKey = "", ""
+  ja, _ := json.Marshal(a)
+  jb, _ := json.Marshal(b)
+  return string(ja) == string(jb)
+}
+
+func positiveQuery(w http.ResponseWriter, r *http.Request, name string, def int) (int, bool) {
+  raw := r.URL.Query().Get(name)
+  if raw == "" {
+    return def, true
+  }
+  v, err := strconv.Atoi(raw)
+  if err != nil || v <= 0 {
+    writeError(w, http.StatusBadRequest, "validation_failed", "invalid "+name, []client.FieldError{{Field: name, Code: "invalid_value", Message: "invalid " + name}})
+    return 0, false
+  }
+  return v, true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+  w.Header().Set("Content-Type", "application/json")
+  w.WriteHeader(status)
+  _ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string, details []client.FieldError) {
+  if details == nil {
+    details = []client.FieldError{}
+  }
+  writeJSON(w, status, errorBody{Code: code, Message: message, Details: details, Error: message})
+}
@@ -0,0 +1,217 @@
+package client
+
+import (
+  "context"
+  "crypto/rand"
+  "encoding/hex"
+  "fmt"
+  "net/http"
+  "net/url"
+  "strconv"
+  "time"
+)
+
+// Order statuses. An order starts as Processing.
+const (
+  StatusProcessing    = "Processing"
+  StatusFundsReceived = "Funds Received"
+  StatusSubmitted     = "Submitted"
+  StatusPaid          = "Paid"
+  StatusFailed        = "Failed"
+)
+
+// CreateOrderRequest is the body of POST /customer/orders.
+type CreateOrderRequest struct {
+  TransactionNetwork string   `json:"transaction_network"`
+  TransactionAsset   string   `json:"transaction_asset"`
+  TXID               string   `json:"txid"`
+  Amount             *float64 `json:"amount"`
+  Email              string   `json:"email"`
+  BeneficiaryName    string   `json:"beneficiary_name"`
+  BankCountry        string   `json:"bank_country"`
+  BankName           string   `json:"bank_name"`
+  IBAN               string   `json:"iban"`
+  SWIFT              string   `json:"swift"`
+  ReferenceNote      *string  `json:"reference_note"`
+
+  // IdempotencyKey identifies the order across retries, up to 128
+  // characters. Creating an order again with the same key returns the
+  // first order instead of a duplicate, even from another process. When
+  // empty, CreateOrder generates one that covers its own retries.
+  IdempotencyKey string `json:"-"`
+}
+
+// CreatedOrder is the result of CreateOrder.
+type CreatedOrder struct {
+  ID int64 `json:"id"`
+  // Replayed is true when the idempotency key had been used before and ID
+  // is the order created then.
+  Replayed bool `json:"-"`
+}
+
+// Order is an order as returned by the API.
+type Order struct {
+  ID                 int64     `json:"id"`
+  TransactionNetwork string    `json:"transaction_network"`
+  TransactionAsset   string    `json:"transaction_asset"`
+  TXID               string    `json:"txid"`
+  Amount             *float64  `json:"amount"`
+  Email              string    `json:"email"`
+  BeneficiaryName    string    `json:"beneficiary_name"`
+  BankCountry        string    `json:"bank_country"`
+  BankName           string    `json:"bank_name"`
+  IBAN               string    `json:"iban"`
+  SWIFT              string    `json:"swift"`
+  ReferenceNote      *string   `json:"reference_note"`
+  Status             string    `json:"status"`
+  CreatedAt          time.Time `json:"created_at"`
+}
+
+// OrderList is one page of orders, newest first.
+type OrderList struct {
+  Total    int64   `json:"total"`
+  Page     int     `json:"page"`
+  PageSize int     `json:"page_size"`
+  Orders   []Order `json:"orders"`
+}
+
+// ListOptions selects a page. Zero values use the server defaults.
+type ListOptions struct {
+  Page     int
+  PageSize int
+}
+
+// CreateOrder creates an order. Retries reuse req.IdempotencyKey, or a
+// generated key, so a retried call never creates a second order.
+func (c *Client) CreateOrder(ctx context.Context, req CreateOrderRequest) (CreatedOrder, error) {
+  key := req.IdempotencyKey
+  if key == "" {
+    var err error
+    if key, err = newIdempotencyKey(); err != nil {
+      return CreatedOrder{}, err
+    }
+  }
+
+  var out CreatedOrder
+  header, err := c.do(ctx, request{
+    method: http.MethodPost,
+    path:   "/customer/orders",
+    header: http.Header{"Idempotency-Key": {key}},
+    body:   req,
+  }, &out)
+  if err != nil {
+    return CreatedOrder{}, err
+  }
+  out.Replayed = header.Get("Idempotent-Replayed") == "true"
+  return out, nil
+}
+
+// GetOrder returns one of the merchant's orders. A missing order is an
+// *Error for which IsNotFound is true.
+func (c *Client) GetOrder(ctx context.Context, id int64) (Order, error) {
+  var out struct {
+    Order Order `json:"order"`
+  }
+  _, err := c.do(ctx, request{
+    method: http.MethodGet,
+    path:   "/customer/orders/" + strconv.FormatInt(id, 10),
+  }, &out)
+  return out.Order, err
+}
+
+// ListOrders returns one page of the merchant's orders, newest first.
+func (c *Client) ListOrders(ctx context.Context, opts ListOptions) (OrderList, error) {
+  query := url.Values{}
+  if opts.Page > 0 {
+    query.Set("page", strconv.Itoa(opts.Page))
+  }
+  if opts.PageSize > 0 {
+    query.Set("page_size", strconv.Itoa(opts.PageSize))
+  }
+  var out OrderList
+  _, err := c.do(ctx, request{method: http.MethodGet, path: "/customer/orders", query: query}, &out)
+  return out, err
+}
+
+// Orders returns an iterator over all of the merchant's orders, newest
+// first, fetching pageSize orders per request (0 uses the server default).
+//
+//	it := c.Orders(ctx, 100)
+//	for it.Next() {
+//	  order := it.Order()
+//	}
+//	if err := it.Err(); err != nil {
+//
+// Orders created while iterating are skipped rather than shifting later
+// pages and yielding an order twice.
+func (c *Client) Orders(ctx context.Context, pageSize int) *OrderIterator {
+  return &OrderIterator{c: c, ctx: ctx, pageSize: pageSize}
+}
+
+// OrderIterator walks the pages of ListOrders. It is not safe for
+// concurrent use.
+type OrderIterator struct {
+  c        *Client
+  ctx      context.Context
+  pageSize int
+
+  page    int
+  buf     []Order
+  current Order
+  // lastID is the smallest ID yielded so far; the list is ordered by ID
+  // descending, so anything not below it was already seen or is new.
+  lastID int64
+  done   bool
+  err    error
+}
+
+// Next advances to the next order. It returns false at the end or on
+// error; check Err.
+func (it *OrderIterator) Next() bool {
+  for {
+    for len(it.buf) > 0 {
+      order := it.buf[0]
+      it.buf = it.buf[1:]
+      if it.lastID != 0 && order.ID >= it.lastID {
+        continue
+      }
+      it.current, it.lastID = order, order.ID
+      return true
+    }
+    if it.done || it.err != nil {
+      return false
+    }
+    it.fetch()
+  }
+}
+
+func (it *OrderIterator) fetch() {
+  it.page++
+  list, err := it.c.ListOrders(it.ctx, ListOptions{Page: it.page, PageSize: it.pageSize})
+  if err != nil {
+    it.err = err
+    return
+  }
+  it.buf = list.Orders
+  if len(list.Orders) == 0 || int64(list.Page)*int64(list.PageSize) >= list.Total {
+    it.done = true
+  }
+}
+
+// Order returns the order Next advanced to.
+func (it *OrderIterator) Order() Order {
+  return it.current
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *OrderIterator) Err() error {
+  return it.err
+}
+
+func newIdempotencyKey() (string, error) {
+  buf := make([]byte, 16)
+  if _, err := rand.Read(buf); err != nil {
+    return "", fmt.Errorf("client: idempotency key: %w", err)
+  }
+  return hex.EncodeToString(buf), nil
+}
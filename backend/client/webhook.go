@@ -0,0 +1,107 @@
+package client
+
+import (
+  "crypto/hmac"
+  "crypto/sha256"
+  "encoding/hex"
+  "errors"
+  "fmt"
+  "io"
+  "net/http"
+  "strconv"
+  "strings"
+  "time"
+)
+
+// Webhook signatures follow this scheme:
+//
+//	X-Webhook-Signature: t=<unix seconds>,v1=<hex HMAC-SHA256>
+//
+// where the HMAC is keyed with the merchant's webhook secret and covers
+// "<t>.<raw body>". During secret rotation the header carries one v1 entry
+// per secret; a receiver accepts the request if any entry matches.
+const (
+  WebhookSignatureHeader = "X-Webhook-Signature"
+  // DefaultWebhookTolerance is how old a signature VerifyWebhookRequest
+  // accepts, bounding replays of a captured delivery.
+  DefaultWebhookTolerance = 5 * time.Minute
+  // maxWebhookBody bounds the body VerifyWebhookRequest reads.
+  maxWebhookBody = 1 << 20
+)
+
+var (
+  // ErrWebhookSignature means the signature header is missing, malformed
+  // or signed with another secret.
+  ErrWebhookSignature = errors.New("client: invalid webhook signature")
+  // ErrWebhookExpired means the signature is valid but older, or further
+  // in the future, than the tolerance.
+  ErrWebhookExpired = errors.New("client: webhook signature timestamp outside tolerance")
+)
+
+// SignWebhook returns the signature header value for payload sent at t.
+// Senders, and tests of webhook receivers, use it.
+func SignWebhook(secret string, payload []byte, t time.Time) string {
+  ts := strconv.FormatInt(t.Unix(), 10)
+  return "t=" + ts + ",v1=" + webhookMAC(secret, ts, payload)
+}
+
+// VerifyWebhook checks a signature header against payload. A tolerance of
+// 0 skips the timestamp check.
+func VerifyWebhook(secret string, header string, payload []byte, tolerance time.Duration) error {
+  var ts string
+  var sigs []string
+  for _, part := range strings.Split(header, ",") {
+    k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+    switch k {
+    case "t":
+      ts = v
+    case "v1":
+      sigs = append(sigs, v)
+    }
+  }
+  unix, err := strconv.ParseInt(ts, 10, 64)
+  if err != nil || len(sigs) == 0 {
+    return ErrWebhookSignature
+  }
+
+  want := webhookMAC(secret, ts, payload)
+  matched := false
+  for _, sig := range sigs {
+    if hmac.Equal([]byte(sig), []byte(want)) {
+      matched = true
+    }
+  }
+  if !matched {
+    return ErrWebhookSignature
+  }
+  if tolerance > 0 {
+    if age := time.Since(time.Unix(unix, 0)); age > tolerance || age < -tolerance {
+      return ErrWebhookExpired
+    }
+  }
+  return nil
+}
+
+// VerifyWebhookRequest reads and verifies a webhook delivery with
+// DefaultWebhookTolerance and returns its body.
+func VerifyWebhookRequest(r *http.Request, secret string) ([]byte, error) {
+  payload, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBody+1))
+  if err != nil {
+    return nil, fmt.Errorf("client: read webhook: %w", err)
+  }
+  if len(payload) > maxWebhookBody {
+    return nil, fmt.Errorf("client: webhook body exceeds %d bytes", maxWebhookBody)
+  }
+  if err := VerifyWebhook(secret, r.Header.Get(WebhookSignatureHeader), payload, DefaultWebhookTolerance); err != nil {
+    return nil, err
+  }
+  return payload, nil
+}
+
+func webhookMAC(secret, ts string, payload []byte) string {
+  mac := hmac.New(sha256.New, []byte(secret))
+  mac.Write([]byte(ts))
+  mac.Write([]byte("."))
+  mac.Write(payload)
+  return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+  "context"
+  "flag"
+  "fmt"
+  "log"
+  "os"
+  "strconv"
+
+  "sarah-project-backend/config"
+  "sarah-project-backend/database"
+  "sarah-project-backend/migrations"
+)
+
+const usage = `usage: migrate [-dir migrations/sql] [-config file] <command> [args]
+
+commands:
+  up [n]        apply all pending migrations, or the next n
+  down [n]      roll back the last n migrations (default 1)
+  status        list migrations and whether they are applied
+  create NAME   write empty up/down files for a new migration (one pair per driver)
+
+The database is selected by DB_DRIVER (mysql or sqlite) or the database
+section of the config file.
+`
+
+func main() {
+  dir := flag.String("dir", "migrations/sql", "directory used by the create command")
+  configPath := flag.String("config", os.Getenv("CONFIG_FILE"), "optional YAML or TOML config file")
+  flag.Usage = func() {
+    fmt.Fprint(os.Stderr, usage)
+  }
+  flag.Parse()
+
+  args := flag.Args()
+  if len(args) == 0 {
+    flag.Usage()
+    os.Exit(2)
+  }
+
+  if args[0] == "create" {
+    if len(args) < 2 {
+      log.Fatal("create requires a migration name")
+    }
+    paths, err := migrations.Create(*dir, args[1])
+    if err != nil {
+      log.Fatal(err)
+    }
+    for _, path := range paths {
+      fmt.Println("created", path)
+    }
+    return
+  }
+
+  cfg, err := config.Load(*configPath)
+  if err != nil {
+    log.Fatalf("load configuration:\n%v", err)
+  }
+  if err := cfg.Database.Validate(); err != nil {
+    log.Fatalf("invalid configuration:\n%v", err)
+  }
+  dbConfig := cfg.Database.Options()
+  db, err := database.Open(dbConfig)
+  if err != nil {
+    log.Fatal(err)
+  }
+  defer db.Close()
+
+  migrator, err := migrations.New(db, dbConfig.Driver)
+  if err != nil {
+    log.Fatal(err)
+  }
+
+  ctx := context.Background()
+  switch args[0] {
+  case "up":
+    applied, err := migrator.Up(ctx, stepsArg(args))
+    for _, m := range applied {
+      fmt.Printf("applied %04d_%s\n", m.Version, m.Name)
+    }
+    if err != nil {
+      log.Fatal(err)
+    }
+    if len(applied) == 0 {
+      fmt.Println("no pending migrations")
+    }
+  case "down":
+    reverted, err := migrator.Down(ctx, stepsArg(args))
+    for _, m := range reverted {
+      fmt.Printf("reverted %04d_%s\n", m.Version, m.Name)
+    }
+    if err != nil {
+      log.Fatal(err)
+    }
+  case "status":
+    statuses, err := migrator.Status(ctx)
+    if err != nil {
+      log.Fatal(err)
+    }
+    for _, s := range statuses {
+      state := "pending"
+      if s.Applied {
+        state = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+      }
+      if s.Modified {
+        state += " (modified since applied)"
+      }
+      fmt.Printf("%04d_%-40s %s\n", s.Version, s.Name, state)
+    }
+  default:
+    flag.Usage()
+    os.Exit(2)
+  }
+}
+
+func stepsArg(args []string) int {
+  if len(args) < 2 {
+    return 0
+  }
+  n, err := strconv.Atoi(args[1])
+  if err != nil || n <= 0 {
+    log.Fatalf("invalid step count %q", args[1])
+  }
+  return n
+}
@@ -0,0 +1,477 @@
+// Package config loads service settings from built-in defaults, an optional
+// YAML or TOML file, .env and the process environment, in increasing order of
+// precedence.
+package config
+
+import (
+  "errors"
+  "fmt"
+  "os"
+  "path/filepath"
+  "strconv"
+  "strings"
+  "time"
+
+  "github.com/BurntSushi/toml"
+  "github.com/joho/godotenv"
+  "gopkg.in/yaml.v3"
+
+  "sarah-project-backend/database"
+)
+
+// Config is the effective service configuration.
+type Config struct {
+  HTTP       HTTP       `yaml:"http" toml:"http"`
+  Database   Database   `yaml:"database" toml:"database"`
+  JWT        JWT        `yaml:"jwt" toml:"jwt"`
+  Pagination Pagination `yaml:"pagination" toml:"pagination"`
+  Payout     Payout     `yaml:"payout" toml:"payout"`
+  Log        Log        `yaml:"log" toml:"log"`
+  Tracing    Tracing    `yaml:"tracing" toml:"tracing"`
+  RateLimit  RateLimit  `yaml:"rate_limit" toml:"rate_limit"`
+  CORS       CORS       `yaml:"cors" toml:"cors"`
+  // APIKeyCache caches merchant API key lookups in each instance.
+  APIKeyCache APIKeyCache `yaml:"api_key_cache" toml:"api_key_cache"`
+}
+
+type HTTP struct {
+  Addr            string        `yaml:"addr" toml:"addr"`
+  ReadTimeout     time.Duration `yaml:"read_timeout" toml:"read_timeout"`
+  WriteTimeout    time.Duration `yaml:"write_timeout" toml:"write_timeout"`
+  IdleTimeout     time.Duration `yaml:"idle_timeout" toml:"idle_timeout"`
+  ShutdownTimeout time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+  // HSTSMaxAge is sent in Strict-Transport-Security; 0 omits the header.
+  HSTSMaxAge            time.Duration `yaml:"hsts_max_age" toml:"hsts_max_age"`
+  ContentSecurityPolicy string        `yaml:"content_security_policy" toml:"content_security_policy"`
+}
+
+type Database struct {
+  Driver string `yaml:"driver" toml:"driver"`
+  MySQL  MySQL  `yaml:"mysql" toml:"mysql"`
+  // Replica is an optional MySQL read replica for dashboard and list
+  // queries. Empty fields other than Host are taken from MySQL.
+  Replica MySQL  `yaml:"replica" toml:"replica"`
+  SQLite  SQLite `yaml:"sqlite" toml:"sqlite"`
+
+  MaxOpenConns    int           `yaml:"max_open_conns" toml:"max_open_conns"`
+  MaxIdleConns    int           `yaml:"max_idle_conns" toml:"max_idle_conns"`
+  ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" toml:"conn_max_lifetime"`
+  ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" toml:"conn_max_idle_time"`
+
+  PingTimeout      time.Duration `yaml:"ping_timeout" toml:"ping_timeout"`
+  ConnectTimeout   time.Duration `yaml:"connect_timeout" toml:"connect_timeout"`
+  QueryTimeout     time.Duration `yaml:"query_timeout" toml:"query_timeout"`
+  AuthQueryTimeout time.Duration `yaml:"auth_query_timeout" toml:"auth_query_timeout"`
+  PayoutTimeout    time.Duration `yaml:"payout_timeout" toml:"payout_timeout"`
+}
+
+type MySQL struct {
+  Host     string `yaml:"host" toml:"host"`
+  Port     string `yaml:"port" toml:"port"`
+  User     string `yaml:"user" toml:"user"`
+  Password string `yaml:"password" toml:"password"`
+  Name     string `yaml:"name" toml:"name"`
+  Params   string `yaml:"params" toml:"params"`
+}
+
+type SQLite struct {
+  Path string `yaml:"path" toml:"path"`
+}
+
+type JWT struct {
+  // Secret is a single HS256 key with kid "default". Tokens issued before
+  // key rotation have no kid and are verified against it.
+  Secret string        `yaml:"secret" toml:"secret"`
+  Issuer string        `yaml:"issuer" toml:"issuer"`
+  TTL    time.Duration `yaml:"ttl" toml:"ttl"`
+  // Keys lists additional signing keys. New tokens are signed with
+  // SigningKID, or the first key when it is empty; all keys verify.
+  Keys       []JWTKey `yaml:"keys" toml:"keys"`
+  SigningKID string   `yaml:"signing_kid" toml:"signing_kid"`
+}
+
+// JWTKey is an HS256 secret, or a PEM RSA/Ed25519 key read from File.
+type JWTKey struct {
+  KID    string `yaml:"kid" toml:"kid"`
+  File   string `yaml:"file" toml:"file"`
+  Secret string `yaml:"secret" toml:"secret"`
+}
+
+type Pagination struct {
+  DefaultPageSize int `yaml:"default_page_size" toml:"default_page_size"`
+  MaxPageSize     int `yaml:"max_page_size" toml:"max_page_size"`
+}
+
+type Payout struct {
+  OriginatorName string `yaml:"originator_name" toml:"originator_name"`
+  DebtorAccount  string `yaml:"debtor_account" toml:"debtor_account"`
+  DebtorBIC      string `yaml:"debtor_bic" toml:"debtor_bic"`
+
+  NACHAImmediateDestination string `yaml:"nacha_immediate_destination" toml:"nacha_immediate_destination"`
+  NACHAImmediateOrigin      string `yaml:"nacha_immediate_origin" toml:"nacha_immediate_origin"`
+  NACHADestinationName      string `yaml:"nacha_destination_name" toml:"nacha_destination_name"`
+  NACHACompanyID            string `yaml:"nacha_company_id" toml:"nacha_company_id"`
+  NACHAODFIRouting          string `yaml:"nacha_odfi_routing" toml:"nacha_odfi_routing"`
+
+  CPA005OriginatorID      string `yaml:"cpa005_originator_id" toml:"cpa005_originator_id"`
+  CPA005DataCentre        string `yaml:"cpa005_data_centre" toml:"cpa005_data_centre"`
+  CPA005ReturnInstitution string `yaml:"cpa005_return_institution" toml:"cpa005_return_institution"`
+  CPA005ReturnAccount     string `yaml:"cpa005_return_account" toml:"cpa005_return_account"`
+}
+
+type Log struct {
+  Level  string `yaml:"level" toml:"level"`
+  Format string `yaml:"format" toml:"format"`
+}
+
+// Tracing mirrors tracing.Config.
+type Tracing struct {
+  Exporter    string  `yaml:"exporter" toml:"exporter"`
+  Endpoint    string  `yaml:"endpoint" toml:"endpoint"`
+  SampleRatio float64 `yaml:"sample_ratio" toml:"sample_ratio"`
+  ServiceName string  `yaml:"service_name" toml:"service_name"`
+}
+
+// CORS lists the browser origins allowed to call each part of the API. "*"
+// allows any origin.
+type CORS struct {
+  AdminOrigins     []string      `yaml:"admin_origins" toml:"admin_origins"`
+  MerchantOrigins  []string      `yaml:"merchant_origins" toml:"merchant_origins"`
+  AllowCredentials bool          `yaml:"allow_credentials" toml:"allow_credentials"`
+  MaxAge           time.Duration `yaml:"max_age" toml:"max_age"`
+}
+
+type RateLimit struct {
+  // Store is memory or database.
+  Store             string        `yaml:"store" toml:"store"`
+  Merchant          RateLimitRule `yaml:"merchant" toml:"merchant"`
+  IP                RateLimitRule `yaml:"ip" toml:"ip"`
+  Login             RateLimitRule `yaml:"login" toml:"login"`
+  TrustForwardedFor bool          `yaml:"trust_forwarded_for" toml:"trust_forwarded_for"`
+}
+
+// APIKeyCache mirrors keycache.Config. Size 0 disables the cache.
+type APIKeyCache struct {
+  Size        int           `yaml:"size" toml:"size"`
+  TTL         time.Duration `yaml:"ttl" toml:"ttl"`
+  NegativeTTL time.Duration `yaml:"negative_ttl" toml:"negative_ttl"`
+}
+
+// RateLimitRule mirrors ratelimit.Limit. PerMinute 0 disables the limit.
+type RateLimitRule struct {
+  PerMinute int `yaml:"per_minute" toml:"per_minute"`
+  Burst     int `yaml:"burst" toml:"burst"`
+}
+
+// Default returns the built-in settings. JWT.Secret and the MySQL
+// credentials have no defaults.
+func Default() Config {
+  return Config{
+    HTTP: HTTP{
+      Addr:                  ":8080",
+      ReadTimeout:           5 * time.Second,
+      WriteTimeout:          10 * time.Second,
+      IdleTimeout:           60 * time.Second,
+      ShutdownTimeout:       15 * time.Second,
+      HSTSMaxAge:            365 * 24 * time.Hour,
+      ContentSecurityPolicy: "default-src 'none'; frame-ancestors 'none'",
+    },
+    Database: Database{
+      Driver:           string(database.MySQL),
+      MySQL:            MySQL{Params: "charset=utf8mb4&parseTime=True&loc=Local"},
+      SQLite:           SQLite{Path: "sarah.db"},
+      MaxOpenConns:     25,
+      MaxIdleConns:     10,
+      ConnMaxLifetime:  30 * time.Minute,
+      ConnMaxIdleTime:  5 * time.Minute,
+      PingTimeout:      3 * time.Second,
+      ConnectTimeout:   30 * time.Second,
+      QueryTimeout:     5 * time.Second,
+      AuthQueryTimeout: 3 * time.Second,
+      PayoutTimeout:    10 * time.Second,
+    },
+    JWT: JWT{
+      Issuer: "sarah-project",
+      TTL:    60 * time.Minute,
+    },
+    Pagination: Pagination{
+      DefaultPageSize: 20,
+      MaxPageSize:     100,
+    },
+    Log: Log{
+      Level:  "info",
+      Format: "json",
+    },
+    Tracing: Tracing{
+      Exporter:    "none",
+      SampleRatio: 1,
+      ServiceName: "sarah-project-backend",
+    },
+    RateLimit: RateLimit{
+      Store:    "memory",
+      Merchant: RateLimitRule{PerMinute: 120, Burst: 30},
+      IP:       RateLimitRule{PerMinute: 600, Burst: 100},
+      Login:    RateLimitRule{PerMinute: 10, Burst: 5},
+    },
+    CORS: CORS{
+      // The admin frontend's development server.
+      AdminOrigins: []string{"http://localhost:8081"},
+      MaxAge:       10 * time.Minute,
+    },
+    APIKeyCache: APIKeyCache{
+      Size:        10000,
+      TTL:         30 * time.Second,
+      NegativeTTL: 5 * time.Second,
+    },
+  }
+}
+
+// Load builds the configuration from defaults, the file at path (skipped
+// when path is empty), .env and the environment. It reports every malformed
+// value at once; call Validate to check the result.
+func Load(path string) (Config, error) {
+  cfg := Default()
+  if path != "" {
+    if err := loadFile(path, &cfg); err != nil {
+      return cfg, err
+    }
+  }
+
+  // .env never overrides variables already set in the environment.
+  _ = godotenv.Load()
+
+  var env envLoader
+  env.string("HTTP_ADDR", &cfg.HTTP.Addr)
+  env.duration("HTTP_READ_TIMEOUT_SECONDS", time.Second, &cfg.HTTP.ReadTimeout)
+  env.duration("HTTP_WRITE_TIMEOUT_SECONDS", time.Second, &cfg.HTTP.WriteTimeout)
+  env.duration("HTTP_IDLE_TIMEOUT_SECONDS", time.Second, &cfg.HTTP.IdleTimeout)
+  env.duration("SHUTDOWN_TIMEOUT_SECONDS", time.Second, &cfg.HTTP.ShutdownTimeout)
+  env.duration("HTTP_HSTS_MAX_AGE_SECONDS", time.Second, &cfg.HTTP.HSTSMaxAge)
+  env.string("HTTP_CONTENT_SECURITY_POLICY", &cfg.HTTP.ContentSecurityPolicy)
+
+  env.string("DB_DRIVER", &cfg.Database.Driver)
+  env.string("MYSQL_HOST", &cfg.Database.MySQL.Host)
+  env.string("MYSQL_PORT", &cfg.Database.MySQL.Port)
+  env.string("MYSQL_USER", &cfg.Database.MySQL.User)
+  env.secret("MYSQL_PASSWORD", &cfg.Database.MySQL.Password)
+  env.string("MYSQL_DB", &cfg.Database.MySQL.Name)
+  env.string("MYSQL_PARAMS", &cfg.Database.MySQL.Params)
+  env.string("MYSQL_REPLICA_HOST", &cfg.Database.Replica.Host)
+  env.string("MYSQL_REPLICA_PORT", &cfg.Database.Replica.Port)
+  env.string("MYSQL_REPLICA_USER", &cfg.Database.Replica.User)
+  env.secret("MYSQL_REPLICA_PASSWORD", &cfg.Database.Replica.Password)
+  env.string("MYSQL_REPLICA_DB", &cfg.Database.Replica.Name)
+  env.string("MYSQL_REPLICA_PARAMS", &cfg.Database.Replica.Params)
+  env.string("SQLITE_PATH", &cfg.Database.SQLite.Path)
+  env.int("DB_MAX_OPEN_CONNS", &cfg.Database.MaxOpenConns)
+  env.int("DB_MAX_IDLE_CONNS", &cfg.Database.MaxIdleConns)
+  env.duration("DB_CONN_MAX_LIFETIME_SECONDS", time.Second, &cfg.Database.ConnMaxLifetime)
+  env.duration("DB_CONN_MAX_IDLE_TIME_SECONDS", time.Second, &cfg.Database.ConnMaxIdleTime)
+  env.duration("DB_PING_TIMEOUT_SECONDS", time.Second, &cfg.Database.PingTimeout)
+  env.duration("DB_CONNECT_TIMEOUT_SECONDS", time.Second, &cfg.Database.ConnectTimeout)
+  env.duration("DB_QUERY_TIMEOUT_SECONDS", time.Second, &cfg.Database.QueryTimeout)
+  env.duration("DB_AUTH_QUERY_TIMEOUT_SECONDS", time.Second, &cfg.Database.AuthQueryTimeout)
+  env.duration("DB_PAYOUT_TIMEOUT_SECONDS", time.Second, &cfg.Database.PayoutTimeout)
+
+  env.secret("JWT_SECRET", &cfg.JWT.Secret)
+  env.string("JWT_ISSUER", &cfg.JWT.Issuer)
+  env.duration("JWT_TTL_MINUTES", time.Minute, &cfg.JWT.TTL)
+  env.jwtKeys("JWT_KEYS", &cfg.JWT.Keys)
+  env.string("JWT_SIGNING_KID", &cfg.JWT.SigningKID)
+
+  env.int("PAGINATION_DEFAULT_PAGE_SIZE", &cfg.Pagination.DefaultPageSize)
+  env.int("PAGINATION_MAX_PAGE_SIZE", &cfg.Pagination.MaxPageSize)
+
+  env.string("PAYOUT_ORIGINATOR_NAME", &cfg.Payout.OriginatorName)
+  env.secret("PAYOUT_DEBTOR_ACCOUNT", &cfg.Payout.DebtorAccount)
+  env.string("PAYOUT_DEBTOR_BIC", &cfg.Payout.DebtorBIC)
+  env.string("PAYOUT_NACHA_IMMEDIATE_DESTINATION", &cfg.Payout.NACHAImmediateDestination)
+  env.string("PAYOUT_NACHA_IMMEDIATE_ORIGIN", &cfg.Payout.NACHAImmediateOrigin)
+  env.string("PAYOUT_NACHA_DESTINATION_NAME", &cfg.Payout.NACHADestinationName)
+  env.string("PAYOUT_NACHA_COMPANY_ID", &cfg.Payout.NACHACompanyID)
+  env.string("PAYOUT_NACHA_ODFI_ROUTING", &cfg.Payout.NACHAODFIRouting)
+  env.string("PAYOUT_CPA005_ORIGINATOR_ID", &cfg.Payout.CPA005OriginatorID)
+  env.string("PAYOUT_CPA005_DATA_CENTRE", &cfg.Payout.CPA005DataCentre)
+  env.string("PAYOUT_CPA005_RETURN_INSTITUTION", &cfg.Payout.CPA005ReturnInstitution)
+  env.secret("PAYOUT_CPA005_RETURN_ACCOUNT", &cfg.Payout.CPA005ReturnAccount)
+
+  env.string("LOG_LEVEL", &cfg.Log.Level)
+  env.string("LOG_FORMAT", &cfg.Log.Format)
+
+  env.string("TRACING_EXPORTER", &cfg.Tracing.Exporter)
+  env.string("TRACING_OTLP_ENDPOINT", &cfg.Tracing.Endpoint)
+  env.float("TRACING_SAMPLE_RATIO", &cfg.Tracing.SampleRatio)
+  env.string("TRACING_SERVICE_NAME", &cfg.Tracing.ServiceName)
+
+  env.string("RATE_LIMIT_STORE", &cfg.RateLimit.Store)
+  env.int("RATE_LIMIT_MERCHANT_PER_MINUTE", &cfg.RateLimit.Merchant.PerMinute)
+  env.int("RATE_LIMIT_MERCHANT_BURST", &cfg.RateLimit.Merchant.Burst)
+  env.int("RATE_LIMIT_IP_PER_MINUTE", &cfg.RateLimit.IP.PerMinute)
+  env.int("RATE_LIMIT_IP_BURST", &cfg.RateLimit.IP.Burst)
+  env.int("RATE_LIMIT_LOGIN_PER_MINUTE", &cfg.RateLimit.Login.PerMinute)
+  env.int("RATE_LIMIT_LOGIN_BURST", &cfg.RateLimit.Login.Burst)
+  env.bool("RATE_LIMIT_TRUST_FORWARDED_FOR", &cfg.RateLimit.TrustForwardedFor)
+
+  env.list("CORS_ADMIN_ORIGINS", &cfg.CORS.AdminOrigins)
+  env.list("CORS_MERCHANT_ORIGINS", &cfg.CORS.MerchantOrigins)
+  env.bool("CORS_ALLOW_CREDENTIALS", &cfg.CORS.AllowCredentials)
+  env.duration("CORS_MAX_AGE_SECONDS", time.Second, &cfg.CORS.MaxAge)
+
+  env.int("API_KEY_CACHE_SIZE", &cfg.APIKeyCache.Size)
+  env.duration("API_KEY_CACHE_TTL_SECONDS", time.Second, &cfg.APIKeyCache.TTL)
+  env.duration("API_KEY_CACHE_NEGATIVE_TTL_SECONDS", time.Second, &cfg.APIKeyCache.NegativeTTL)
+
+  return cfg, errors.Join(env.errs...)
+}
+
+// loadFile decodes a .yaml, .yml or .toml file over cfg. Unknown keys are
+// rejected so typos do not silently fall back to defaults.
+func loadFile(path string, cfg *Config) error {
+  f, err := os.Open(path)
+  if err != nil {
+    return fmt.Errorf("config file: %w", err)
+  }
+  defer f.Close()
+
+  switch strings.ToLower(filepath.Ext(path)) {
+  case ".yaml", ".yml":
+    dec := yaml.NewDecoder(f)
+    dec.KnownFields(true)
+    if err := dec.Decode(cfg); err != nil {
+      return fmt.Errorf("config file %s: %w", path, err)
+    }
+  case ".toml":
+    meta, err := toml.NewDecoder(f).Decode(cfg)
+    if err != nil {
+      return fmt.Errorf("config file %s: %w", path, err)
+    }
+    if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+      keys := make([]string, len(undecoded))
+      for i, key := range undecoded {
+        keys[i] = key.String()
+      }
+      return fmt.Errorf("config file %s: unknown keys %s", path, strings.Join(keys, ", "))
+    }
+  default:
+    return fmt.Errorf("config file %s: unsupported extension (use .yaml, .yml or .toml)", path)
+  }
+  return nil
+}
+
+// envLoader copies non-empty environment variables into config fields and
+// collects parse errors.
+type envLoader struct {
+  errs []error
+}
+
+func (l *envLoader) string(key string, dst *string) {
+  if raw := os.Getenv(key); raw != "" {
+    *dst = raw
+  }
+}
+
+// secret also accepts KEY_FILE, the path of a file holding the value, as
+// mounted by Docker and Kubernetes secrets.
+func (l *envLoader) secret(key string, dst *string) {
+  path := os.Getenv(key + "_FILE")
+  if path == "" {
+    l.string(key, dst)
+    return
+  }
+  if os.Getenv(key) != "" {
+    l.errs = append(l.errs, fmt.Errorf("set only one of %s and %s_FILE", key, key))
+    return
+  }
+  data, err := os.ReadFile(path)
+  if err != nil {
+    l.errs = append(l.errs, fmt.Errorf("%s_FILE: %w", key, err))
+    return
+  }
+  *dst = strings.TrimRight(string(data), "\r\n")
+}
+
+// jwtKeys reads a comma-separated list of kid=path pairs.
+func (l *envLoader) jwtKeys(key string, dst *[]JWTKey) {
+  raw := os.Getenv(key)
+  if raw == "" {
+    return
+  }
+  var keys []JWTKey
+  for _, entry := range strings.Split(raw, ",") {
+    kid, path, ok := strings.Cut(strings.TrimSpace(entry), "=")
+    if !ok || kid == "" || path == "" {
+      l.errs = append(l.errs, fmt.Errorf("%s must be a comma-separated list of kid=path", key))
+      return
+    }
+    keys = append(keys, JWTKey{KID: kid, File: path})
+  }
+  *dst = keys
+}
+
+func (l *envLoader) int(key string, dst *int) {
+  raw := os.Getenv(key)
+  if raw == "" {
+    return
+  }
+  parsed, err := strconv.Atoi(raw)
+  if err != nil {
+    l.errs = append(l.errs, fmt.Errorf("%s must be an integer", key))
+    return
+  }
+  *dst = parsed
+}
+
+// list reads a comma-separated list, dropping empty entries.
+func (l *envLoader) list(key string, dst *[]string) {
+  raw := os.Getenv(key)
+  if raw == "" {
+    return
+  }
+  var items []string
+  for _, item := range strings.Split(raw, ",") {
+    if item = strings.TrimSpace(item); item != "" {
+      items = append(items, item)
+    }
+  }
+  *dst = items
+}
+
+func (l *envLoader) bool(key string, dst *bool) {
+  raw := os.Getenv(key)
+  if raw == "" {
+    return
+  }
+  parsed, err := strconv.ParseBool(raw)
+  if err != nil {
+    l.errs = append(l.errs, fmt.Errorf("%s must be true or false", key))
+    return
+  }
+  *dst = parsed
+}
+
+func (l *envLoader) float(key string, dst *float64) {
+  raw := os.Getenv(key)
+  if raw == "" {
+    return
+  }
+  parsed, err := strconv.ParseFloat(raw, 64)
+  if err != nil {
+    l.errs = append(l.errs, fmt.Errorf("%s must be a number", key))
+    return
+  }
+  *dst = parsed
+}
+
+// duration reads an integer count of unit, e.g. JWT_TTL_MINUTES.
+func (l *envLoader) duration(key string, unit time.Duration, dst *time.Duration) {
+  raw := os.Getenv(key)
+  if raw == "" {
+    return
+  }
+  parsed, err := strconv.Atoi(raw)
+  if err != nil {
+    l.errs = append(l.errs, fmt.Errorf("%s must be an integer", key))
+    return
+  }
+  *dst = time.Duration(parsed) * unit
+}
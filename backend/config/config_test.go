@@ -0,0 +1,254 @@
+package config
+
+import (
+  "bytes"
+  "os"
+  "path/filepath"
+  "strings"
+  "testing"
+  "time"
+)
+
+func writeFile(t *testing.T, name string, content string) string {
+  t.Helper()
+
+  path := filepath.Join(t.TempDir(), name)
+  if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+    t.Fatal(err)
+  }
+  return path
+}
+
+func TestLoadFile(t *testing.T) {
+  tests := []struct {
+    name    string
+    file    string
+    content string
+  }{
+    {
+      name: "yaml",
+      file: "config.yaml",
+      content: `
+http:
+  addr: ":9090"
+  shutdown_timeout: 30s
+database:
+  driver: sqlite
+  sqlite:
+    path: /tmp/test.db
+jwt:
+  secret: from-file
+  ttl: 2h
+pagination:
+  max_page_size: 50
+`,
+    },
+    {
+      name: "toml",
+      file: "config.toml",
+      content: `
+[http]
+addr = ":9090"
+shutdown_timeout = "30s"
+
+[database]
+driver = "sqlite"
+sqlite = { path = "/tmp/test.db" }
+
+[jwt]
+secret = "from-file"
+ttl = "2h"
+
+[pagination]
+max_page_size = 50
+`,
+    },
+  }
+
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      cfg, err := Load(writeFile(t, tt.file, tt.content))
+      if err != nil {
+        t.Fatalf("Load: %v", err)
+      }
+      if err := cfg.Validate(); err != nil {
+        t.Fatalf("Validate: %v", err)
+      }
+
+      if cfg.HTTP.Addr != ":9090" || cfg.HTTP.ShutdownTimeout != 30*time.Second {
+        t.Errorf("http = %+v", cfg.HTTP)
+      }
+      if cfg.Database.Driver != "sqlite" || cfg.Database.SQLite.Path != "/tmp/test.db" {
+        t.Errorf("database = %+v", cfg.Database)
+      }
+      if cfg.JWT.Secret != "from-file" || cfg.JWT.TTL != 2*time.Hour {
+        t.Errorf("jwt = %+v", cfg.JWT)
+      }
+      // Keys absent from the file keep their defaults.
+      if cfg.HTTP.ReadTimeout != 5*time.Second || cfg.Pagination.DefaultPageSize != 20 || cfg.Pagination.MaxPageSize != 50 {
+        t.Errorf("defaults not kept: http = %+v, pagination = %+v", cfg.HTTP, cfg.Pagination)
+      }
+    })
+  }
+}
+
+func TestLoadEnvOverridesFile(t *testing.T) {
+  path := writeFile(t, "config.yaml", "http:\n  addr: \":9090\"\njwt:\n  secret: from-file\n")
+  t.Setenv("HTTP_ADDR", ":7070")
+  t.Setenv("JWT_TTL_MINUTES", "15")
+
+  cfg, err := Load(path)
+  if err != nil {
+    t.Fatalf("Load: %v", err)
+  }
+  if cfg.HTTP.Addr != ":7070" {
+    t.Errorf("addr = %q, want env value", cfg.HTTP.Addr)
+  }
+  if cfg.JWT.Secret != "from-file" {
+    t.Errorf("secret = %q, want file value", cfg.JWT.Secret)
+  }
+  if cfg.JWT.TTL != 15*time.Minute {
+    t.Errorf("ttl = %s, want 15m", cfg.JWT.TTL)
+  }
+}
+
+func TestLoadErrors(t *testing.T) {
+  tests := []struct {
+    name string
+    file string
+    env  map[string]string
+    want []string
+  }{
+    {
+      name: "unknown yaml key",
+      file: writeFile(t, "config.yaml", "http:\n  adress: \":9090\"\n"),
+      want: []string{"adress"},
+    },
+    {
+      name: "unknown toml key",
+      file: writeFile(t, "config.toml", "[http]\nadress = \":9090\"\n"),
+      want: []string{"http.adress"},
+    },
+    {
+      name: "unsupported extension",
+      file: writeFile(t, "config.json", "{}"),
+      want: []string{"unsupported extension"},
+    },
+    {
+      name: "malformed env values are all reported",
+      env:  map[string]string{"JWT_TTL_MINUTES": "soon", "DB_MAX_OPEN_CONNS": "many"},
+      want: []string{"JWT_TTL_MINUTES must be an integer", "DB_MAX_OPEN_CONNS must be an integer"},
+    },
+  }
+
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      for k, v := range tt.env {
+        t.Setenv(k, v)
+      }
+      _, err := Load(tt.file)
+      if err == nil {
+        t.Fatal("Load succeeded, want error")
+      }
+      for _, want := range tt.want {
+        if !strings.Contains(err.Error(), want) {
+          t.Errorf("error %q does not mention %q", err, want)
+        }
+      }
+    })
+  }
+}
+
+func TestValidate(t *testing.T) {
+  cfg := Default()
+  cfg.HTTP.ReadTimeout = 0
+  cfg.Pagination.MaxPageSize = 10
+  cfg.Log.Format = "xml"
+  cfg.CORS.AdminOrigins = []string{"admin.example.com"}
+
+  err := cfg.Validate()
+  if err == nil {
+    t.Fatal("Validate succeeded, want error")
+  }
+  for _, want := range []string{
+    "http.read_timeout (HTTP_READ_TIMEOUT_SECONDS) must be positive",
+    "database.mysql.host (MYSQL_HOST) is required",
+    "database.mysql.password (MYSQL_PASSWORD) is required",
+    "jwt.secret (JWT_SECRET) or jwt.keys (JWT_KEYS) is required",
+    "pagination.max_page_size (PAGINATION_MAX_PAGE_SIZE) must be at least the default page size",
+    `log (LOG_LEVEL, LOG_FORMAT): unknown format "xml"`,
+    `cors.admin_origins (CORS_ADMIN_ORIGINS): "admin.example.com" is not an origin`,
+  } {
+    if !strings.Contains(err.Error(), want) {
+      t.Errorf("error does not mention %q:\n%v", want, err)
+    }
+  }
+}
+
+func TestPrintRedactsSecrets(t *testing.T) {
+  cfg := Default()
+  cfg.Database.MySQL.Password = "db-password"
+  cfg.JWT.Secret = "jwt-secret"
+  cfg.Payout.DebtorAccount = "123456789"
+
+  var buf bytes.Buffer
+  if err := cfg.Print(&buf); err != nil {
+    t.Fatal(err)
+  }
+  out := buf.String()
+  for _, secret := range []string{"db-password", "jwt-secret", "123456789"} {
+    if strings.Contains(out, secret) {
+      t.Errorf("printed config contains %q:\n%s", secret, out)
+    }
+  }
+  if !strings.Contains(out, "read_timeout: 5s") {
+    t.Errorf("printed config missing durations:\n%s", out)
+  }
+}
+
+func TestLoadSecretFiles(t *testing.T) {
+  t.Setenv("JWT_SECRET_FILE", writeFile(t, "jwt_secret", "from-secret-file\n"))
+  t.Setenv("MYSQL_PASSWORD_FILE", writeFile(t, "mysql_password", "db-password"))
+
+  cfg, err := Load("")
+  if err != nil {
+    t.Fatalf("Load: %v", err)
+  }
+  if cfg.JWT.Secret != "from-secret-file" {
+    t.Errorf("jwt secret = %q, want trailing newline trimmed", cfg.JWT.Secret)
+  }
+  if cfg.Database.MySQL.Password != "db-password" {
+    t.Errorf("mysql password = %q", cfg.Database.MySQL.Password)
+  }
+
+  t.Setenv("JWT_SECRET", "also-set")
+  if _, err := Load(""); err == nil || !strings.Contains(err.Error(), "set only one of JWT_SECRET and JWT_SECRET_FILE") {
+    t.Errorf("Load with both JWT_SECRET and JWT_SECRET_FILE: err = %v", err)
+  }
+}
+
+func TestJWTKeySet(t *testing.T) {
+  t.Setenv("JWT_SECRET", "legacy-secret")
+  t.Setenv("JWT_KEYS", "2025-10="+writeFile(t, "new", "new-secret")+", 2025-04="+writeFile(t, "old", "old-secret"))
+
+  cfg, err := Load("")
+  if err != nil {
+    t.Fatalf("Load: %v", err)
+  }
+  if len(cfg.JWT.Keys) != 2 || cfg.JWT.Keys[0].KID != "2025-10" || cfg.JWT.Keys[1].KID != "2025-04" {
+    t.Fatalf("keys = %+v", cfg.JWT.Keys)
+  }
+  if _, err := cfg.JWT.KeySet(); err != nil {
+    t.Fatalf("KeySet: %v", err)
+  }
+
+  cfg.JWT.SigningKID = "missing"
+  if _, err := cfg.JWT.KeySet(); err == nil {
+    t.Error("KeySet with unknown signing kid succeeded")
+  }
+
+  t.Setenv("JWT_KEYS", "no-path")
+  if _, err := Load(""); err == nil || !strings.Contains(err.Error(), "JWT_KEYS must be") {
+    t.Errorf("Load with malformed JWT_KEYS: err = %v", err)
+  }
+}
@@ -0,0 +1,39 @@
+package config
+
+import (
+  "io"
+
+  "gopkg.in/yaml.v3"
+)
+
+const redacted = "<redacted>"
+
+// Redacted returns a copy with secrets and bank account numbers masked.
+func (c Config) Redacted() Config {
+  mask := func(s *string) {
+    if *s != "" {
+      *s = redacted
+    }
+  }
+  mask(&c.Database.MySQL.Password)
+  mask(&c.Database.Replica.Password)
+  mask(&c.JWT.Secret)
+  mask(&c.Payout.DebtorAccount)
+  mask(&c.Payout.CPA005ReturnAccount)
+  c.JWT.Keys = append([]JWTKey(nil), c.JWT.Keys...)
+  for i := range c.JWT.Keys {
+    mask(&c.JWT.Keys[i].Secret)
+  }
+  return c
+}
+
+// Print writes the redacted configuration as YAML, in the same layout
+// accepted by a config file.
+func (c Config) Print(w io.Writer) error {
+  enc := yaml.NewEncoder(w)
+  enc.SetIndent(2)
+  if err := enc.Encode(c.Redacted()); err != nil {
+    return err
+  }
+  return enc.Close()
+}
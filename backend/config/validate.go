@@ -0,0 +1,309 @@
+package config
+
+import (
+  "bytes"
+  "errors"
+  "fmt"
+  "io"
+  "log/slog"
+  "net/url"
+  "os"
+  "strings"
+  "time"
+
+  "sarah-project-backend/database"
+  "sarah-project-backend/logging"
+  "sarah-project-backend/security"
+  "sarah-project-backend/tracing"
+)
+
+// defaultKID identifies the key built from jwt.secret.
+const defaultKID = "default"
+
+// Validate reports every invalid setting at once.
+func (c Config) Validate() error {
+  var v validator
+
+  v.require(c.HTTP.Addr, "http.addr (HTTP_ADDR)")
+  v.positive(c.HTTP.ReadTimeout, "http.read_timeout (HTTP_READ_TIMEOUT_SECONDS)")
+  v.positive(c.HTTP.WriteTimeout, "http.write_timeout (HTTP_WRITE_TIMEOUT_SECONDS)")
+  v.positive(c.HTTP.IdleTimeout, "http.idle_timeout (HTTP_IDLE_TIMEOUT_SECONDS)")
+  v.positive(c.HTTP.ShutdownTimeout, "http.shutdown_timeout (SHUTDOWN_TIMEOUT_SECONDS)")
+
+  v.add(c.Database.Validate())
+
+  if c.JWT.Secret == "" && len(c.JWT.Keys) == 0 {
+    v.errorf("jwt.secret (JWT_SECRET) or jwt.keys (JWT_KEYS) is required")
+  } else if _, err := c.JWT.KeySet(); err != nil {
+    v.errorf("jwt: %v", err)
+  }
+  v.require(c.JWT.Issuer, "jwt.issuer (JWT_ISSUER)")
+  v.positive(c.JWT.TTL, "jwt.ttl (JWT_TTL_MINUTES)")
+
+  if c.Pagination.DefaultPageSize <= 0 {
+    v.errorf("pagination.default_page_size (PAGINATION_DEFAULT_PAGE_SIZE) must be positive")
+  }
+  if c.Pagination.MaxPageSize < c.Pagination.DefaultPageSize {
+    v.errorf("pagination.max_page_size (PAGINATION_MAX_PAGE_SIZE) must be at least the default page size")
+  }
+
+  if _, err := c.Log.Logger(io.Discard); err != nil {
+    v.errorf("log (LOG_LEVEL, LOG_FORMAT): %v", err)
+  }
+
+  v.add(c.Tracing.validate())
+  v.add(c.RateLimit.validate())
+  v.add(c.CORS.validate())
+  if c.APIKeyCache.Size < 0 {
+    v.errorf("api_key_cache.size (API_KEY_CACHE_SIZE) must not be negative")
+  }
+  if c.APIKeyCache.TTL < 0 || c.APIKeyCache.NegativeTTL < 0 {
+    v.errorf("api_key_cache.ttl and negative_ttl (API_KEY_CACHE_TTL_SECONDS, API_KEY_CACHE_NEGATIVE_TTL_SECONDS) must not be negative")
+  }
+  if c.HTTP.HSTSMaxAge < 0 {
+    v.errorf("http.hsts_max_age (HTTP_HSTS_MAX_AGE_SECONDS) must not be negative")
+  }
+
+  return v.err()
+}
+
+// Validate reports invalid database settings. It is separate so tools that
+// only need a connection, like cmd/migrate, can skip the rest.
+func (d Database) Validate() error {
+  var v validator
+
+  driver, err := database.ParseDriver(d.Driver)
+  if err != nil {
+    v.errorf("database.driver (DB_DRIVER): %v", err)
+  }
+  switch driver {
+  case database.MySQL:
+    v.require(d.MySQL.Host, "database.mysql.host (MYSQL_HOST)")
+    v.require(d.MySQL.Port, "database.mysql.port (MYSQL_PORT)")
+    v.require(d.MySQL.User, "database.mysql.user (MYSQL_USER)")
+    v.require(d.MySQL.Password, "database.mysql.password (MYSQL_PASSWORD)")
+    v.require(d.MySQL.Name, "database.mysql.name (MYSQL_DB)")
+  case database.SQLite:
+    v.require(d.SQLite.Path, "database.sqlite.path (SQLITE_PATH)")
+    if d.Replica.Host != "" {
+      v.errorf("database.replica (MYSQL_REPLICA_HOST) requires the mysql driver")
+    }
+  }
+
+  if d.MaxOpenConns < 0 {
+    v.errorf("database.max_open_conns (DB_MAX_OPEN_CONNS) must not be negative")
+  }
+  if d.MaxIdleConns < 0 {
+    v.errorf("database.max_idle_conns (DB_MAX_IDLE_CONNS) must not be negative")
+  }
+  if d.MaxOpenConns > 0 && d.MaxIdleConns > d.MaxOpenConns {
+    v.errorf("database.max_idle_conns (DB_MAX_IDLE_CONNS) must not exceed max_open_conns")
+  }
+  if d.ConnMaxLifetime < 0 {
+    v.errorf("database.conn_max_lifetime (DB_CONN_MAX_LIFETIME_SECONDS) must not be negative")
+  }
+  if d.ConnMaxIdleTime < 0 {
+    v.errorf("database.conn_max_idle_time (DB_CONN_MAX_IDLE_TIME_SECONDS) must not be negative")
+  }
+  if d.ConnectTimeout < 0 {
+    v.errorf("database.connect_timeout (DB_CONNECT_TIMEOUT_SECONDS) must not be negative")
+  }
+  v.positive(d.PingTimeout, "database.ping_timeout (DB_PING_TIMEOUT_SECONDS)")
+  v.positive(d.QueryTimeout, "database.query_timeout (DB_QUERY_TIMEOUT_SECONDS)")
+  v.positive(d.AuthQueryTimeout, "database.auth_query_timeout (DB_AUTH_QUERY_TIMEOUT_SECONDS)")
+  v.positive(d.PayoutTimeout, "database.payout_timeout (DB_PAYOUT_TIMEOUT_SECONDS)")
+
+  return v.err()
+}
+
+// Options converts the settings for database.Open. Call Validate first.
+func (d Database) Options() database.Config {
+  driver, _ := database.ParseDriver(d.Driver)
+  return database.Config{
+    Driver: driver,
+    MySQL: database.MySQLConfig{
+      Host:     d.MySQL.Host,
+      Port:     d.MySQL.Port,
+      User:     d.MySQL.User,
+      Password: d.MySQL.Password,
+      Name:     d.MySQL.Name,
+      Params:   d.MySQL.Params,
+    },
+    Replica:         d.replica(),
+    SQLitePath:      d.SQLite.Path,
+    MaxOpenConns:    d.MaxOpenConns,
+    MaxIdleConns:    d.MaxIdleConns,
+    ConnMaxLifetime: d.ConnMaxLifetime,
+    ConnMaxIdleTime: d.ConnMaxIdleTime,
+    PingTimeout:     d.PingTimeout,
+    ConnectTimeout:  d.ConnectTimeout,
+  }
+}
+
+// Logger returns a logger writing to w at the configured level and format.
+func (l Log) Logger(w io.Writer) (*slog.Logger, error) {
+  return logging.New(w, l.Format, l.Level)
+}
+
+func (t Tracing) validate() error {
+  var v validator
+  switch t.Exporter {
+  case tracing.ExporterNone, tracing.ExporterStdout:
+  case tracing.ExporterOTLP:
+    if t.Endpoint != "" {
+      u, err := url.Parse(t.Endpoint)
+      if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+        v.errorf("tracing.endpoint (TRACING_OTLP_ENDPOINT) must be an http or https URL")
+      }
+    }
+  default:
+    v.errorf("tracing.exporter (TRACING_EXPORTER) must be none, otlp or stdout")
+  }
+  if t.SampleRatio < 0 || t.SampleRatio > 1 {
+    v.errorf("tracing.sample_ratio (TRACING_SAMPLE_RATIO) must be between 0 and 1")
+  }
+  v.require(t.ServiceName, "tracing.service_name (TRACING_SERVICE_NAME)")
+  return v.err()
+}
+
+func (c CORS) validate() error {
+  var v validator
+  for _, list := range []struct {
+    origins   []string
+    name, env string
+  }{
+    {c.AdminOrigins, "admin_origins", "CORS_ADMIN_ORIGINS"},
+    {c.MerchantOrigins, "merchant_origins", "CORS_MERCHANT_ORIGINS"},
+  } {
+    for _, origin := range list.origins {
+      if origin == "*" {
+        if c.AllowCredentials {
+          v.errorf("cors.%s (%s): \"*\" cannot be combined with cors.allow_credentials", list.name, list.env)
+        }
+        continue
+      }
+      u, err := url.Parse(origin)
+      if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || (u.Path != "" && u.Path != "/") || u.RawQuery != "" {
+        v.errorf("cors.%s (%s): %q is not an origin like https://admin.example.com", list.name, list.env, origin)
+      }
+    }
+  }
+  if c.MaxAge < 0 {
+    v.errorf("cors.max_age (CORS_MAX_AGE_SECONDS) must not be negative")
+  }
+  return v.err()
+}
+
+func (r RateLimit) validate() error {
+  var v validator
+  if r.Store != "memory" && r.Store != "database" {
+    v.errorf("rate_limit.store (RATE_LIMIT_STORE) must be memory or database")
+  }
+  for _, rule := range []struct {
+    RateLimitRule
+    name, env string
+  }{
+    {r.Merchant, "merchant", "MERCHANT"},
+    {r.IP, "ip", "IP"},
+    {r.Login, "login", "LOGIN"},
+  } {
+    if rule.PerMinute < 0 {
+      v.errorf("rate_limit.%s.per_minute (RATE_LIMIT_%s_PER_MINUTE) must not be negative", rule.name, rule.env)
+    }
+    if rule.PerMinute > 0 && rule.Burst <= 0 {
+      v.errorf("rate_limit.%s.burst (RATE_LIMIT_%s_BURST) must be positive", rule.name, rule.env)
+    }
+  }
+  return v.err()
+}
+
+// KeySet loads the configured JWT keys. Call Validate first.
+func (j JWT) KeySet() (*security.KeySet, error) {
+  keys := make([]security.Key, 0, len(j.Keys)+1)
+  for _, k := range j.Keys {
+    material := []byte(k.Secret)
+    if k.File != "" {
+      if k.Secret != "" {
+        return nil, fmt.Errorf("key %s: set only one of file and secret", k.KID)
+      }
+      data, err := os.ReadFile(k.File)
+      if err != nil {
+        return nil, fmt.Errorf("key %s: %w", k.KID, err)
+      }
+      material = bytes.TrimRight(data, "\r\n")
+    }
+    key, err := security.ParseKey(k.KID, material)
+    if err != nil {
+      return nil, err
+    }
+    keys = append(keys, key)
+  }
+
+  signingKID := j.SigningKID
+  if j.Secret != "" {
+    key, err := security.ParseKey(defaultKID, []byte(j.Secret))
+    if err != nil {
+      return nil, err
+    }
+    keys = append(keys, key)
+    if signingKID == "" && len(j.Keys) == 0 {
+      signingKID = defaultKID
+    }
+  }
+  if signingKID == "" && len(j.Keys) > 0 {
+    signingKID = j.Keys[0].KID
+  }
+  return security.NewKeySet(signingKID, keys...)
+}
+
+// replica fills unset replica fields from the primary. It returns nil when
+// no replica is configured.
+func (d Database) replica() *database.MySQLConfig {
+  if d.Replica.Host == "" {
+    return nil
+  }
+  orDefault := func(value string, fallback string) string {
+    if value == "" {
+      return fallback
+    }
+    return value
+  }
+  return &database.MySQLConfig{
+    Host:     d.Replica.Host,
+    Port:     orDefault(d.Replica.Port, d.MySQL.Port),
+    User:     orDefault(d.Replica.User, d.MySQL.User),
+    Password: orDefault(d.Replica.Password, d.MySQL.Password),
+    Name:     orDefault(d.Replica.Name, d.MySQL.Name),
+    Params:   orDefault(d.Replica.Params, d.MySQL.Params),
+  }
+}
+
+type validator struct {
+  errs []error
+}
+
+func (v *validator) errorf(format string, args ...any) {
+  v.errs = append(v.errs, fmt.Errorf(format, args...))
+}
+
+func (v *validator) add(err error) {
+  if err != nil {
+    v.errs = append(v.errs, err)
+  }
+}
+
+func (v *validator) require(value string, name string) {
+  if strings.TrimSpace(value) == "" {
+    v.errorf("%s is required", name)
+  }
+}
+
+func (v *validator) positive(d time.Duration, name string) {
+  if d <= 0 {
+    v.errorf("%s must be positive", name)
+  }
+}
+
+func (v *validator) err() error {
+  return errors.Join(v.errs...)
+}
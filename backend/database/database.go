@@ -0,0 +1,203 @@
+package database
+
+import (
+  "context"
+  "database/sql"
+  "fmt"
+  "log/slog"
+  "strings"
+  "time"
+
+  "github.com/XSAM/otelsql"
+  _ "github.com/go-sql-driver/mysql"
+  semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+  _ "modernc.org/sqlite"
+)
+
+// Driver names a supported database backend.
+type Driver string
+
+const (
+  MySQL  Driver = "mysql"
+  SQLite Driver = "sqlite"
+)
+
+// ParseDriver normalizes a DB_DRIVER value. An empty value selects MySQL.
+func ParseDriver(raw string) (Driver, error) {
+  switch strings.ToLower(strings.TrimSpace(raw)) {
+  case "", "mysql":
+    return MySQL, nil
+  case "sqlite", "sqlite3":
+    return SQLite, nil
+  default:
+    return "", fmt.Errorf("unsupported database driver %q (use mysql or sqlite)", raw)
+  }
+}
+
+// Config selects a backend and its connection settings.
+type Config struct {
+  Driver Driver
+  MySQL  MySQLConfig
+  // Replica is an optional MySQL read replica.
+  Replica    *MySQLConfig
+  SQLitePath string
+
+  // Pool settings apply to MySQL only; SQLite always uses one connection.
+  MaxOpenConns    int
+  MaxIdleConns    int
+  ConnMaxLifetime time.Duration
+  ConnMaxIdleTime time.Duration
+
+  // PingTimeout bounds each connection attempt. ConnectTimeout bounds the
+  // whole startup, including retries; zero tries once.
+  PingTimeout    time.Duration
+  ConnectTimeout time.Duration
+}
+
+// MySQLConfig holds the parts of a MySQL DSN.
+type MySQLConfig struct {
+  Host     string
+  Port     string
+  User     string
+  Password string
+  Name     string
+  Params   string
+}
+
+// DSN formats the settings for the go-sql-driver/mysql driver.
+func (c MySQLConfig) DSN() string {
+  return fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?%s", c.User, c.Password, c.Host, c.Port, c.Name, c.Params)
+}
+
+// Open connects to the configured backend and verifies the connection with
+// a ping. Failed pings are retried with exponential backoff until
+// ConnectTimeout elapses, so a database that is still starting does not
+// crash the service.
+func Open(cfg Config) (*sql.DB, error) {
+  var (
+    db  *sql.DB
+    err error
+  )
+  switch cfg.Driver {
+  case MySQL:
+    db, err = openMySQL(cfg, cfg.MySQL)
+  case SQLite:
+    db, err = OpenSQLite(cfg.SQLitePath)
+  default:
+    return nil, fmt.Errorf("unsupported database driver %q", cfg.Driver)
+  }
+  if err != nil {
+    return nil, err
+  }
+
+  if err := pingWithRetry(db, cfg.pingTimeout(), cfg.ConnectTimeout); err != nil {
+    db.Close()
+    return nil, err
+  }
+  return db, nil
+}
+
+// OpenReplica opens the configured read replica, or returns nil when there
+// is none. An unreachable replica is logged rather than returned as an error
+// because reads fall back to the primary.
+func OpenReplica(cfg Config) (*sql.DB, error) {
+  if cfg.Replica == nil {
+    return nil, nil
+  }
+  db, err := openMySQL(cfg, *cfg.Replica)
+  if err != nil {
+    return nil, err
+  }
+
+  ctx, cancel := context.WithTimeout(context.Background(), cfg.pingTimeout())
+  defer cancel()
+  if err := db.PingContext(ctx); err != nil {
+    slog.Warn("read replica not reachable, reads will use the primary until it is", "error", err)
+  }
+  return db, nil
+}
+
+// open wraps the driver so each query, exec and transaction is traced as a
+// child of the span in its context.
+func open(driver Driver, dsn string) (*sql.DB, error) {
+  system := semconv.DBSystemMySQL
+  if driver == SQLite {
+    system = semconv.DBSystemSqlite
+  }
+  return otelsql.Open(string(driver), dsn,
+    otelsql.WithAttributes(system),
+    otelsql.WithSpanOptions(otelsql.SpanOptions{
+      DisableErrSkip:       true,
+      OmitConnResetSession: true,
+      OmitRows:             true,
+    }),
+  )
+}
+
+func openMySQL(cfg Config, conn MySQLConfig) (*sql.DB, error) {
+  db, err := open(MySQL, conn.DSN())
+  if err != nil {
+    return nil, err
+  }
+  db.SetMaxOpenConns(cfg.MaxOpenConns)
+  db.SetMaxIdleConns(cfg.MaxIdleConns)
+  db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+  db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+  return db, nil
+}
+
+const (
+  initialBackoff = 250 * time.Millisecond
+  maxBackoff     = 5 * time.Second
+)
+
+func (c Config) pingTimeout() time.Duration {
+  if c.PingTimeout <= 0 {
+    return 3 * time.Second
+  }
+  return c.PingTimeout
+}
+
+func pingWithRetry(db *sql.DB, pingTimeout time.Duration, connectTimeout time.Duration) error {
+  deadline := time.Now().Add(connectTimeout)
+  backoff := initialBackoff
+
+  for attempt := 1; ; attempt++ {
+    ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+    err := db.PingContext(ctx)
+    cancel()
+    if err == nil {
+      return nil
+    }
+
+    if time.Now().Add(backoff).After(deadline) {
+      return fmt.Errorf("database unreachable after %d attempts: %w", attempt, err)
+    }
+    slog.Warn("database not ready", "attempt", attempt, "error", err, "retry_in", backoff.String())
+    time.Sleep(backoff)
+    backoff = min(backoff*2, maxBackoff)
+  }
+}
+
+// OpenSQLite opens a SQLite database at path. Use ":memory:" for a private
+// in-memory database.
+func OpenSQLite(path string) (*sql.DB, error) {
+  dsn := "file:" + path + "?" + strings.Join([]string{
+    "_pragma=foreign_keys(1)",
+    "_pragma=busy_timeout(5000)",
+    "_pragma=journal_mode(WAL)",
+    "_time_format=sqlite",
+    "_txlock=immediate",
+  }, "&")
+
+  db, err := open(SQLite, dsn)
+  if err != nil {
+    return nil, err
+  }
+  // SQLite allows a single writer, and every connection to ":memory:" is a
+  // separate database, so the pool is limited to one connection.
+  db.SetMaxOpenConns(1)
+  db.SetConnMaxLifetime(0)
+  db.SetConnMaxIdleTime(0)
+  return db, nil
+}
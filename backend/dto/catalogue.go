@@ -0,0 +1,31 @@
+package dto
+
+import (
+  "database/sql"
+  "time"
+)
+
+// CatalogueAssetDTO represents a supported (network, asset) pair read from database.
+type CatalogueAssetDTO struct {
+  ID              int64           `db:"id"`
+  Network         string          `db:"network"`
+  Asset           string          `db:"asset"`
+  ContractAddress string          `db:"contract_address"`
+  Decimals        int             `db:"decimals"`
+  MinAmount       sql.NullFloat64 `db:"min_amount"`
+  MaxAmount       sql.NullFloat64 `db:"max_amount"`
+  Enabled         bool            `db:"enabled"`
+  CreatedAt       time.Time       `db:"created_at"`
+  UpdatedAt       time.Time       `db:"updated_at"`
+}
+
+// PayoutCountryDTO represents an enabled payout country read from database.
+type PayoutCountryDTO struct {
+  ID        int64     `db:"id"`
+  Name      string    `db:"name"`
+  ISOCode   string    `db:"iso_code"`
+  Currency  string    `db:"currency"`
+  Enabled   bool      `db:"enabled"`
+  CreatedAt time.Time `db:"created_at"`
+  UpdatedAt time.Time `db:"updated_at"`
+}
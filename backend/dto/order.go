@@ -5,6 +5,15 @@ import (
   "time"
 )
 
+// Order statuses stored in the orders table.
+const (
+  StatusPaid          = "Paid"
+  StatusProcessing    = "Processing"
+  StatusSubmitted     = "Submitted"
+  StatusFailed        = "Failed"
+  StatusFundsReceived = "Funds Received"
+)
+
 // OrderDTO represents order fields read from database.
 type OrderDTO struct {
   ID                 int64          `db:"id"`
@@ -21,6 +30,9 @@ type OrderDTO struct {
   SWIFT              string         `db:"swift"`
   ReferenceNote      sql.NullString  `db:"reference_note"`
   Status             string         `db:"status"`
+  // IdempotencyKey is the merchant's Idempotency-Key header, if it sent
+  // one.
+  IdempotencyKey     sql.NullString  `db:"idempotency_key"`
   CreatedAt          time.Time      `db:"created_at"`
   UpdatedAt          time.Time      `db:"updated_at"`
 }
@@ -0,0 +1,19 @@
+package dto
+
+import (
+  "time"
+)
+
+// PayoutBatchDTO represents a generated payout file read from database.
+type PayoutBatchDTO struct {
+  ID          int64     `db:"id"`
+  BatchID     string    `db:"batch_id"`
+  Format      string    `db:"format"`
+  FileName    string    `db:"file_name"`
+  OrderCount  int       `db:"order_count"`
+  TotalAmount float64   `db:"total_amount"`
+  Content     string    `db:"content"`
+  CreatedBy   int64     `db:"created_by"`
+  CreatedAt   time.Time `db:"created_at"`
+  UpdatedAt   time.Time `db:"updated_at"`
+}
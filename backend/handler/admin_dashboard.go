@@ -1,21 +1,22 @@
 package handler
 
 import (
-  "context"
-  "database/sql"
-  "encoding/json"
-  "log"
+  "errors"
+  "log/slog"
   "net/http"
-  "strconv"
-  "strings"
   "time"
+
+  "sarah-project-backend/dto"
+  "sarah-project-backend/metrics"
+  "sarah-project-backend/store"
+  "sarah-project-backend/validation"
 )
 
 type adminStats struct {
-  FundsReceived int64 `json:"funds_received"`
-  Processing    int64 `json:"processing"`
+  FundsReceived  int64 `json:"funds_received"`
+  Processing     int64 `json:"processing"`
   ActionRequired int64 `json:"action_required"`
-  Awaiting      int64 `json:"awaiting"`
+  Awaiting       int64 `json:"awaiting"`
   CompletedToday int64 `json:"completed_today"`
 }
 
@@ -35,25 +36,26 @@ type adminRecentRow struct {
   Network      string    `json:"network"`
   Amount       *float64  `json:"amount"`
   Asset        string    `json:"asset"`
+  TimeReceived time.Time `json:"time_received"`
   LastUpdate   time.Time `json:"last_update"`
 }
 
 type adminOrderDetail struct {
-  OrderID           int64     `json:"order_id"`
-  MerchantName      string    `json:"merchant_name"`
+  OrderID            int64     `json:"order_id"`
+  MerchantName       string    `json:"merchant_name"`
   TransactionNetwork string    `json:"transaction_network"`
   TransactionAsset   string    `json:"transaction_asset"`
-  TXID              string    `json:"txid"`
-  Amount            *float64  `json:"amount"`
-  Email             string    `json:"email"`
-  BeneficiaryName   string    `json:"beneficiary_name"`
-  BankCountry       string    `json:"bank_country"`
-  BankName          string    `json:"bank_name"`
-  IBAN              string    `json:"iban"`
-  SWIFT             string    `json:"swift"`
-  ReferenceNote     *string   `json:"reference_note"`
-  Status            string    `json:"status"`
-  CreatedAt         time.Time `json:"created_at"`
+  TXID               string    `json:"txid"`
+  Amount             *float64  `json:"amount"`
+  Email              string    `json:"email"`
+  BeneficiaryName    string    `json:"beneficiary_name"`
+  BankCountry        string    `json:"bank_country"`
+  BankName           string    `json:"bank_name"`
+  IBAN               string    `json:"iban"`
+  SWIFT              string    `json:"swift"`
+  ReferenceNote      *string   `json:"reference_note"`
+  Status             string    `json:"status"`
+  CreatedAt          time.Time `json:"created_at"`
 }
 
 type adminOrderDetailResponse struct {
@@ -73,395 +75,232 @@ type adminListResponse[T any] struct {
 }
 
 // AdminStats returns summary stats for admin UI.
-func AdminStats(db *sql.DB, cfg AuthConfig) http.HandlerFunc {
+func AdminStats(orders store.OrderStore) http.HandlerFunc {
   return func(w http.ResponseWriter, r *http.Request) {
-    if r.Method != http.MethodGet {
-      writeError(w, http.StatusMethodNotAllowed, "method not allowed")
-      return
-    }
-
-    claims, err := authenticateRequest(r, cfg.JWTSecret)
-    if err != nil || claims.Role != "admin" {
-      writeError(w, http.StatusUnauthorized, "unauthorized")
-      return
-    }
-
-    stats, err := loadAdminStats(r.Context(), db)
+    stats, err := orders.OrderStats(store.AllowStale(r.Context()), time.Now())
     if err != nil {
-      log.Printf("admin stats error: %v", err)
-      writeError(w, http.StatusInternalServerError, "server error")
+      slog.ErrorContext(r.Context(), "admin stats failed", "error", err)
+      writeError(w, http.StatusInternalServerError, codeInternal, "server error")
       return
     }
 
-    writeJSON(w, http.StatusOK, stats)
+    writeJSON(w, http.StatusOK, adminStats{
+      FundsReceived:  stats.FundsReceived,
+      Processing:     stats.Processing,
+      ActionRequired: stats.ActionRequired,
+      Awaiting:       stats.Awaiting,
+      CompletedToday: stats.CompletedToday,
+    })
   }
 }
 
-// AdminReadyProcessing returns processing orders with pagination.
-func AdminReadyProcessing(db *sql.DB, cfg AuthConfig) http.HandlerFunc {
+// AdminReadyProcessing returns processing orders with pagination. It backs
+// the legacy /admin/ready-processing route; new clients use AdminListOrders
+// with status=Processing.
+func AdminReadyProcessing(orders store.OrderStore, pages PageConfig) http.HandlerFunc {
   return func(w http.ResponseWriter, r *http.Request) {
-    if r.Method != http.MethodGet {
-      writeError(w, http.StatusMethodNotAllowed, "method not allowed")
-      return
-    }
-
-    claims, err := authenticateRequest(r, cfg.JWTSecret)
-    if err != nil || claims.Role != "admin" {
-      writeError(w, http.StatusUnauthorized, "unauthorized")
+    page, pageSize, err := parsePagination(r, pages)
+    if err != nil {
+      writeRequestError(w, err)
       return
     }
 
-    page, pageSize, err := parsePagination(r)
+    total, rows, err := orders.ListOrdersByStatus(store.AllowStale(r.Context()), dto.StatusProcessing, page, pageSize)
     if err != nil {
-      writeError(w, http.StatusBadRequest, err.Error())
+      slog.ErrorContext(r.Context(), "admin ready list failed", "error", err)
+      writeError(w, http.StatusInternalServerError, codeInternal, "server error")
       return
     }
 
-    total, rows, err := loadReadyProcessing(r.Context(), db, page, pageSize)
-    if err != nil {
-      log.Printf("admin ready list error: %v", err)
-      writeError(w, http.StatusInternalServerError, "server error")
-      return
+    items := make([]adminOrderRow, 0, len(rows))
+    for _, order := range rows {
+      items = append(items, toAdminOrderRow(order))
     }
 
     writeJSON(w, http.StatusOK, adminListResponse[adminOrderRow]{
       Total:    total,
       Page:     page,
       PageSize: pageSize,
-      Items:    rows,
+      Items:    items,
     })
   }
 }
 
-// AdminRecentOrders returns recent orders with pagination.
-func AdminRecentOrders(db *sql.DB, cfg AuthConfig) http.HandlerFunc {
+// AdminListOrders returns orders with pagination, most recently updated
+// first. With a status query parameter it returns only orders in that
+// status, newest created first.
+func AdminListOrders(orders store.OrderStore, pages PageConfig) http.HandlerFunc {
   return func(w http.ResponseWriter, r *http.Request) {
-    if r.Method != http.MethodGet {
-      writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+    page, pageSize, err := parsePagination(r, pages)
+    if err != nil {
+      writeRequestError(w, err)
       return
     }
 
-    claims, err := authenticateRequest(r, cfg.JWTSecret)
-    if err != nil || claims.Role != "admin" {
-      writeError(w, http.StatusUnauthorized, "unauthorized")
-      return
+    var total int64
+    var rows []dto.OrderDTO
+    if raw := r.URL.Query().Get("status"); raw != "" {
+      status := normalizeStatus(raw)
+      if !isAllowedStatus(status) {
+        writeFieldError(w, "status", validation.CodeUnsupported, "invalid status")
+        return
+      }
+      total, rows, err = orders.ListOrdersByStatus(store.AllowStale(r.Context()), status, page, pageSize)
+    } else {
+      total, rows, err = orders.ListRecentOrders(store.AllowStale(r.Context()), page, pageSize)
     }
-
-    page, pageSize, err := parsePagination(r)
     if err != nil {
-      writeError(w, http.StatusBadRequest, err.Error())
+      slog.ErrorContext(r.Context(), "admin order list failed", "error", err)
+      writeError(w, http.StatusInternalServerError, codeInternal, "server error")
       return
     }
 
-    total, rows, err := loadRecentOrders(r.Context(), db, page, pageSize)
-    if err != nil {
-      log.Printf("admin recent list error: %v", err)
-      writeError(w, http.StatusInternalServerError, "server error")
-      return
+    items := make([]adminRecentRow, 0, len(rows))
+    for _, order := range rows {
+      items = append(items, toAdminRecentRow(r, order))
     }
 
     writeJSON(w, http.StatusOK, adminListResponse[adminRecentRow]{
       Total:    total,
       Page:     page,
       PageSize: pageSize,
-      Items:    rows,
+      Items:    items,
     })
   }
 }
 
 // AdminOrderDetail returns a single order by ID for admin view.
-func AdminOrderDetail(db *sql.DB, cfg AuthConfig) http.HandlerFunc {
+func AdminOrderDetail(orders store.OrderStore) http.HandlerFunc {
   return func(w http.ResponseWriter, r *http.Request) {
-    if r.Method != http.MethodGet {
-      writeError(w, http.StatusMethodNotAllowed, "method not allowed")
-      return
-    }
-
-    claims, err := authenticateRequest(r, cfg.JWTSecret)
-    if err != nil || claims.Role != "admin" {
-      writeError(w, http.StatusUnauthorized, "unauthorized")
-      return
-    }
-
-    rawID := r.URL.Query().Get("id")
-    if rawID == "" {
-      writeError(w, http.StatusBadRequest, "id is required")
-      return
-    }
-    orderID, err := strconv.ParseInt(rawID, 10, 64)
-    if err != nil || orderID <= 0 {
-      writeError(w, http.StatusBadRequest, "invalid id")
+    orderID, err := parseIDParam(r, "id")
+    if err != nil {
+      writeRequestError(w, err)
       return
     }
 
-    order, err := loadAdminOrderDetail(r.Context(), db, orderID)
+    order, err := orders.GetOrder(r.Context(), orderID)
     if err != nil {
-      if err == sql.ErrNoRows {
-        writeError(w, http.StatusNotFound, "order not found")
+      if errors.Is(err, store.ErrNotFound) {
+        writeError(w, http.StatusNotFound, codeNotFound, "order not found")
         return
       }
-      log.Printf("admin order detail error: %v", err)
-      writeError(w, http.StatusInternalServerError, "server error")
+      slog.ErrorContext(r.Context(), "admin order detail failed", "error", err)
+      writeError(w, http.StatusInternalServerError, codeInternal, "server error")
       return
     }
 
-    writeJSON(w, http.StatusOK, adminOrderDetailResponse{Order: order})
+    writeJSON(w, http.StatusOK, adminOrderDetailResponse{Order: toAdminOrderDetail(r, order)})
   }
 }
 
 // AdminUpdateOrderStatus updates an order status.
-func AdminUpdateOrderStatus(db *sql.DB, cfg AuthConfig) http.HandlerFunc {
+func AdminUpdateOrderStatus(orders store.OrderStore) http.HandlerFunc {
   return func(w http.ResponseWriter, r *http.Request) {
-    if r.Method != http.MethodPost {
-      writeError(w, http.StatusMethodNotAllowed, "method not allowed")
-      return
-    }
-
-    claims, err := authenticateRequest(r, cfg.JWTSecret)
-    if err != nil || claims.Role != "admin" {
-      writeError(w, http.StatusUnauthorized, "unauthorized")
-      return
-    }
-
     var req updateOrderStatusRequest
-    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-      writeError(w, http.StatusBadRequest, "invalid json body")
+    if err := decodeJSON(w, r, &req, withMaxBodyBytes(4<<10)); err != nil {
+      writeDecodeError(w, err)
       return
     }
+    // PATCH /admin/orders/{id}/status carries the ID in the path; the legacy
+    // route sends it in the body.
+    if r.PathValue("id") != "" {
+      id, err := parseIDParam(r, "id")
+      if err != nil {
+        writeRequestError(w, err)
+        return
+      }
+      req.ID = id
+    }
+    req.Status = normalizeStatus(req.Status)
+    var errs validation.Errors
     if req.ID <= 0 {
-      writeError(w, http.StatusBadRequest, "invalid id")
-      return
+      errs.Add("id", validation.CodeInvalidValue, "invalid id")
     }
     if !isAllowedStatus(req.Status) {
-      writeError(w, http.StatusBadRequest, "invalid status")
+      errs.Add("status", validation.CodeUnsupported, "invalid status")
+    }
+    if len(errs) > 0 {
+      writeRequestError(w, errs)
       return
     }
 
-    order, err := updateOrderStatus(r.Context(), db, req.ID, req.Status)
+    // The previous status is only needed for the transition metric.
+    previous, err := orders.GetOrder(r.Context(), req.ID)
     if err != nil {
-      if err == sql.ErrNoRows {
-        writeError(w, http.StatusNotFound, "order not found")
+      if errors.Is(err, store.ErrNotFound) {
+        writeError(w, http.StatusNotFound, codeNotFound, "order not found")
         return
       }
-      log.Printf("admin update status error: %v", err)
-      writeError(w, http.StatusInternalServerError, "server error")
+      slog.ErrorContext(r.Context(), "admin update status query failed", "error", err)
+      writeError(w, http.StatusInternalServerError, codeInternal, "server error")
       return
     }
 
-    writeJSON(w, http.StatusOK, adminOrderDetailResponse{Order: order})
-  }
-}
-
-func loadAdminStats(ctx context.Context, db *sql.DB) (adminStats, error) {
-  ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
-  defer cancel()
-
-  today := time.Now().Format("2006-01-02")
-  stats := adminStats{}
-
-  queries := []struct {
-    sql    string
-    target *int64
-  }{
-    {`SELECT COUNT(*) FROM orders WHERE status = 'Funds Received'`, &stats.FundsReceived},
-    {`SELECT COUNT(*) FROM orders WHERE status = 'Processing'`, &stats.Processing},
-    {`SELECT COUNT(*) FROM orders WHERE status = 'Failed'`, &stats.ActionRequired},
-    {`SELECT COUNT(*) FROM orders WHERE status = 'Summitted'`, &stats.Awaiting},
-    {`SELECT COUNT(*) FROM orders WHERE status = 'Paid' AND DATE(created_at) = ?`, &stats.CompletedToday},
-  }
-
-  for _, q := range queries {
-    if q.sql == "SELECT COUNT(*) FROM orders WHERE status = 'Paid' AND DATE(created_at) = ?" {
-      if err := db.QueryRowContext(ctx, q.sql, today).Scan(q.target); err != nil {
-        return adminStats{}, err
+    order, err := orders.UpdateOrderStatus(r.Context(), req.ID, req.Status)
+    if err != nil {
+      if errors.Is(err, store.ErrNotFound) {
+        writeError(w, http.StatusNotFound, codeNotFound, "order not found")
+        return
       }
-      continue
+      slog.ErrorContext(r.Context(), "admin update status failed", "error", err)
+      writeError(w, http.StatusInternalServerError, codeInternal, "server error")
+      return
     }
-    if err := db.QueryRowContext(ctx, q.sql).Scan(q.target); err != nil {
-      return adminStats{}, err
+    if previous.Status != order.Status {
+      metrics.StatusTransitions.WithLabelValues(previous.Status, order.Status).Inc()
     }
-  }
 
-  return stats, nil
-}
-
-func loadReadyProcessing(ctx context.Context, db *sql.DB, page int, pageSize int) (int64, []adminOrderRow, error) {
-  ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
-  defer cancel()
-
-  var total int64
-  if err := db.QueryRowContext(ctx, `
-    SELECT COUNT(*) FROM orders WHERE status = 'Processing'
-  `).Scan(&total); err != nil {
-    return 0, nil, err
-  }
-
-  offset := (page - 1) * pageSize
-  rows, err := db.QueryContext(ctx, `
-    SELECT id, merchant_name, transaction_asset, transaction_network, amount, created_at
-    FROM orders
-    WHERE status = 'Processing'
-    ORDER BY created_at DESC
-    LIMIT ? OFFSET ?
-  `, pageSize, offset)
-  if err != nil {
-    return 0, nil, err
-  }
-  defer rows.Close()
-
-  results := make([]adminOrderRow, 0)
-  for rows.Next() {
-    var (
-      amount sql.NullFloat64
-      row    adminOrderRow
-    )
-    if err := rows.Scan(
-      &row.OrderID,
-      &row.MerchantName,
-      &row.Asset,
-      &row.Network,
-      &amount,
-      &row.TimeReceived,
-    ); err != nil {
-      return 0, nil, err
-    }
-    if amount.Valid {
-      row.Amount = &amount.Float64
-    }
-    results = append(results, row)
+    writeJSON(w, http.StatusOK, adminOrderDetailResponse{Order: toAdminOrderDetail(r, order)})
   }
-  if err := rows.Err(); err != nil {
-    return 0, nil, err
-  }
-
-  return total, results, nil
 }
 
-func loadRecentOrders(ctx context.Context, db *sql.DB, page int, pageSize int) (int64, []adminRecentRow, error) {
-  ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
-  defer cancel()
-
-  var total int64
-  if err := db.QueryRowContext(ctx, `
-    SELECT COUNT(*) FROM orders
-  `).Scan(&total); err != nil {
-    return 0, nil, err
-  }
-
-  offset := (page - 1) * pageSize
-  rows, err := db.QueryContext(ctx, `
-    SELECT id, status, merchant_name, transaction_network, amount, transaction_asset, updated_at
-    FROM orders
-    ORDER BY updated_at DESC
-    LIMIT ? OFFSET ?
-  `, pageSize, offset)
-  if err != nil {
-    return 0, nil, err
+func toAdminOrderRow(order dto.OrderDTO) adminOrderRow {
+  return adminOrderRow{
+    OrderID:      order.ID,
+    MerchantName: order.MerchantName,
+    Asset:        order.TransactionAsset,
+    Network:      order.TransactionNetwork,
+    Amount:       floatPtr(order.Amount),
+    TimeReceived: order.CreatedAt,
   }
-  defer rows.Close()
-
-  results := make([]adminRecentRow, 0)
-  for rows.Next() {
-    var (
-      amount sql.NullFloat64
-      row    adminRecentRow
-    )
-    if err := rows.Scan(
-      &row.OrderID,
-      &row.Status,
-      &row.MerchantName,
-      &row.Network,
-      &amount,
-      &row.Asset,
-      &row.LastUpdate,
-    ); err != nil {
-      return 0, nil, err
-    }
-    if amount.Valid {
-      row.Amount = &amount.Float64
-    }
-    results = append(results, row)
-  }
-  if err := rows.Err(); err != nil {
-    return 0, nil, err
-  }
-
-  return total, results, nil
 }
 
-func loadAdminOrderDetail(ctx context.Context, db *sql.DB, orderID int64) (adminOrderDetail, error) {
-  ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
-  defer cancel()
-
-  var (
-    amount sql.NullFloat64
-    note   sql.NullString
-    order  adminOrderDetail
-  )
-
-  row := db.QueryRowContext(ctx, `
-    SELECT id, merchant_name, transaction_network, transaction_asset, txid, amount,
-           email, beneficiary_name, bank_country, bank_name, iban, swift, reference_note, status, created_at
-    FROM orders
-    WHERE id = ?
-    LIMIT 1
-  `, orderID)
-
-  if err := row.Scan(
-    &order.OrderID,
-    &order.MerchantName,
-    &order.TransactionNetwork,
-    &order.TransactionAsset,
-    &order.TXID,
-    &amount,
-    &order.Email,
-    &order.BeneficiaryName,
-    &order.BankCountry,
-    &order.BankName,
-    &order.IBAN,
-    &order.SWIFT,
-    &note,
-    &order.Status,
-    &order.CreatedAt,
-  ); err != nil {
-    return adminOrderDetail{}, err
-  }
-
-  if amount.Valid {
-    order.Amount = &amount.Float64
+func toAdminRecentRow(r *http.Request, order dto.OrderDTO) adminRecentRow {
+  return adminRecentRow{
+    OrderID:      order.ID,
+    Status:       presentStatus(r, order.Status),
+    MerchantName: order.MerchantName,
+    Network:      order.TransactionNetwork,
+    Amount:       floatPtr(order.Amount),
+    Asset:        order.TransactionAsset,
+    TimeReceived: order.CreatedAt,
+    LastUpdate:   order.UpdatedAt,
   }
-  if note.Valid && strings.TrimSpace(note.String) != "" {
-    order.ReferenceNote = &note.String
-  }
-
-  return order, nil
 }
 
-func updateOrderStatus(ctx context.Context, db *sql.DB, orderID int64, status string) (adminOrderDetail, error) {
-  ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
-  defer cancel()
-
-  res, err := db.ExecContext(ctx, `
-    UPDATE orders
-    SET status = ?
-    WHERE id = ?
-  `, status, orderID)
-  if err != nil {
-    return adminOrderDetail{}, err
+func toAdminOrderDetail(r *http.Request, order dto.OrderDTO) adminOrderDetail {
+  return adminOrderDetail{
+    OrderID:            order.ID,
+    MerchantName:       order.MerchantName,
+    TransactionNetwork: order.TransactionNetwork,
+    TransactionAsset:   order.TransactionAsset,
+    TXID:               order.TXID,
+    Amount:             floatPtr(order.Amount),
+    Email:              order.Email,
+    BeneficiaryName:    order.BeneficiaryName,
+    BankCountry:        order.BankCountry,
+    BankName:           order.BankName,
+    IBAN:               order.IBAN,
+    SWIFT:              order.SWIFT,
+    ReferenceNote:      notePtr(order.ReferenceNote),
+    Status:             presentStatus(r, order.Status),
+    CreatedAt:          order.CreatedAt,
   }
-  if rows, err := res.RowsAffected(); err != nil || rows == 0 {
-    if err != nil {
-      return adminOrderDetail{}, err
-    }
-    return adminOrderDetail{}, sql.ErrNoRows
-  }
-
-  return loadAdminOrderDetail(ctx, db, orderID)
 }
 
 func isAllowedStatus(status string) bool {
   switch status {
-  case "Paid", "Processing", "Summitted", "Failed", "Funds Received":
+  case dto.StatusPaid, dto.StatusProcessing, dto.StatusSubmitted, dto.StatusFailed, dto.StatusFundsReceived:
     return true
   default:
     return false
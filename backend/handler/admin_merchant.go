@@ -0,0 +1,27 @@
+package handler
+
+import (
+  "errors"
+  "log/slog"
+  "net/http"
+
+  "sarah-project-backend/store"
+)
+
+// AdminDeactivateAPIKey revokes a merchant's API key. Later requests with
+// the key are rejected.
+func AdminDeactivateAPIKey(merchants store.MerchantStore) http.HandlerFunc {
+  return func(w http.ResponseWriter, r *http.Request) {
+    merchant := r.PathValue("merchant")
+    if err := merchants.DeactivateAPIKey(r.Context(), merchant); err != nil {
+      if errors.Is(err, store.ErrNotFound) {
+        writeError(w, http.StatusNotFound, codeNotFound, "no active api key")
+        return
+      }
+      slog.ErrorContext(r.Context(), "admin deactivate api key failed", "error", err)
+      writeError(w, http.StatusInternalServerError, codeInternal, "server error")
+      return
+    }
+    w.WriteHeader(http.StatusNoContent)
+  }
+}
@@ -0,0 +1,184 @@
+package handler
+
+import (
+  "crypto/rand"
+  "encoding/hex"
+  "errors"
+  "fmt"
+  "log/slog"
+  "net/http"
+  "strings"
+  "time"
+
+  "sarah-project-backend/dto"
+  "sarah-project-backend/metrics"
+  "sarah-project-backend/payout"
+  "sarah-project-backend/store"
+  "sarah-project-backend/validation"
+)
+
+const maxPayoutBatchSize = 500
+
+type createPayoutBatchRequest struct {
+  OrderIDs []int64 `json:"order_ids"`
+  Format   string  `json:"format"`
+}
+
+type payoutBatchResponse struct {
+  BatchID     string    `json:"batch_id"`
+  Format      string    `json:"format"`
+  FileName    string    `json:"file_name"`
+  OrderCount  int       `json:"order_count"`
+  TotalAmount float64   `json:"total_amount"`
+  Content     string    `json:"content"`
+  CreatedAt   time.Time `json:"created_at"`
+}
+
+// AdminCreatePayoutBatch exports Funds Received orders into a bank payment file
+// and moves them to Submitted.
+func AdminCreatePayoutBatch(payouts store.PayoutStore, payoutCfg payout.Config) http.HandlerFunc {
+  return func(w http.ResponseWriter, r *http.Request) {
+    adminID := principal(r).AdminID
+
+    var req createPayoutBatchRequest
+    if err := decodeJSON(w, r, &req); err != nil {
+      writeDecodeError(w, err)
+      return
+    }
+    format, err := payout.ParseFormat(req.Format)
+    if err != nil {
+      writeFieldError(w, "format", validation.CodeUnsupported, "invalid format")
+      return
+    }
+    orderIDs, err := normalizeOrderIDs(req.OrderIDs)
+    if err != nil {
+      writeRequestError(w, err)
+      return
+    }
+    if err := payoutCfg.Validate(format); err != nil {
+      slog.ErrorContext(r.Context(), "admin payout config failed", "error", err)
+      writeError(w, http.StatusServiceUnavailable, codeNotConfigured, "payout format not configured")
+      return
+    }
+
+    batchID, err := newPayoutBatchID()
+    if err != nil {
+      slog.ErrorContext(r.Context(), "admin payout batch id failed", "error", err)
+      writeError(w, http.StatusInternalServerError, codeInternal, "server error")
+      return
+    }
+    createdAt := time.Now()
+    build := func(sequence int64, payments []payout.Payment) (payout.File, error) {
+      return payout.Generate(format, payoutCfg, payout.Batch{
+        ID:        batchID,
+        Sequence:  sequence,
+        CreatedAt: createdAt,
+        Payments:  payments,
+      })
+    }
+
+    batch, err := payouts.CreatePayoutBatch(r.Context(), dto.PayoutBatchDTO{
+      BatchID:   batchID,
+      Format:    string(format),
+      CreatedBy: adminID,
+      CreatedAt: createdAt,
+    }, orderIDs, build)
+    if err != nil {
+      var paymentErr payout.PaymentError
+      var conflict store.ConflictError
+      switch {
+      case errors.As(err, &paymentErr):
+        writeErrorDetails(w, http.StatusUnprocessableEntity, codeUnprocessable, paymentErr.Error(), validation.Errors{
+          {Field: "order_ids", Code: validation.CodeInvalidValue, Message: paymentErr.Error()},
+        })
+      case errors.As(err, &conflict):
+        writeError(w, http.StatusConflict, codeConflict, conflict.Error())
+      default:
+        slog.ErrorContext(r.Context(), "admin payout batch failed", "error", err)
+        writeError(w, http.StatusInternalServerError, codeInternal, "server error")
+      }
+      return
+    }
+    metrics.StatusTransitions.WithLabelValues(dto.StatusFundsReceived, dto.StatusSubmitted).Add(float64(batch.OrderCount))
+
+    writeJSON(w, http.StatusCreated, payoutBatchResponse{
+      BatchID:     batch.BatchID,
+      Format:      batch.Format,
+      FileName:    batch.FileName,
+      OrderCount:  batch.OrderCount,
+      TotalAmount: batch.TotalAmount,
+      Content:     batch.Content,
+      CreatedAt:   createdAt,
+    })
+  }
+}
+
+// AdminPayoutFile downloads a previously generated payout file.
+func AdminPayoutFile(payouts store.PayoutStore) http.HandlerFunc {
+  return func(w http.ResponseWriter, r *http.Request) {
+    batchID := r.PathValue("batch_id")
+    if batchID == "" {
+      batchID = strings.TrimSpace(r.URL.Query().Get("batch_id"))
+    }
+    if batchID == "" {
+      writeFieldError(w, "batch_id", validation.CodeRequired, "batch_id is required")
+      return
+    }
+
+    batch, err := payouts.GetPayoutBatch(r.Context(), batchID)
+    if err != nil {
+      if errors.Is(err, store.ErrNotFound) {
+        writeError(w, http.StatusNotFound, codeNotFound, "batch not found")
+        return
+      }
+      slog.ErrorContext(r.Context(), "admin payout file failed", "error", err)
+      writeError(w, http.StatusInternalServerError, codeInternal, "server error")
+      return
+    }
+
+    contentType := "text/plain; charset=utf-8"
+    if batch.Format == string(payout.FormatPain001) {
+      contentType = "application/xml"
+    }
+    w.Header().Set("Content-Type", contentType)
+    w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", batch.FileName))
+    w.WriteHeader(http.StatusOK)
+    _, _ = w.Write([]byte(batch.Content))
+  }
+}
+
+func normalizeOrderIDs(ids []int64) ([]int64, error) {
+  if len(ids) == 0 {
+    return nil, validation.Errors{{Field: "order_ids", Code: validation.CodeRequired, Message: "order_ids is required"}}
+  }
+  if len(ids) > maxPayoutBatchSize {
+    return nil, validation.Errors{{Field: "order_ids", Code: validation.CodeOutOfRange, Message: fmt.Sprintf("at most %d orders per batch", maxPayoutBatchSize)}}
+  }
+
+  var errs validation.Errors
+  seen := make(map[int64]bool, len(ids))
+  result := make([]int64, 0, len(ids))
+  for i, id := range ids {
+    if id <= 0 {
+      errs.Add(fmt.Sprintf("order_ids[%d]", i), validation.CodeInvalidValue, "invalid order id")
+      continue
+    }
+    if seen[id] {
+      continue
+    }
+    seen[id] = true
+    result = append(result, id)
+  }
+  if len(errs) > 0 {
+    return nil, errs
+  }
+  return result, nil
+}
+
+func newPayoutBatchID() (string, error) {
+  buf := make([]byte, 4)
+  if _, err := rand.Read(buf); err != nil {
+    return "", err
+  }
+  return fmt.Sprintf("PB%s%s", time.Now().UTC().Format("20060102150405"), strings.ToUpper(hex.EncodeToString(buf))), nil
+}
@@ -1,22 +1,28 @@
 package handler
 
 import (
-  "context"
-  "database/sql"
-  "encoding/json"
-  "log"
+  "errors"
+  "log/slog"
   "net/http"
   "time"
 
-  "sarah-project-backend/dto"
+  "go.opentelemetry.io/otel"
+  "go.opentelemetry.io/otel/attribute"
+  "go.opentelemetry.io/otel/trace"
+
+  "sarah-project-backend/metrics"
+  "sarah-project-backend/security"
+  "sarah-project-backend/store"
+  "sarah-project-backend/validation"
 )
 
+var tracer = otel.Tracer("sarah-project-backend/handler")
+
 type adminLoginRequest struct {
   Username string `json:"username"`
   Password string `json:"password"`
 }
 
-
 type adminLoginResponse struct {
   ID       int64  `json:"id"`
   Username string `json:"username"`
@@ -24,50 +30,54 @@ type adminLoginResponse struct {
   Token    string `json:"token"`
 }
 
-
 type AuthConfig struct {
-  JWTSecret string
+  Keys      *security.KeySet
   JWTIssuer string
   JWTTTL    time.Duration
 }
 
 // AdminLogin handles admin login requests.
-func AdminLogin(db *sql.DB, cfg AuthConfig) http.HandlerFunc {
+func AdminLogin(admins store.AdminStore, cfg AuthConfig) http.HandlerFunc {
   return func(w http.ResponseWriter, r *http.Request) {
-    if r.Method != http.MethodPost {
-      writeError(w, http.StatusMethodNotAllowed, "method not allowed")
-      return
-    }
-
     var req adminLoginRequest
-    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-      writeError(w, http.StatusBadRequest, "invalid json body")
+    // Login forms may post extra UI fields, so unknown fields are tolerated here.
+    if err := decodeJSON(w, r, &req, withMaxBodyBytes(4<<10), withUnknownFields()); err != nil {
+      writeDecodeError(w, err)
       return
     }
-    if req.Username == "" || req.Password == "" {
-      writeError(w, http.StatusBadRequest, "username and password required")
+    var errs validation.Errors
+    if req.Username == "" {
+      errs.Add("username", validation.CodeRequired, "username is required")
+    }
+    if req.Password == "" {
+      errs.Add("password", validation.CodeRequired, "password is required")
+    }
+    if len(errs) > 0 {
+      writeRequestError(w, errs)
       return
     }
 
-    admin, err := getAdminByUsername(r.Context(), db, req.Username)
+    admin, err := admins.AdminByUsername(r.Context(), req.Username)
     if err != nil {
-      if err == sql.ErrNoRows {
-        writeError(w, http.StatusUnauthorized, "invalid credentials")
+      if errors.Is(err, store.ErrNotFound) {
+        authFailed(trace.SpanFromContext(r.Context()), "password", "invalid")
+        writeError(w, http.StatusUnauthorized, codeInvalidCredentials, "invalid credentials")
         return
       }
-      log.Printf("admin login query error: %v", err)
-      writeError(w, http.StatusInternalServerError, "server error")
+      slog.ErrorContext(r.Context(), "admin login query failed", "error", err)
+      writeError(w, http.StatusInternalServerError, codeInternal, "server error")
       return
     }
     if err := admin.VerifyPassword(req.Password); err != nil {
-      writeError(w, http.StatusUnauthorized, "invalid credentials")
+      authFailed(trace.SpanFromContext(r.Context()), "password", "invalid")
+      writeError(w, http.StatusUnauthorized, codeInvalidCredentials, "invalid credentials")
       return
     }
 
-    token, err := createToken(admin.ID, "admin", cfg.JWTSecret, cfg.JWTIssuer, cfg.JWTTTL)
+    token, err := createToken(admin.ID, "admin", cfg.Keys, cfg.JWTIssuer, cfg.JWTTTL)
     if err != nil {
-      log.Printf("admin login token error: %v", err)
-      writeError(w, http.StatusInternalServerError, "server error")
+      slog.ErrorContext(r.Context(), "admin login token failed", "error", err)
+      writeError(w, http.StatusInternalServerError, codeInternal, "server error")
       return
     }
 
@@ -81,27 +91,9 @@ func AdminLogin(db *sql.DB, cfg AuthConfig) http.HandlerFunc {
   }
 }
 
-func getAdminByUsername(ctx context.Context, db *sql.DB, username string) (dto.AdminDTO, error) {
-  ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
-  defer cancel()
-
-  var admin dto.AdminDTO
-  row := db.QueryRowContext(ctx, `
-    SELECT id, username, email, password_hash, created_at, updated_at
-    FROM admin_users
-    WHERE username = ?
-    LIMIT 1
-  `, username)
-  if err := row.Scan(
-    &admin.ID,
-    &admin.Username,
-    &admin.Email,
-    &admin.PasswordHash,
-    &admin.CreatedAt,
-    &admin.UpdatedAt,
-  ); err != nil {
-    return dto.AdminDTO{}, err
-  }
-  return admin, nil
+// authFailed counts a rejected credential and notes the reason on span.
+// method is jwt, api_key or password.
+func authFailed(span trace.Span, method, reason string) {
+  metrics.AuthFailures.WithLabelValues(method, reason).Inc()
+  span.SetAttributes(attribute.String("auth.failure", reason))
 }
-
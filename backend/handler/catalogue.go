@@ -0,0 +1,204 @@
+package handler
+
+import (
+  "database/sql"
+  "log/slog"
+  "net/http"
+  "strings"
+
+  "sarah-project-backend/dto"
+  "sarah-project-backend/store"
+  "sarah-project-backend/validation"
+)
+
+type catalogueAsset struct {
+  Network         string   `json:"network"`
+  Asset           string   `json:"asset"`
+  ContractAddress string   `json:"contract_address"`
+  Decimals        int      `json:"decimals"`
+  MinAmount       *float64 `json:"min_amount"`
+  MaxAmount       *float64 `json:"max_amount"`
+  Enabled         bool     `json:"enabled"`
+}
+
+type catalogueCountry struct {
+  Name     string `json:"name"`
+  ISOCode  string `json:"iso_code"`
+  Currency string `json:"currency"`
+  Enabled  bool   `json:"enabled"`
+}
+
+type catalogueResponse struct {
+  Assets    []catalogueAsset   `json:"assets"`
+  Countries []catalogueCountry `json:"countries"`
+}
+
+type upsertCatalogueAssetRequest struct {
+  Network         string   `json:"network"`
+  Asset           string   `json:"asset"`
+  ContractAddress string   `json:"contract_address"`
+  Decimals        *int     `json:"decimals"`
+  MinAmount       *float64 `json:"min_amount"`
+  MaxAmount       *float64 `json:"max_amount"`
+  Enabled         *bool    `json:"enabled"`
+}
+
+type upsertCatalogueCountryRequest struct {
+  Name     string `json:"name"`
+  ISOCode  string `json:"iso_code"`
+  Currency string `json:"currency"`
+  Enabled  *bool  `json:"enabled"`
+}
+
+// AdminCatalogue lists every configured asset and payout country.
+func AdminCatalogue(catalogue store.CatalogueStore) http.HandlerFunc {
+  return func(w http.ResponseWriter, r *http.Request) {
+    cat, err := catalogue.LoadCatalogue(r.Context(), false)
+    if err != nil {
+      slog.ErrorContext(r.Context(), "admin catalogue failed", "error", err)
+      writeError(w, http.StatusInternalServerError, codeInternal, "server error")
+      return
+    }
+
+    writeJSON(w, http.StatusOK, toCatalogueResponse(cat))
+  }
+}
+
+// AdminUpsertCatalogueAsset creates or updates a supported (network, asset) pair.
+func AdminUpsertCatalogueAsset(catalogue store.CatalogueStore) http.HandlerFunc {
+  return func(w http.ResponseWriter, r *http.Request) {
+    var req upsertCatalogueAssetRequest
+    if err := decodeJSON(w, r, &req, withMaxBodyBytes(4<<10)); err != nil {
+      writeDecodeError(w, err)
+      return
+    }
+
+    asset := catalogueAsset{
+      Network:         strings.TrimSpace(req.Network),
+      Asset:           strings.TrimSpace(req.Asset),
+      ContractAddress: strings.TrimSpace(req.ContractAddress),
+      MinAmount:       req.MinAmount,
+      MaxAmount:       req.MaxAmount,
+      Enabled:         req.Enabled == nil || *req.Enabled,
+    }
+    var errs validation.Errors
+    if asset.Network == "" {
+      errs.Add("network", validation.CodeRequired, "network is required")
+    }
+    if asset.Asset == "" {
+      errs.Add("asset", validation.CodeRequired, "asset is required")
+    }
+    if req.Decimals == nil {
+      errs.Add("decimals", validation.CodeRequired, "decimals is required")
+    } else if *req.Decimals < 0 || *req.Decimals > 36 {
+      errs.Add("decimals", validation.CodeOutOfRange, "decimals must be between 0 and 36")
+    } else {
+      asset.Decimals = *req.Decimals
+    }
+    if req.MinAmount != nil && *req.MinAmount < 0 {
+      errs.Add("min_amount", validation.CodeOutOfRange, "min_amount must be non-negative")
+    }
+    if req.MinAmount != nil && req.MaxAmount != nil && *req.MaxAmount < *req.MinAmount {
+      errs.Add("max_amount", validation.CodeOutOfRange, "max_amount must not be less than min_amount")
+    }
+    if len(errs) > 0 {
+      writeRequestError(w, errs)
+      return
+    }
+
+    if err := catalogue.UpsertCatalogueAsset(r.Context(), dto.CatalogueAssetDTO{
+      Network:         asset.Network,
+      Asset:           asset.Asset,
+      ContractAddress: asset.ContractAddress,
+      Decimals:        asset.Decimals,
+      MinAmount:       nullFloat(asset.MinAmount),
+      MaxAmount:       nullFloat(asset.MaxAmount),
+      Enabled:         asset.Enabled,
+    }); err != nil {
+      slog.ErrorContext(r.Context(), "admin catalogue asset failed", "error", err)
+      writeError(w, http.StatusInternalServerError, codeInternal, "server error")
+      return
+    }
+
+    writeJSON(w, http.StatusOK, asset)
+  }
+}
+
+// AdminUpsertCatalogueCountry creates or updates a payout country.
+func AdminUpsertCatalogueCountry(catalogue store.CatalogueStore) http.HandlerFunc {
+  return func(w http.ResponseWriter, r *http.Request) {
+    var req upsertCatalogueCountryRequest
+    if err := decodeJSON(w, r, &req, withMaxBodyBytes(4<<10)); err != nil {
+      writeDecodeError(w, err)
+      return
+    }
+
+    country := catalogueCountry{
+      Name:     strings.TrimSpace(req.Name),
+      ISOCode:  strings.ToUpper(strings.TrimSpace(req.ISOCode)),
+      Currency: strings.ToUpper(strings.TrimSpace(req.Currency)),
+      Enabled:  req.Enabled == nil || *req.Enabled,
+    }
+    var errs validation.Errors
+    if country.Name == "" {
+      errs.Add("name", validation.CodeRequired, "name is required")
+    }
+    if len(country.ISOCode) != 2 {
+      errs.Add("iso_code", validation.CodeInvalidFormat, "iso_code must be a 2 letter ISO 3166 code")
+    }
+    if len(country.Currency) != 3 {
+      errs.Add("currency", validation.CodeInvalidFormat, "currency must be a 3 letter ISO 4217 code")
+    }
+    if len(errs) > 0 {
+      writeRequestError(w, errs)
+      return
+    }
+
+    if err := catalogue.UpsertPayoutCountry(r.Context(), dto.PayoutCountryDTO{
+      Name:     country.Name,
+      ISOCode:  country.ISOCode,
+      Currency: country.Currency,
+      Enabled:  country.Enabled,
+    }); err != nil {
+      slog.ErrorContext(r.Context(), "admin catalogue country failed", "error", err)
+      writeError(w, http.StatusInternalServerError, codeInternal, "server error")
+      return
+    }
+
+    writeJSON(w, http.StatusOK, country)
+  }
+}
+
+func toCatalogueResponse(cat store.Catalogue) catalogueResponse {
+  resp := catalogueResponse{
+    Assets:    make([]catalogueAsset, 0, len(cat.Assets)),
+    Countries: make([]catalogueCountry, 0, len(cat.Countries)),
+  }
+  for _, a := range cat.Assets {
+    resp.Assets = append(resp.Assets, catalogueAsset{
+      Network:         a.Network,
+      Asset:           a.Asset,
+      ContractAddress: a.ContractAddress,
+      Decimals:        a.Decimals,
+      MinAmount:       floatPtr(a.MinAmount),
+      MaxAmount:       floatPtr(a.MaxAmount),
+      Enabled:         a.Enabled,
+    })
+  }
+  for _, c := range cat.Countries {
+    resp.Countries = append(resp.Countries, catalogueCountry{
+      Name:     c.Name,
+      ISOCode:  c.ISOCode,
+      Currency: c.Currency,
+      Enabled:  c.Enabled,
+    })
+  }
+  return resp
+}
+
+func nullFloat(value *float64) sql.NullFloat64 {
+  if value == nil {
+    return sql.NullFloat64{}
+  }
+  return sql.NullFloat64{Float64: *value, Valid: true}
+}
@@ -1,41 +1,45 @@
 package handler
 
 import (
-  "context"
-  "database/sql"
-  "fmt"
+  "errors"
   "net/http"
   "strings"
-  "time"
+
+  "go.opentelemetry.io/otel/attribute"
+  "go.opentelemetry.io/otel/codes"
+
+  "sarah-project-backend/logging"
+  "sarah-project-backend/store"
 )
 
-func authenticateCustomer(r *http.Request, db *sql.DB) (string, error) {
+func authenticateCustomer(r *http.Request, merchants store.MerchantStore) (string, error) {
+  ctx, span := tracer.Start(r.Context(), "authenticateCustomer")
+  defer span.End()
+
   apiKey := strings.TrimSpace(r.Header.Get("X-API-Key"))
   if apiKey == "" {
-    return "", fmt.Errorf("missing api key")
+    authFailed(span, "api_key", "missing")
+    return "", errNoCredentials
   }
 
   merchantName := strings.TrimSpace(r.Header.Get("X-Merchant-Name"))
   if merchantName == "" {
-    return "", fmt.Errorf("missing merchant name")
+    authFailed(span, "api_key", "missing")
+    return "", errNoCredentials
   }
 
-  ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
-  defer cancel()
-
-  var matched string
-  err := db.QueryRowContext(ctx, `
-    SELECT merchant_name
-    FROM customer_api_keys
-    WHERE api_key = ? AND merchant_name = ? AND active = 1
-    LIMIT 1
-  `, apiKey, merchantName).Scan(&matched)
+  matched, err := merchants.MerchantByAPIKey(ctx, apiKey, merchantName)
   if err != nil {
-    if err == sql.ErrNoRows {
-      return "", fmt.Errorf("invalid api key")
+    if errors.Is(err, store.ErrNotFound) {
+      authFailed(span, "api_key", "invalid")
+      return "", errInvalidCredentials
     }
+    span.RecordError(err)
+    span.SetStatus(codes.Error, "merchant lookup failed")
     return "", err
   }
 
+  span.SetAttributes(attribute.String("merchant.name", matched))
+  logging.SetMerchant(ctx, matched)
   return matched, nil
 }
@@ -0,0 +1,233 @@
+package handler
+
+import (
+  "bytes"
+  "encoding/json"
+  "errors"
+  "fmt"
+  "io"
+  "mime"
+  "net/http"
+  "reflect"
+  "strings"
+
+  "sarah-project-backend/validation"
+)
+
+const defaultMaxBodyBytes = 64 << 10
+
+// decodeOptions controls how decodeJSON reads a request body.
+type decodeOptions struct {
+  maxBytes           int64
+  allowUnknownFields bool
+}
+
+type decodeOption func(*decodeOptions)
+
+// withMaxBodyBytes overrides the default request body size limit.
+func withMaxBodyBytes(n int64) decodeOption {
+  return func(o *decodeOptions) {
+    o.maxBytes = n
+  }
+}
+
+// withUnknownFields accepts JSON fields that do not exist on the target type.
+func withUnknownFields() decodeOption {
+  return func(o *decodeOptions) {
+    o.allowUnknownFields = true
+  }
+}
+
+// decodeError is returned by decodeJSON and knows how it should be reported.
+type decodeError struct {
+  status  int
+  code    string
+  message string
+  details validation.Errors
+}
+
+func (e *decodeError) Error() string {
+  return e.message
+}
+
+// decodeJSON reads a single JSON object from the request body into dst. It
+// requires a JSON content type whenever there is a body, enforces a body
+// size limit, rejects unknown fields unless allowed and rejects trailing
+// data after the object.
+func decodeJSON(w http.ResponseWriter, r *http.Request, dst any, opts ...decodeOption) error {
+  options := decodeOptions{maxBytes: defaultMaxBodyBytes}
+  for _, opt := range opts {
+    opt(&options)
+  }
+
+  // ContentLength is -1 for a chunked body of unknown size.
+  if contentType := r.Header.Get("Content-Type"); contentType != "" || r.ContentLength != 0 {
+    mediaType, _, err := mime.ParseMediaType(contentType)
+    if err != nil || (mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json")) {
+      return &decodeError{
+        status:  http.StatusUnsupportedMediaType,
+        code:    codeUnsupportedMedia,
+        message: "content type must be application/json",
+      }
+    }
+  }
+
+  body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, options.maxBytes))
+  if err != nil {
+    var maxErr *http.MaxBytesError
+    if errors.As(err, &maxErr) {
+      return &decodeError{
+        status:  http.StatusRequestEntityTooLarge,
+        code:    codePayloadTooLarge,
+        message: fmt.Sprintf("request body must not exceed %d bytes", options.maxBytes),
+      }
+    }
+    return &decodeError{status: http.StatusBadRequest, code: codeInvalidJSON, message: "unable to read request body"}
+  }
+  if len(bytes.TrimSpace(body)) == 0 {
+    return &decodeError{status: http.StatusBadRequest, code: codeInvalidJSON, message: "request body is required"}
+  }
+
+  dec := json.NewDecoder(bytes.NewReader(body))
+  if !options.allowUnknownFields {
+    dec.DisallowUnknownFields()
+  }
+
+  if err := dec.Decode(dst); err != nil {
+    return describeDecodeError(body, dec, err)
+  }
+
+  if _, err := dec.Token(); err != io.EOF {
+    line, column := position(body, dec.InputOffset())
+    return &decodeError{
+      status:  http.StatusBadRequest,
+      code:    codeInvalidJSON,
+      message: fmt.Sprintf("unexpected data after JSON body at line %d, column %d", line, column),
+      details: validation.Errors{{
+        Code:    validation.CodeInvalidFormat,
+        Message: "request body must contain a single JSON object",
+        Line:    line,
+        Column:  column,
+      }},
+    }
+  }
+
+  return nil
+}
+
+func describeDecodeError(body []byte, dec *json.Decoder, err error) error {
+  var (
+    syntaxErr *json.SyntaxError
+    typeErr   *json.UnmarshalTypeError
+  )
+
+  switch {
+  case errors.As(err, &syntaxErr):
+    line, column := position(body, syntaxErr.Offset)
+    return &decodeError{
+      status:  http.StatusBadRequest,
+      code:    codeInvalidJSON,
+      message: fmt.Sprintf("malformed JSON at line %d, column %d", line, column),
+      details: validation.Errors{{
+        Code:    validation.CodeInvalidFormat,
+        Message: syntaxErr.Error(),
+        Line:    line,
+        Column:  column,
+      }},
+    }
+  case errors.Is(err, io.ErrUnexpectedEOF):
+    line, column := position(body, int64(len(body)))
+    return &decodeError{
+      status:  http.StatusBadRequest,
+      code:    codeInvalidJSON,
+      message: fmt.Sprintf("malformed JSON at line %d, column %d", line, column),
+      details: validation.Errors{{
+        Code:    validation.CodeInvalidFormat,
+        Message: "unexpected end of JSON input",
+        Line:    line,
+        Column:  column,
+      }},
+    }
+  case errors.As(err, &typeErr):
+    line, column := position(body, typeErr.Offset)
+    message := fmt.Sprintf("%s must be a JSON %s", typeErr.Field, jsonKind(typeErr.Type))
+    if typeErr.Field == "" {
+      message = fmt.Sprintf("request body must be a JSON %s", jsonKind(typeErr.Type))
+    }
+    return &decodeError{
+      status:  http.StatusBadRequest,
+      code:    codeValidationFailed,
+      message: message,
+      details: validation.Errors{{
+        Field:   typeErr.Field,
+        Code:    validation.CodeInvalidType,
+        Message: message,
+        Line:    line,
+        Column:  column,
+      }},
+    }
+  case strings.HasPrefix(err.Error(), "json: unknown field "):
+    field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+    line, column := position(body, dec.InputOffset())
+    message := fmt.Sprintf("unknown field %q", field)
+    return &decodeError{
+      status:  http.StatusBadRequest,
+      code:    codeValidationFailed,
+      message: message,
+      details: validation.Errors{{
+        Field:   field,
+        Code:    validation.CodeUnknownField,
+        Message: message,
+        Line:    line,
+        Column:  column,
+      }},
+    }
+  default:
+    return &decodeError{status: http.StatusBadRequest, code: codeInvalidJSON, message: "invalid json body"}
+  }
+}
+
+// jsonKind names the JSON type expected for a Go type.
+func jsonKind(t reflect.Type) string {
+  switch t.Kind() {
+  case reflect.Struct, reflect.Map:
+    return "object"
+  case reflect.Slice, reflect.Array:
+    return "array"
+  case reflect.String:
+    return "string"
+  case reflect.Bool:
+    return "boolean"
+  case reflect.Pointer:
+    return jsonKind(t.Elem())
+  default:
+    return "number"
+  }
+}
+
+// position converts a byte offset into a 1-based line and column.
+func position(body []byte, offset int64) (int, int) {
+  if offset > int64(len(body)) {
+    offset = int64(len(body))
+  }
+  line, column := 1, 1
+  for _, b := range body[:offset] {
+    if b == '\n' {
+      line++
+      column = 1
+      continue
+    }
+    column++
+  }
+  return line, column
+}
+
+// writeDecodeError reports an error returned by decodeJSON.
+func writeDecodeError(w http.ResponseWriter, err error) {
+  var decErr *decodeError
+  if errors.As(err, &decErr) {
+    writeErrorDetails(w, decErr.status, decErr.code, decErr.message, decErr.details)
+    return
+  }
+  writeError(w, http.StatusBadRequest, codeInvalidJSON, "invalid json body")
+}
@@ -0,0 +1,89 @@
+package handler
+
+import (
+  "context"
+  "database/sql"
+  "log/slog"
+  "net/http"
+  "time"
+)
+
+// Health reports that the process is up. It does not touch the database, so
+// it suits liveness probes.
+func Health() http.HandlerFunc {
+  return func(w http.ResponseWriter, r *http.Request) {
+    writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+  }
+}
+
+// ReadyCheck is a dependency that must be available before the service
+// takes traffic.
+type ReadyCheck struct {
+  Name  string
+  Check func(ctx context.Context) error
+  // Public marks errors that are safe to show to unauthenticated callers.
+  // Other errors are logged and reported as "unavailable".
+  Public bool
+}
+
+type readyResponse struct {
+  Status string            `json:"status"`
+  Checks map[string]string `json:"checks"`
+  Pool   poolStatsResponse `json:"database_pool"`
+}
+
+type poolStatsResponse struct {
+  MaxOpenConnections int   `json:"max_open_connections"`
+  OpenConnections    int   `json:"open_connections"`
+  InUse              int   `json:"in_use"`
+  Idle               int   `json:"idle"`
+  WaitCount          int64 `json:"wait_count"`
+  WaitDurationMS     int64 `json:"wait_duration_ms"`
+  MaxIdleClosed      int64 `json:"max_idle_closed"`
+  MaxIdleTimeClosed  int64 `json:"max_idle_time_closed"`
+  MaxLifetimeClosed  int64 `json:"max_lifetime_closed"`
+}
+
+// Ready runs every check and responds 200 when all pass, 503 otherwise, so
+// it suits readiness probes. Connection pool statistics are included for
+// monitoring.
+func Ready(checks []ReadyCheck, pool func() sql.DBStats) http.HandlerFunc {
+  return func(w http.ResponseWriter, r *http.Request) {
+    resp := readyResponse{Status: "ready", Checks: make(map[string]string, len(checks))}
+    status := http.StatusOK
+    for _, c := range checks {
+      ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+      err := c.Check(ctx)
+      cancel()
+
+      switch {
+      case err == nil:
+        resp.Checks[c.Name] = "ok"
+      case c.Public:
+        resp.Checks[c.Name] = err.Error()
+      default:
+        slog.ErrorContext(r.Context(), "readiness check failed", "check", c.Name, "error", err)
+        resp.Checks[c.Name] = "unavailable"
+      }
+      if err != nil {
+        resp.Status = "not_ready"
+        status = http.StatusServiceUnavailable
+      }
+    }
+
+    stats := pool()
+    resp.Pool = poolStatsResponse{
+      MaxOpenConnections: stats.MaxOpenConnections,
+      OpenConnections:    stats.OpenConnections,
+      InUse:              stats.InUse,
+      Idle:               stats.Idle,
+      WaitCount:          stats.WaitCount,
+      WaitDurationMS:     stats.WaitDuration.Milliseconds(),
+      MaxIdleClosed:      stats.MaxIdleClosed,
+      MaxIdleTimeClosed:  stats.MaxIdleTimeClosed,
+      MaxLifetimeClosed:  stats.MaxLifetimeClosed,
+    }
+
+    writeJSON(w, status, resp)
+  }
+}
@@ -1,12 +1,16 @@
 package handler
 
 import (
+  "errors"
   "fmt"
   "net/http"
   "strings"
   "time"
 
   "github.com/golang-jwt/jwt/v5"
+  "go.opentelemetry.io/otel/attribute"
+  "sarah-project-backend/logging"
+  "sarah-project-backend/security"
 )
 
 type jwtClaims struct {
@@ -14,7 +18,7 @@ type jwtClaims struct {
   jwt.RegisteredClaims
 }
 
-func createToken(userID int64, role string, secret string, issuer string, ttl time.Duration) (string, error) {
+func createToken(userID int64, role string, keys *security.KeySet, issuer string, ttl time.Duration) (string, error) {
   now := time.Now()
   claims := jwtClaims{
     Role: role,
@@ -26,33 +30,38 @@ func createToken(userID int64, role string, secret string, issuer string, ttl ti
     },
   }
 
-  token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-  return token.SignedString([]byte(secret))
+  return keys.Sign(claims)
 }
 
-func authenticateRequest(r *http.Request, secret string) (*jwtClaims, error) {
+func authenticateRequest(r *http.Request, keys *security.KeySet) (*jwtClaims, error) {
+  _, span := tracer.Start(r.Context(), "authenticateRequest")
+  defer span.End()
+
   authHeader := r.Header.Get("Authorization")
   if authHeader == "" {
-    return nil, fmt.Errorf("missing authorization header")
+    authFailed(span, "jwt", "missing")
+    return nil, errNoCredentials
   }
 
   parts := strings.SplitN(authHeader, " ", 2)
   if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
+    authFailed(span, "jwt", "malformed")
     return nil, fmt.Errorf("invalid authorization header")
   }
 
   tokenStr := strings.TrimSpace(parts[1])
   if tokenStr == "" {
+    authFailed(span, "jwt", "malformed")
     return nil, fmt.Errorf("empty token")
   }
 
-  token, err := jwt.ParseWithClaims(tokenStr, &jwtClaims{}, func(token *jwt.Token) (any, error) {
-    if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-      return nil, fmt.Errorf("unexpected signing method")
-    }
-    return []byte(secret), nil
-  })
+  token, err := jwt.ParseWithClaims(tokenStr, &jwtClaims{}, keys.Keyfunc, jwt.WithValidMethods(keys.Methods()))
   if err != nil || !token.Valid {
+    reason := "invalid"
+    if errors.Is(err, jwt.ErrTokenExpired) {
+      reason = "expired"
+    }
+    authFailed(span, "jwt", reason)
     return nil, fmt.Errorf("invalid token")
   }
 
@@ -60,5 +69,18 @@ func authenticateRequest(r *http.Request, secret string) (*jwtClaims, error) {
   if !ok {
     return nil, fmt.Errorf("invalid claims")
   }
+  span.SetAttributes(attribute.String("user.role", claims.Role))
+  if claims.Role == "admin" {
+    logging.SetAdmin(r.Context(), claims.Subject)
+  }
   return claims, nil
 }
+
+// JWKS publishes the public keys used to sign admin tokens so other
+// services can verify them. HMAC keys are never included.
+func JWKS(cfg AuthConfig) http.HandlerFunc {
+  return func(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Cache-Control", "public, max-age=300")
+    writeJSON(w, http.StatusOK, cfg.Keys.JWKS())
+  }
+}
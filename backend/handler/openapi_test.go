@@ -0,0 +1,197 @@
+package handler
+
+import (
+  "encoding/json"
+  "reflect"
+  "sort"
+  "strings"
+  "testing"
+  "time"
+
+  "sarah-project-backend/openapi"
+  "sarah-project-backend/validation"
+)
+
+type schema struct {
+  Ref        string             `json:"$ref"`
+  Type       any                `json:"type"`
+  Properties map[string]*schema `json:"properties"`
+  Required   []string           `json:"required"`
+  Items      *schema            `json:"items"`
+}
+
+// specSchemas maps each component schema to the handler type it
+// documents.
+var specSchemas = map[string]any{
+  "Error":                         errorResponse{},
+  "FieldError":                    validation.FieldError{},
+  "AdminLoginRequest":             adminLoginRequest{},
+  "AdminLoginResponse":            adminLoginResponse{},
+  "AdminStats":                    adminStats{},
+  "AdminProcessingOrder":          adminOrderRow{},
+  "AdminProcessingOrderList":      adminListResponse[adminOrderRow]{},
+  "AdminOrderSummary":             adminRecentRow{},
+  "AdminOrderList":                adminListResponse[adminRecentRow]{},
+  "AdminOrder":                    adminOrderDetail{},
+  "AdminOrderResponse":            adminOrderDetailResponse{},
+  "UpdateOrderStatusRequest":      updateOrderStatusRequest{},
+  "CreatePayoutBatchRequest":      createPayoutBatchRequest{},
+  "PayoutBatch":                   payoutBatchResponse{},
+  "CatalogueAsset":                catalogueAsset{},
+  "CatalogueCountry":              catalogueCountry{},
+  "Catalogue":                     catalogueResponse{},
+  "UpsertCatalogueAssetRequest":   upsertCatalogueAssetRequest{},
+  "UpsertCatalogueCountryRequest": upsertCatalogueCountryRequest{},
+  "CreateOrderRequest":            createOrderRequest{},
+  "CreateOrderResponse":           createOrderResponse{},
+  "Order":                         orderResponse{},
+  "OrderList":                     listOrdersResponse{},
+  "OrderResponse":                 orderDetailResponse{},
+  "Ready":                         readyResponse{},
+  "PoolStats":                     poolStatsResponse{},
+}
+
+// TestOpenAPISchemas fails when a request or response type gains, loses or
+// retypes a field without the same change to openapi.json.
+func TestOpenAPISchemas(t *testing.T) {
+  var doc struct {
+    Components struct {
+      Schemas map[string]*schema `json:"schemas"`
+    } `json:"components"`
+  }
+  if err := json.Unmarshal(openapi.Spec, &doc); err != nil {
+    t.Fatalf("openapi.json: %v", err)
+  }
+  schemas := doc.Components.Schemas
+
+  for name, v := range specSchemas {
+    t.Run(name, func(t *testing.T) {
+      s, ok := schemas[name]
+      if !ok {
+        t.Fatalf("no schema %s", name)
+      }
+      checkStruct(t, schemas, name, s, reflect.TypeOf(v))
+    })
+  }
+}
+
+func checkStruct(t *testing.T, schemas map[string]*schema, path string, s *schema, typ reflect.Type) {
+  t.Helper()
+
+  fields := map[string]reflect.StructField{}
+  for i := 0; i < typ.NumField(); i++ {
+    f := typ.Field(i)
+    name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+    if name != "" && name != "-" {
+      fields[name] = f
+    }
+  }
+
+  required := map[string]bool{}
+  for _, name := range s.Required {
+    required[name] = true
+  }
+  for name, f := range fields {
+    prop, ok := s.Properties[name]
+    if !ok {
+      t.Errorf("%s.%s: field %s is not documented", path, name, f.Name)
+      continue
+    }
+    checkType(t, schemas, path+"."+name, prop, f.Type, required[name])
+  }
+  for name := range s.Properties {
+    if _, ok := fields[name]; !ok {
+      t.Errorf("%s.%s: documented but %s has no such field", path, name, typ)
+    }
+  }
+  for _, name := range s.Required {
+    if _, ok := fields[name]; !ok {
+      t.Errorf("%s: required property %s does not exist", path, name)
+    }
+  }
+}
+
+// checkType compares a property with a field type. Pointer fields must be
+// nullable, except required ones, where the pointer only detects absence.
+func checkType(t *testing.T, schemas map[string]*schema, path string, s *schema, typ reflect.Type, required bool) {
+  t.Helper()
+
+  if s.Ref != "" {
+    name := strings.TrimPrefix(s.Ref, "#/components/schemas/")
+    if s = schemas[name]; s == nil {
+      t.Errorf("%s: dangling $ref %s", path, name)
+      return
+    }
+  }
+
+  types := schemaTypes(s)
+  nullable := typ.Kind() == reflect.Pointer
+  if nullable {
+    typ = typ.Elem()
+  }
+  if types["null"] && !nullable || nullable && !types["null"] && !required {
+    t.Errorf("%s: nullable is %v in the spec, %v in %s", path, types["null"], nullable, typ)
+  }
+
+  want := jsonType(typ)
+  if !types[want] {
+    t.Errorf("%s: spec type %v does not match %s (%s)", path, keys(types), typ, want)
+    return
+  }
+  switch want {
+  case "array":
+    if s.Items == nil {
+      t.Errorf("%s: array without items", path)
+      return
+    }
+    checkType(t, schemas, path+"[]", s.Items, typ.Elem(), false)
+  case "object":
+    if typ.Kind() == reflect.Struct {
+      checkStruct(t, schemas, path, s, typ)
+    }
+  }
+}
+
+// schemaTypes returns the set of JSON types s allows; type may be a string
+// or, in OpenAPI 3.1, a list.
+func schemaTypes(s *schema) map[string]bool {
+  set := map[string]bool{}
+  switch v := s.Type.(type) {
+  case string:
+    set[v] = true
+  case []any:
+    for _, t := range v {
+      set[t.(string)] = true
+    }
+  }
+  return set
+}
+
+func jsonType(typ reflect.Type) string {
+  if typ == reflect.TypeOf(time.Time{}) {
+    return "string"
+  }
+  switch typ.Kind() {
+  case reflect.String:
+    return "string"
+  case reflect.Bool:
+    return "boolean"
+  case reflect.Int, reflect.Int32, reflect.Int64:
+    return "integer"
+  case reflect.Float32, reflect.Float64:
+    return "number"
+  case reflect.Slice:
+    return "array"
+  default:
+    return "object"
+  }
+}
+
+func keys(set map[string]bool) []string {
+  out := make([]string, 0, len(set))
+  for k := range set {
+    out = append(out, k)
+  }
+  sort.Strings(out)
+  return out
+}
@@ -1,14 +1,20 @@
 package handler
 
 import (
-  "context"
   "database/sql"
-  "encoding/json"
-  "log"
+  "errors"
+  "fmt"
+  "log/slog"
+  "math"
   "net/http"
   "strconv"
   "strings"
   "time"
+
+  "sarah-project-backend/dto"
+  "sarah-project-backend/metrics"
+  "sarah-project-backend/store"
+  "sarah-project-backend/validation"
 )
 
 type createOrderRequest struct {
@@ -25,6 +31,9 @@ type createOrderRequest struct {
   ReferenceNote      *string  `json:"reference_note"`
 }
 
+// maxIdempotencyKeyLength matches the orders.idempotency_key column.
+const maxIdempotencyKeyLength = 128
+
 type createOrderResponse struct {
   ID int64 `json:"id"`
 }
@@ -41,362 +50,329 @@ type orderDetailResponse struct {
 }
 
 type orderResponse struct {
-  ID                 int64    `json:"id"`
-  TransactionNetwork string   `json:"transaction_network"`
-  TransactionAsset   string   `json:"transaction_asset"`
-  TXID               string   `json:"txid"`
-  Amount             *float64 `json:"amount"`
-  Email              string   `json:"email"`
-  BeneficiaryName    string   `json:"beneficiary_name"`
-  BankCountry        string   `json:"bank_country"`
-  BankName           string   `json:"bank_name"`
-  IBAN               string   `json:"iban"`
-  SWIFT              string   `json:"swift"`
-  ReferenceNote      *string  `json:"reference_note"`
-  Status             string   `json:"status"`
+  ID                 int64     `json:"id"`
+  TransactionNetwork string    `json:"transaction_network"`
+  TransactionAsset   string    `json:"transaction_asset"`
+  TXID               string    `json:"txid"`
+  Amount             *float64  `json:"amount"`
+  Email              string    `json:"email"`
+  BeneficiaryName    string    `json:"beneficiary_name"`
+  BankCountry        string    `json:"bank_country"`
+  BankName           string    `json:"bank_name"`
+  IBAN               string    `json:"iban"`
+  SWIFT              string    `json:"swift"`
+  ReferenceNote      *string   `json:"reference_note"`
+  Status             string    `json:"status"`
   CreatedAt          time.Time `json:"created_at"`
 }
 
-// CreateOrder allows a customer to create a new order.
-func CreateOrder(db *sql.DB) http.HandlerFunc {
+// CreateOrder allows a customer to create a new order. A request repeating
+// an earlier Idempotency-Key gets the order created the first time instead
+// of a duplicate.
+func CreateOrder(orders store.OrderStore, catalogue store.CatalogueStore) http.HandlerFunc {
   return func(w http.ResponseWriter, r *http.Request) {
-    if r.Method != http.MethodPost {
-      writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+    merchantName := principal(r).Merchant
+
+    key := strings.TrimSpace(r.Header.Get("Idempotency-Key"))
+    if len(key) > maxIdempotencyKeyLength {
+      writeFieldError(w, "Idempotency-Key", validation.CodeOutOfRange, fmt.Sprintf("Idempotency-Key must be at most %d characters", maxIdempotencyKeyLength))
       return
     }
 
-    merchantName, err := authenticateCustomer(r, db)
-    if err != nil {
-      writeError(w, http.StatusUnauthorized, "unauthorized")
+    var req createOrderRequest
+    if err := decodeJSON(w, r, &req); err != nil {
+      writeDecodeError(w, err)
       return
     }
+    order := newOrder(merchantName, req)
+
+    // The replay is checked before validation so a retry still succeeds
+    // after the catalogue changes.
+    if key != "" {
+      order.IdempotencyKey = sql.NullString{String: key, Valid: true}
+      existing, err := orders.GetOrderByIdempotencyKey(r.Context(), merchantName, key)
+      if err == nil {
+        replayCreateOrder(w, existing, order)
+        return
+      }
+      if !errors.Is(err, store.ErrNotFound) {
+        slog.ErrorContext(r.Context(), "create order idempotency lookup failed", "error", err)
+        writeError(w, http.StatusInternalServerError, codeInternal, "server error")
+        return
+      }
+    }
 
-    var req createOrderRequest
-    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-      writeError(w, http.StatusBadRequest, "invalid json body")
+    cat, err := catalogue.LoadCatalogue(r.Context(), true)
+    if err != nil {
+      slog.ErrorContext(r.Context(), "create order catalogue failed", "error", err)
+      writeError(w, http.StatusInternalServerError, codeInternal, "server error")
       return
     }
 
-    if err := validateCreateOrder(req); err != nil {
-      writeError(w, http.StatusBadRequest, err.Error())
+    if err := validateCreateOrder(req, cat); err != nil {
+      writeRequestError(w, err)
       return
     }
 
-    id, err := insertOrder(r.Context(), db, merchantName, req)
+    id, err := orders.CreateOrder(r.Context(), order)
     if err != nil {
-      log.Printf("create order error: %v", err)
-      writeError(w, http.StatusInternalServerError, "server error")
+      // A concurrent request with the same key won the insert.
+      if key != "" {
+        if existing, lookupErr := orders.GetOrderByIdempotencyKey(r.Context(), merchantName, key); lookupErr == nil {
+          replayCreateOrder(w, existing, order)
+          return
+        }
+      }
+      slog.ErrorContext(r.Context(), "create order failed", "error", err)
+      writeError(w, http.StatusInternalServerError, codeInternal, "server error")
       return
     }
+    metrics.OrdersCreated.WithLabelValues(order.TransactionNetwork, order.TransactionAsset).Inc()
 
     writeJSON(w, http.StatusCreated, createOrderResponse{ID: id})
   }
 }
 
 // ListCustomerOrders allows a customer to list their orders with pagination.
-func ListCustomerOrders(db *sql.DB) http.HandlerFunc {
+func ListCustomerOrders(orders store.OrderStore, pages PageConfig) http.HandlerFunc {
   return func(w http.ResponseWriter, r *http.Request) {
-    if r.Method != http.MethodGet {
-      writeError(w, http.StatusMethodNotAllowed, "method not allowed")
-      return
-    }
+    merchantName := principal(r).Merchant
 
-    merchantName, err := authenticateCustomer(r, db)
+    page, pageSize, err := parsePagination(r, pages)
     if err != nil {
-      writeError(w, http.StatusUnauthorized, "unauthorized")
+      writeRequestError(w, err)
       return
     }
 
-    page, pageSize, err := parsePagination(r)
+    total, rows, err := orders.ListMerchantOrders(store.AllowStale(r.Context()), merchantName, page, pageSize)
     if err != nil {
-      writeError(w, http.StatusBadRequest, err.Error())
+      slog.ErrorContext(r.Context(), "list orders failed", "error", err)
+      writeError(w, http.StatusInternalServerError, codeInternal, "server error")
       return
     }
 
-    total, orders, err := listOrdersByMerchant(r.Context(), db, merchantName, page, pageSize)
-    if err != nil {
-      log.Printf("list orders error: %v", err)
-      writeError(w, http.StatusInternalServerError, "server error")
-      return
+    items := make([]orderResponse, 0, len(rows))
+    for _, order := range rows {
+      items = append(items, toOrderResponse(r, order))
     }
 
     writeJSON(w, http.StatusOK, listOrdersResponse{
       Total:    total,
       Page:     page,
       PageSize: pageSize,
-      Orders:   orders,
+      Orders:   items,
     })
   }
 }
 
 // GetCustomerOrder returns a single order by ID for the authenticated merchant.
-func GetCustomerOrder(db *sql.DB) http.HandlerFunc {
+func GetCustomerOrder(orders store.OrderStore) http.HandlerFunc {
   return func(w http.ResponseWriter, r *http.Request) {
-    if r.Method != http.MethodGet {
-      writeError(w, http.StatusMethodNotAllowed, "method not allowed")
-      return
-    }
+    merchantName := principal(r).Merchant
 
-    merchantName, err := authenticateCustomer(r, db)
+    orderID, err := parseIDParam(r, "id")
     if err != nil {
-      writeError(w, http.StatusUnauthorized, "unauthorized")
+      writeRequestError(w, err)
       return
     }
 
-    rawID := r.URL.Query().Get("id")
-    if rawID == "" {
-      writeError(w, http.StatusBadRequest, "id is required")
-      return
-    }
-    orderID, err := strconv.ParseInt(rawID, 10, 64)
-    if err != nil || orderID <= 0 {
-      writeError(w, http.StatusBadRequest, "invalid id")
-      return
-    }
-
-    order, err := getOrderByID(r.Context(), db, merchantName, orderID)
+    order, err := orders.GetMerchantOrder(r.Context(), merchantName, orderID)
     if err != nil {
-      if err == sql.ErrNoRows {
-        writeError(w, http.StatusNotFound, "order not found")
+      if errors.Is(err, store.ErrNotFound) {
+        writeError(w, http.StatusNotFound, codeNotFound, "order not found")
         return
       }
-      log.Printf("get order error: %v", err)
-      writeError(w, http.StatusInternalServerError, "server error")
+      slog.ErrorContext(r.Context(), "get order failed", "error", err)
+      writeError(w, http.StatusInternalServerError, codeInternal, "server error")
       return
     }
 
-    writeJSON(w, http.StatusOK, orderDetailResponse{Order: order})
+    writeJSON(w, http.StatusOK, orderDetailResponse{Order: toOrderResponse(r, order)})
   }
 }
 
-func validateCreateOrder(req createOrderRequest) error {
-  if req.TransactionNetwork == "" ||
-    req.TransactionAsset == "" ||
-    req.TXID == "" ||
-    req.Email == "" ||
-    req.BeneficiaryName == "" ||
-    req.BankCountry == "" ||
-    req.BankName == "" ||
-    req.IBAN == "" ||
-    req.SWIFT == "" {
-    return errBadRequest("missing required fields")
-  }
-
-  if !isAllowed(req.TransactionNetwork, []string{"TRON", "BSC", "Ethereum"}) {
-    return errBadRequest("invalid transaction_network")
-  }
-  if !isAllowed(req.TransactionAsset, []string{"USDT", "USDC"}) {
-    return errBadRequest("invalid transaction_asset")
-  }
-  if !isAllowed(req.BankCountry, []string{"Canada", "United States"}) {
-    return errBadRequest("invalid bank_country")
-  }
-
-  if req.Amount != nil && *req.Amount < 0 {
-    return errBadRequest("amount must be non-negative")
-  }
-
-  if !strings.Contains(req.Email, "@") {
-    return errBadRequest("invalid email")
+// replayCreateOrder answers a repeated Idempotency-Key with the order it
+// created, or 409 when the key was used for a different order.
+func replayCreateOrder(w http.ResponseWriter, existing dto.OrderDTO, order dto.OrderDTO) {
+  if !sameOrder(existing, order) {
+    writeError(w, http.StatusConflict, codeConflict, "Idempotency-Key was already used for a different order")
+    return
   }
+  w.Header().Set("Idempotent-Replayed", "true")
+  writeJSON(w, http.StatusCreated, createOrderResponse{ID: existing.ID})
+}
 
-  return nil
+// sameOrder compares the fields a merchant sets when creating an order.
+func sameOrder(a, b dto.OrderDTO) bool {
+  return a.MerchantName == b.MerchantName &&
+    a.TransactionNetwork == b.TransactionNetwork &&
+    a.TransactionAsset == b.TransactionAsset &&
+    a.TXID == b.TXID &&
+    sameAmount(a.Amount, b.Amount) &&
+    a.Email == b.Email &&
+    a.BeneficiaryName == b.BeneficiaryName &&
+    a.BankCountry == b.BankCountry &&
+    a.BankName == b.BankName &&
+    a.IBAN == b.IBAN &&
+    a.SWIFT == b.SWIFT &&
+    a.ReferenceNote == b.ReferenceNote
 }
 
-func insertOrder(ctx context.Context, db *sql.DB, merchantName string, req createOrderRequest) (int64, error) {
-  ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
-  defer cancel()
+// sameAmount compares amounts at the 8 decimal places the orders.amount
+// column keeps.
+func sameAmount(a, b sql.NullFloat64) bool {
+  return a.Valid == b.Valid && math.Round(a.Float64*1e8) == math.Round(b.Float64*1e8)
+}
 
-  var amount sql.NullFloat64
-  if req.Amount != nil {
-    amount = sql.NullFloat64{Float64: *req.Amount, Valid: true}
+func validateCreateOrder(req createOrderRequest, cat store.Catalogue) error {
+  var errs validation.Errors
+
+  required := []struct {
+    field string
+    value string
+  }{
+    {"transaction_network", req.TransactionNetwork},
+    {"transaction_asset", req.TransactionAsset},
+    {"txid", req.TXID},
+    {"email", req.Email},
+    {"beneficiary_name", req.BeneficiaryName},
+    {"bank_country", req.BankCountry},
+    {"bank_name", req.BankName},
+    {"iban", req.IBAN},
+    {"swift", req.SWIFT},
   }
-
-  var note sql.NullString
-  if req.ReferenceNote != nil && strings.TrimSpace(*req.ReferenceNote) != "" {
-    note = sql.NullString{String: *req.ReferenceNote, Valid: true}
+  for _, f := range required {
+    if f.value == "" {
+      errs.Add(f.field, validation.CodeRequired, f.field+" is required")
+    }
   }
 
-  result, err := db.ExecContext(ctx, `
-    INSERT INTO orders (
-      merchant_name,
-      transaction_network,
-      transaction_asset,
-      txid,
-      amount,
-      email,
-      beneficiary_name,
-      bank_country,
-      bank_name,
-      iban,
-      swift,
-      reference_note,
-      status
-    ) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-  `,
-    merchantName,
-    req.TransactionNetwork,
-    req.TransactionAsset,
-    req.TXID,
-    amount,
-    req.Email,
-    req.BeneficiaryName,
-    req.BankCountry,
-    req.BankName,
-    req.IBAN,
-    req.SWIFT,
-    note,
-    "Processing",
-  )
-  if err != nil {
-    return 0, err
+  if req.TransactionNetwork != "" && !cat.HasNetwork(req.TransactionNetwork) {
+    errs.Add("transaction_network", validation.CodeUnsupported, "invalid transaction_network")
   }
-
-  return result.LastInsertId()
-}
-
-func listOrdersByMerchant(ctx context.Context, db *sql.DB, merchantName string, page int, pageSize int) (int64, []orderResponse, error) {
-  ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
-  defer cancel()
-
-  var total int64
-  if err := db.QueryRowContext(ctx, `
-    SELECT COUNT(*)
-    FROM orders
-    WHERE merchant_name = ?
-  `, merchantName).Scan(&total); err != nil {
-    return 0, nil, err
+  if req.TransactionAsset != "" && !cat.HasAsset(req.TransactionAsset) {
+    errs.Add("transaction_asset", validation.CodeUnsupported, "invalid transaction_asset")
   }
-
-  offset := (page - 1) * pageSize
-  rows, err := db.QueryContext(ctx, `
-    SELECT id, transaction_network, transaction_asset, txid, amount, email, beneficiary_name,
-           bank_country, bank_name, iban, swift, reference_note, status, created_at
-    FROM orders
-    WHERE merchant_name = ?
-    ORDER BY id DESC
-    LIMIT ? OFFSET ?
-  `, merchantName, pageSize, offset)
-  if err != nil {
-    return 0, nil, err
+  asset, assetOK := cat.FindAsset(req.TransactionNetwork, req.TransactionAsset)
+  if !assetOK && cat.HasNetwork(req.TransactionNetwork) && cat.HasAsset(req.TransactionAsset) {
+    errs.Add("transaction_asset", validation.CodeUnsupported, req.TransactionAsset+" is not supported on "+req.TransactionNetwork)
   }
-  defer rows.Close()
-
-  orders := make([]orderResponse, 0)
-  for rows.Next() {
-    var (
-      amount sql.NullFloat64
-      note   sql.NullString
-      order  orderResponse
-    )
-    if err := rows.Scan(
-      &order.ID,
-      &order.TransactionNetwork,
-      &order.TransactionAsset,
-      &order.TXID,
-      &amount,
-      &order.Email,
-      &order.BeneficiaryName,
-      &order.BankCountry,
-      &order.BankName,
-      &order.IBAN,
-      &order.SWIFT,
-      &note,
-      &order.Status,
-      &order.CreatedAt,
-    ); err != nil {
-      return 0, nil, err
-    }
-    if amount.Valid {
-      order.Amount = &amount.Float64
+  bankCountry, bankCountryOK := cat.FindCountry(req.BankCountry)
+  if req.BankCountry != "" && !bankCountryOK {
+    errs.Add("bank_country", validation.CodeUnsupported, "invalid bank_country")
+  }
+
+  if req.Amount != nil && *req.Amount < 0 {
+    errs.Add("amount", validation.CodeOutOfRange, "amount must be non-negative")
+  } else if req.Amount != nil && assetOK {
+    if asset.MinAmount.Valid && *req.Amount < asset.MinAmount.Float64 {
+      errs.Add("amount", validation.CodeOutOfRange, fmt.Sprintf("amount must be at least %g", asset.MinAmount.Float64))
     }
-    if note.Valid && strings.TrimSpace(note.String) != "" {
-      order.ReferenceNote = &note.String
+    if asset.MaxAmount.Valid && *req.Amount > asset.MaxAmount.Float64 {
+      errs.Add("amount", validation.CodeOutOfRange, fmt.Sprintf("amount must be at most %g", asset.MaxAmount.Float64))
     }
-    orders = append(orders, order)
   }
-  if err := rows.Err(); err != nil {
-    return 0, nil, err
+
+  if req.Email != "" && !strings.Contains(req.Email, "@") {
+    errs.Add("email", validation.CodeInvalidFormat, "invalid email")
   }
 
-  return total, orders, nil
+  if bankCountryOK && req.IBAN != "" && req.SWIFT != "" {
+    errs = append(errs, validation.Beneficiary(bankCountry.ISOCode, req.IBAN, req.SWIFT)...)
+  }
+
+  return errs.Err()
 }
 
-func getOrderByID(ctx context.Context, db *sql.DB, merchantName string, orderID int64) (orderResponse, error) {
-  ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
-  defer cancel()
-
-  var (
-    amount sql.NullFloat64
-    note   sql.NullString
-    order  orderResponse
-  )
-
-  row := db.QueryRowContext(ctx, `
-    SELECT id, transaction_network, transaction_asset, txid, amount, email, beneficiary_name,
-           bank_country, bank_name, iban, swift, reference_note, status, created_at
-    FROM orders
-    WHERE merchant_name = ? AND id = ?
-    LIMIT 1
-  `, merchantName, orderID)
-  if err := row.Scan(
-    &order.ID,
-    &order.TransactionNetwork,
-    &order.TransactionAsset,
-    &order.TXID,
-    &amount,
-    &order.Email,
-    &order.BeneficiaryName,
-    &order.BankCountry,
-    &order.BankName,
-    &order.IBAN,
-    &order.SWIFT,
-    &note,
-    &order.Status,
-    &order.CreatedAt,
-  ); err != nil {
-    return orderResponse{}, err
+func newOrder(merchantName string, req createOrderRequest) dto.OrderDTO {
+  order := dto.OrderDTO{
+    MerchantName:       merchantName,
+    TransactionNetwork: req.TransactionNetwork,
+    TransactionAsset:   req.TransactionAsset,
+    TXID:               req.TXID,
+    Email:              req.Email,
+    BeneficiaryName:    req.BeneficiaryName,
+    BankCountry:        req.BankCountry,
+    BankName:           req.BankName,
+    IBAN:               req.IBAN,
+    SWIFT:              req.SWIFT,
+    Status:             dto.StatusProcessing,
   }
-
-  if amount.Valid {
-    order.Amount = &amount.Float64
+  if req.Amount != nil {
+    order.Amount = sql.NullFloat64{Float64: *req.Amount, Valid: true}
   }
-  if note.Valid && strings.TrimSpace(note.String) != "" {
-    order.ReferenceNote = &note.String
+  if req.ReferenceNote != nil && strings.TrimSpace(*req.ReferenceNote) != "" {
+    order.ReferenceNote = sql.NullString{String: *req.ReferenceNote, Valid: true}
   }
+  return order
+}
 
-  return order, nil
+func toOrderResponse(r *http.Request, order dto.OrderDTO) orderResponse {
+  return orderResponse{
+    ID:                 order.ID,
+    TransactionNetwork: order.TransactionNetwork,
+    TransactionAsset:   order.TransactionAsset,
+    TXID:               order.TXID,
+    Amount:             floatPtr(order.Amount),
+    Email:              order.Email,
+    BeneficiaryName:    order.BeneficiaryName,
+    BankCountry:        order.BankCountry,
+    BankName:           order.BankName,
+    IBAN:               order.IBAN,
+    SWIFT:              order.SWIFT,
+    ReferenceNote:      notePtr(order.ReferenceNote),
+    Status:             presentStatus(r, order.Status),
+    CreatedAt:          order.CreatedAt,
+  }
 }
 
-func isAllowed(value string, allowed []string) bool {
-  for _, item := range allowed {
-    if value == item {
-      return true
-    }
+func floatPtr(value sql.NullFloat64) *float64 {
+  if !value.Valid {
+    return nil
   }
-  return false
+  v := value.Float64
+  return &v
 }
 
-type badRequestError struct {
-  message string
+func notePtr(value sql.NullString) *string {
+  if !value.Valid || strings.TrimSpace(value.String) == "" {
+    return nil
+  }
+  v := value.String
+  return &v
 }
 
-func (e badRequestError) Error() string {
-  return e.message
+// parseIDParam reads a positive ID from the path, falling back to the query
+// string used by the legacy routes.
+func parseIDParam(r *http.Request, name string) (int64, error) {
+  raw := r.PathValue(name)
+  if raw == "" {
+    raw = r.URL.Query().Get(name)
+  }
+  if raw == "" {
+    return 0, validation.Errors{{Field: name, Code: validation.CodeRequired, Message: name + " is required"}}
+  }
+  id, err := strconv.ParseInt(raw, 10, 64)
+  if err != nil || id <= 0 {
+    return 0, validation.Errors{{Field: name, Code: validation.CodeInvalidValue, Message: "invalid " + name}}
+  }
+  return id, nil
 }
 
-func errBadRequest(message string) error {
-  return badRequestError{message: message}
+// PageConfig controls the page_size query parameter of list endpoints.
+type PageConfig struct {
+  DefaultSize int
+  MaxSize     int
 }
 
-func parsePagination(r *http.Request) (int, int, error) {
+func parsePagination(r *http.Request, pages PageConfig) (int, int, error) {
   page := 1
-  pageSize := 20
+  pageSize := pages.DefaultSize
 
   if raw := r.URL.Query().Get("page"); raw != "" {
     parsed, err := strconv.Atoi(raw)
     if err != nil || parsed <= 0 {
-      return 0, 0, errBadRequest("invalid page")
+      return 0, 0, validation.Errors{{Field: "page", Code: validation.CodeInvalidValue, Message: "invalid page"}}
     }
     page = parsed
   }
@@ -404,10 +380,10 @@ func parsePagination(r *http.Request) (int, int, error) {
   if raw := r.URL.Query().Get("page_size"); raw != "" {
     parsed, err := strconv.Atoi(raw)
     if err != nil || parsed <= 0 {
-      return 0, 0, errBadRequest("invalid page_size")
+      return 0, 0, validation.Errors{{Field: "page_size", Code: validation.CodeInvalidValue, Message: "invalid page_size"}}
     }
-    if parsed > 100 {
-      parsed = 100
+    if parsed > pages.MaxSize {
+      parsed = pages.MaxSize
     }
     pageSize = parsed
   }
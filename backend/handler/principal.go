@@ -0,0 +1,151 @@
+package handler
+
+import (
+  "context"
+  "errors"
+  "log/slog"
+  "net/http"
+  "strconv"
+
+  "sarah-project-backend/security"
+  "sarah-project-backend/store"
+)
+
+// Permission names an action a route requires.
+type Permission string
+
+const (
+  PermOrdersRead     Permission = "orders:read"
+  PermOrdersWrite    Permission = "orders:write"
+  PermPayoutsRead    Permission = "payouts:read"
+  PermPayoutsWrite   Permission = "payouts:write"
+  PermCatalogueRead  Permission = "catalogue:read"
+  PermCatalogueWrite Permission = "catalogue:write"
+  PermMerchantsWrite Permission = "merchants:write"
+  // PermMerchantOrders lets a merchant create and read its own orders.
+  PermMerchantOrders Permission = "merchant:orders"
+)
+
+// rolePermissions lists what each admin token role grants. Roles not
+// listed authenticate but are granted nothing.
+var rolePermissions = map[string][]Permission{
+  "admin": {
+    PermOrdersRead, PermOrdersWrite,
+    PermPayoutsRead, PermPayoutsWrite,
+    PermCatalogueRead, PermCatalogueWrite,
+    PermMerchantsWrite,
+  },
+}
+
+var (
+  errNoCredentials      = errors.New("no credentials")
+  errInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Principal is the authenticated caller of a request. Admins are identified
+// by AdminID and Role, merchants by Merchant.
+type Principal struct {
+  AdminID  int64
+  Role     string
+  Merchant string
+}
+
+// Can reports whether p is granted perm.
+func (p Principal) Can(perm Permission) bool {
+  if p.Merchant != "" {
+    return perm == PermMerchantOrders
+  }
+  for _, granted := range rolePermissions[p.Role] {
+    if granted == perm {
+      return true
+    }
+  }
+  return false
+}
+
+type principalKey struct{}
+
+// PrincipalFromContext returns the principal stored by Authenticator.
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+  p, ok := ctx.Value(principalKey{}).(Principal)
+  return p, ok
+}
+
+// principal returns the caller of a request that passed Authenticator.
+func principal(r *http.Request) Principal {
+  p, _ := PrincipalFromContext(r.Context())
+  return p
+}
+
+// Authenticator checks credentials once per request, before the handler
+// runs, and stores the resulting Principal in the request context.
+// Missing or invalid credentials get 401, valid credentials lacking the
+// route's permission get 403; both carry a WWW-Authenticate challenge.
+type Authenticator struct {
+  keys      *security.KeySet
+  merchants store.MerchantStore
+}
+
+func NewAuthenticator(keys *security.KeySet, merchants store.MerchantStore) *Authenticator {
+  return &Authenticator{keys: keys, merchants: merchants}
+}
+
+// Admin requires a bearer token whose role grants perm.
+func (a *Authenticator) Admin(perm Permission, next http.Handler) http.HandlerFunc {
+  return func(w http.ResponseWriter, r *http.Request) {
+    const challenge = `Bearer realm="admin"`
+    claims, err := authenticateRequest(r, a.keys)
+    if err != nil {
+      if errors.Is(err, errNoCredentials) {
+        unauthorized(w, challenge)
+      } else {
+        unauthorized(w, challenge+`, error="invalid_token"`)
+      }
+      return
+    }
+    id, err := strconv.ParseInt(claims.Subject, 10, 64)
+    if err != nil {
+      unauthorized(w, challenge+`, error="invalid_token"`)
+      return
+    }
+    p := Principal{AdminID: id, Role: claims.Role}
+    if !p.Can(perm) {
+      forbidden(w, challenge+`, error="insufficient_scope"`)
+      return
+    }
+    next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), principalKey{}, p)))
+  }
+}
+
+// Merchant requires an active merchant API key granting perm.
+func (a *Authenticator) Merchant(perm Permission, next http.Handler) http.HandlerFunc {
+  return func(w http.ResponseWriter, r *http.Request) {
+    const challenge = `APIKey realm="customer"`
+    merchant, err := authenticateCustomer(r, a.merchants)
+    if err != nil {
+      if errors.Is(err, errNoCredentials) || errors.Is(err, errInvalidCredentials) {
+        unauthorized(w, challenge)
+        return
+      }
+      slog.ErrorContext(r.Context(), "customer auth failed", "error", err)
+      writeError(w, http.StatusInternalServerError, codeInternal, "server error")
+      return
+    }
+    p := Principal{Merchant: merchant}
+    if !p.Can(perm) {
+      forbidden(w, challenge)
+      return
+    }
+    next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), principalKey{}, p)))
+  }
+}
+
+func unauthorized(w http.ResponseWriter, challenge string) {
+  w.Header().Set("WWW-Authenticate", challenge)
+  writeError(w, http.StatusUnauthorized, codeUnauthorized, "unauthorized")
+}
+
+func forbidden(w http.ResponseWriter, challenge string) {
+  w.Header().Set("WWW-Authenticate", challenge)
+  writeError(w, http.StatusForbidden, codeForbidden, "forbidden")
+}
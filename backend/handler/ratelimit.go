@@ -0,0 +1,180 @@
+package handler
+
+import (
+  "context"
+  "crypto/sha256"
+  "encoding/hex"
+  "errors"
+  "log/slog"
+  "math"
+  "net"
+  "net/http"
+  "strconv"
+  "strings"
+  "sync"
+  "time"
+
+  "sarah-project-backend/metrics"
+  "sarah-project-backend/ratelimit"
+  "sarah-project-backend/store"
+)
+
+const (
+  // quotaCacheTTL is how long a merchant quota read from the store is
+  // reused.
+  quotaCacheTTL = time.Minute
+  // maxCachedQuotas bounds the quota cache, which is keyed by the
+  // unauthenticated X-Merchant-Name header.
+  maxCachedQuotas = 10000
+)
+
+// RateLimitConfig sets the limits applied by RateLimiter. A limit with
+// PerMinute <= 0 is disabled.
+type RateLimitConfig struct {
+  // Merchant is the default quota for each merchant and API key pair.
+  Merchant ratelimit.Limit
+  // IP limits each client address on customer routes.
+  IP ratelimit.Limit
+  // Login limits admin sign-in attempts from each client address.
+  Login ratelimit.Limit
+  // TrustForwardedFor takes the client address from the last
+  // X-Forwarded-For entry. Enable it only behind a reverse proxy that
+  // appends to the header.
+  TrustForwardedFor bool
+}
+
+// RateLimiter wraps handlers with token bucket limits.
+type RateLimiter struct {
+  buckets ratelimit.Store
+  quotas  store.RateLimitStore
+  cfg     RateLimitConfig
+
+  mu     sync.Mutex
+  cached map[string]cachedQuota
+}
+
+type cachedQuota struct {
+  limit   ratelimit.Limit
+  expires time.Time
+}
+
+// NewRateLimiter returns a limiter keeping buckets in buckets and reading
+// per-merchant quotas from quotas.
+func NewRateLimiter(buckets ratelimit.Store, quotas store.RateLimitStore, cfg RateLimitConfig) *RateLimiter {
+  return &RateLimiter{
+    buckets: buckets,
+    quotas:  quotas,
+    cfg:     cfg,
+    cached:  make(map[string]cachedQuota),
+  }
+}
+
+// Customer limits merchant API calls per client address and per merchant
+// and API key. Requests without credentials only count against the address
+// limit; the handler rejects them.
+func (l *RateLimiter) Customer(next http.HandlerFunc) http.HandlerFunc {
+  return func(w http.ResponseWriter, r *http.Request) {
+    if !l.allow(w, r, "ip", "ip:"+l.clientIP(r), l.cfg.IP) {
+      return
+    }
+    merchant := strings.TrimSpace(r.Header.Get("X-Merchant-Name"))
+    apiKey := strings.TrimSpace(r.Header.Get("X-API-Key"))
+    if merchant != "" && apiKey != "" {
+      if !l.allow(w, r, "merchant", merchantBucket(merchant, apiKey), l.merchantLimit(r.Context(), merchant)) {
+        return
+      }
+    }
+    next(w, r)
+  }
+}
+
+// Login limits admin sign-in attempts per client address.
+func (l *RateLimiter) Login(next http.HandlerFunc) http.HandlerFunc {
+  return func(w http.ResponseWriter, r *http.Request) {
+    if !l.allow(w, r, "login", "login:"+l.clientIP(r), l.cfg.Login) {
+      return
+    }
+    next(w, r)
+  }
+}
+
+// allow takes a token for key and sets the X-RateLimit-* headers. When the
+// bucket is empty it responds 429 and returns false. Store errors are logged
+// and the request is let through.
+func (l *RateLimiter) allow(w http.ResponseWriter, r *http.Request, scope, key string, limit ratelimit.Limit) bool {
+  if limit.Unlimited() {
+    return true
+  }
+  res, err := l.buckets.Take(r.Context(), key, limit)
+  if err != nil {
+    slog.ErrorContext(r.Context(), "rate limit failed", "scope", scope, "error", err)
+    return true
+  }
+
+  h := w.Header()
+  h.Set("X-RateLimit-Limit", strconv.Itoa(res.Limit))
+  h.Set("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+  h.Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+  if res.Allowed {
+    return true
+  }
+  metrics.RateLimited.WithLabelValues(scope).Inc()
+  h.Set("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+  writeError(w, http.StatusTooManyRequests, codeRateLimited, "rate limit exceeded")
+  return false
+}
+
+// merchantLimit returns the merchant's stored quota or the default.
+func (l *RateLimiter) merchantLimit(ctx context.Context, merchant string) ratelimit.Limit {
+  now := time.Now()
+  l.mu.Lock()
+  cached, ok := l.cached[merchant]
+  l.mu.Unlock()
+  if ok && now.Before(cached.expires) {
+    return cached.limit
+  }
+
+  limit, err := l.quotas.MerchantRateLimit(ctx, merchant)
+  switch {
+  case errors.Is(err, store.ErrNotFound):
+    limit = l.cfg.Merchant
+  case err != nil:
+    slog.ErrorContext(ctx, "merchant rate limit lookup failed", "error", err)
+    return l.cfg.Merchant
+  }
+
+  l.mu.Lock()
+  if len(l.cached) >= maxCachedQuotas {
+    clear(l.cached)
+  }
+  l.cached[merchant] = cachedQuota{limit: limit, expires: now.Add(quotaCacheTTL)}
+  l.mu.Unlock()
+  return limit
+}
+
+func (l *RateLimiter) clientIP(r *http.Request) string {
+  if l.cfg.TrustForwardedFor {
+    if fwd := r.Header.Values("X-Forwarded-For"); len(fwd) > 0 {
+      hops := strings.Split(fwd[len(fwd)-1], ",")
+      if ip := net.ParseIP(strings.TrimSpace(hops[len(hops)-1])); ip != nil {
+        return ip.String()
+      }
+    }
+  }
+  host, _, err := net.SplitHostPort(r.RemoteAddr)
+  if err != nil {
+    return r.RemoteAddr
+  }
+  return host
+}
+
+// merchantBucket hashes the unauthenticated credentials so bucket keys have
+// a fixed length and API keys are not stored.
+func merchantBucket(merchant, apiKey string) string {
+  sum := sha256.Sum256([]byte(merchant + "\x00" + apiKey))
+  return "merchant:" + hex.EncodeToString(sum[:16])
+}
+
+func ceilSeconds(d time.Duration) int {
+  return int(math.Ceil(d.Seconds()))
+}
@@ -2,17 +2,95 @@ package handler
 
 import (
   "encoding/json"
+  "errors"
   "net/http"
+
+  "sarah-project-backend/logging"
+  "sarah-project-backend/validation"
+)
+
+// Machine-readable error codes returned in the "code" field of error responses.
+// These values are part of the public API and must not change.
+const (
+  codeInvalidRequest     = "invalid_request"
+  codeInvalidJSON        = "invalid_json"
+  codePayloadTooLarge    = "payload_too_large"
+  codeUnsupportedMedia   = "unsupported_media_type"
+  codeValidationFailed   = "validation_failed"
+  codeUnauthorized       = "unauthorized"
+  codeForbidden          = "forbidden"
+  codeInvalidCredentials = "invalid_credentials"
+  codeMethodNotAllowed   = "method_not_allowed"
+  codeNotFound           = "not_found"
+  codeConflict           = "conflict"
+  codeUnprocessable      = "unprocessable"
+  codeNotConfigured      = "not_configured"
+  codeRateLimited        = "rate_limited"
+  codeInternal           = "internal_error"
 )
 
+type errorResponse struct {
+  Code    string                  `json:"code"`
+  Message string                  `json:"message"`
+  Details []validation.FieldError `json:"details"`
+  // Error repeats Message for clients written against the original
+  // {"error": "..."} format.
+  Error string `json:"error"`
+  // RequestID lets callers quote the failed request in support tickets.
+  RequestID string `json:"request_id,omitempty"`
+}
+
 func writeJSON(w http.ResponseWriter, status int, payload any) {
   w.Header().Set("Content-Type", "application/json")
   w.WriteHeader(status)
   _ = json.NewEncoder(w).Encode(payload)
 }
 
-func writeError(w http.ResponseWriter, status int, message string) {
-  writeJSON(w, status, map[string]string{
-    "error": message,
+func writeError(w http.ResponseWriter, status int, code string, message string) {
+  writeErrorDetails(w, status, code, message, nil)
+}
+
+func writeErrorDetails(w http.ResponseWriter, status int, code string, message string, details validation.Errors) {
+  if details == nil {
+    details = validation.Errors{}
+  }
+  writeJSON(w, status, errorResponse{
+    Code:    code,
+    Message: message,
+    Details: details,
+    Error:   message,
+    // The request ID middleware has already set the response header.
+    RequestID: w.Header().Get(logging.RequestIDHeader),
   })
 }
+
+// MethodNotAllowed responds 405 with allow, the route's methods, in the
+// Allow header.
+func MethodNotAllowed(allow string) http.HandlerFunc {
+  return func(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Allow", allow)
+    writeError(w, http.StatusMethodNotAllowed, codeMethodNotAllowed, "method not allowed")
+  }
+}
+
+// writeFieldError reports a single invalid field.
+func writeFieldError(w http.ResponseWriter, field string, code string, message string) {
+  writeErrorDetails(w, http.StatusBadRequest, codeValidationFailed, message, validation.Errors{
+    {Field: field, Code: code, Message: message},
+  })
+}
+
+// writeRequestError turns an input error into a 400 response, listing field
+// errors when err carries them.
+func writeRequestError(w http.ResponseWriter, err error) {
+  var fieldErrs validation.Errors
+  if errors.As(err, &fieldErrs) && len(fieldErrs) > 0 {
+    message := fieldErrs[0].Message
+    if len(fieldErrs) > 1 {
+      message = "request validation failed"
+    }
+    writeErrorDetails(w, http.StatusBadRequest, codeValidationFailed, message, fieldErrs)
+    return
+  }
+  writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+}
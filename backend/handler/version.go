@@ -0,0 +1,67 @@
+package handler
+
+import (
+  "fmt"
+  "net/http"
+  "strconv"
+  "strings"
+
+  "sarah-project-backend/dto"
+  "sarah-project-backend/validation"
+)
+
+const (
+  apiVersionHeader = "X-API-Version"
+
+  // apiVersionLegacy is the original API, which spelled the submitted
+  // status "Summitted".
+  apiVersionLegacy  = 1
+  apiVersionCurrent = 2
+
+  legacyStatusSubmitted = "Summitted"
+)
+
+// requestAPIVersion reads the X-API-Version header. Requests without it use
+// the current version; ok is false for a version the server does not
+// implement.
+func requestAPIVersion(r *http.Request) (version int, ok bool) {
+  raw := strings.TrimPrefix(strings.ToLower(strings.TrimSpace(r.Header.Get(apiVersionHeader))), "v")
+  if raw == "" {
+    return apiVersionCurrent, true
+  }
+  version, err := strconv.Atoi(raw)
+  if err != nil || version < apiVersionLegacy || version > apiVersionCurrent {
+    return 0, false
+  }
+  return version, true
+}
+
+// APIVersion rejects requests that ask for an unknown API version before
+// they reach next.
+func APIVersion(next http.Handler) http.Handler {
+  return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    if _, ok := requestAPIVersion(r); !ok {
+      writeFieldError(w, apiVersionHeader, validation.CodeUnsupported, fmt.Sprintf("%s must be %d or %d", apiVersionHeader, apiVersionLegacy, apiVersionCurrent))
+      return
+    }
+    next.ServeHTTP(w, r)
+  })
+}
+
+// normalizeStatus maps legacy status spellings accepted from any client to
+// the stored value.
+func normalizeStatus(status string) string {
+  if status == legacyStatusSubmitted {
+    return dto.StatusSubmitted
+  }
+  return status
+}
+
+// presentStatus converts a stored status into the spelling expected by the
+// caller's API version.
+func presentStatus(r *http.Request, status string) string {
+  if version, _ := requestAPIVersion(r); status == dto.StatusSubmitted && version == apiVersionLegacy {
+    return legacyStatusSubmitted
+  }
+  return status
+}
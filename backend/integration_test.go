@@ -0,0 +1,320 @@
+package main
+
+import (
+  "bytes"
+  "context"
+  "database/sql"
+  "encoding/json"
+  "flag"
+  "io"
+  "log/slog"
+  "net/http"
+  "net/http/httptest"
+  "os"
+  "path/filepath"
+  "strings"
+  "testing"
+  "time"
+
+  "sarah-project-backend/app"
+  "sarah-project-backend/config"
+  "sarah-project-backend/database"
+  "sarah-project-backend/security"
+  "sarah-project-backend/store"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files in testdata/golden")
+
+const (
+  testJWTSecret     = "integration-test-secret"
+  testAdminUsername = "admin"
+  testAdminPassword = "correct-horse-battery-staple"
+)
+
+type testMerchant struct {
+  Name   string
+  APIKey string
+}
+
+var (
+  merchantAcme   = testMerchant{Name: "acme", APIKey: "acme-key"}
+  merchantGlobex = testMerchant{Name: "globex", APIKey: "globex-key"}
+  // merchantRevoked has a key with active = 0.
+  merchantRevoked = testMerchant{Name: "initech", APIKey: "initech-key"}
+)
+
+// zeroTime is how an unset time.Time encodes to JSON.
+const zeroTime = "0001-01-01T00:00:00Z"
+
+// volatileKeys are replaced with placeholders before comparing against
+// golden files because their values change on every run.
+var volatileKeys = map[string]bool{
+  "token":         true,
+  "created_at":    true,
+  "time_received": true,
+  "last_update":   true,
+  "request_id":    true,
+}
+
+func TestMain(m *testing.M) {
+  flag.Parse()
+  // Also silences the log package, which writes through the default logger.
+  slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+  os.Exit(m.Run())
+}
+
+type testServer struct {
+  t     *testing.T
+  url   string
+  db    *sql.DB
+  app   *app.App
+  store store.Store
+}
+
+// newTestServer boots the production router against a fresh, migrated
+// in-memory SQLite database seeded with one admin and three merchants.
+// configure, when given, adjusts the configuration before the app is built.
+func newTestServer(t *testing.T, configure ...func(*config.Config)) *testServer {
+  t.Helper()
+
+  db, err := database.OpenSQLite(":memory:")
+  if err != nil {
+    t.Fatalf("open sqlite: %v", err)
+  }
+  t.Cleanup(func() { db.Close() })
+  cfg := config.Default()
+  cfg.Database.Driver = string(database.SQLite)
+  cfg.JWT.Secret = testJWTSecret
+  cfg.JWT.Issuer = "sarah-project-test"
+  cfg.JWT.TTL = time.Hour
+  // Tests sign in far more often than the production login limit allows.
+  cfg.RateLimit.Login = config.RateLimitRule{PerMinute: 600, Burst: 100}
+  for _, fn := range configure {
+    fn(&cfg)
+  }
+  a, err := app.NewWithDB(cfg, db)
+  if err != nil {
+    t.Fatalf("app: %v", err)
+  }
+  t.Cleanup(func() { a.Close() })
+
+  hash, err := security.HashPassword(testAdminPassword)
+  if err != nil {
+    t.Fatalf("hash password: %v", err)
+  }
+  seed := []struct {
+    query string
+    args  []any
+  }{
+    {`INSERT INTO admin_users (username, email, password_hash) VALUES (?, ?, ?)`, []any{testAdminUsername, "admin@example.com", hash}},
+    {`INSERT INTO customer_api_keys (merchant_name, api_key) VALUES (?, ?)`, []any{merchantAcme.Name, merchantAcme.APIKey}},
+    {`INSERT INTO customer_api_keys (merchant_name, api_key) VALUES (?, ?)`, []any{merchantGlobex.Name, merchantGlobex.APIKey}},
+    {`INSERT INTO customer_api_keys (merchant_name, api_key, active) VALUES (?, ?, 0)`, []any{merchantRevoked.Name, merchantRevoked.APIKey}},
+  }
+  for _, s := range seed {
+    if _, err := db.Exec(s.query, s.args...); err != nil {
+      t.Fatalf("seed: %v", err)
+    }
+  }
+
+  srv := httptest.NewServer(a.Handler())
+  t.Cleanup(srv.Close)
+
+  return &testServer{t: t, url: srv.URL, db: db, app: a, store: a.Store()}
+}
+
+type apiRequest struct {
+  method   string
+  path     string
+  body     string
+  token    string
+  merchant *testMerchant
+  header   map[string]string
+}
+
+type apiResponse struct {
+  status int
+  header http.Header
+  body   []byte
+}
+
+func (s *testServer) do(req apiRequest) apiResponse {
+  s.t.Helper()
+
+  var body io.Reader
+  if req.body != "" {
+    body = strings.NewReader(req.body)
+  }
+  httpReq, err := http.NewRequest(req.method, s.url+req.path, body)
+  if err != nil {
+    s.t.Fatalf("build request: %v", err)
+  }
+  if req.body != "" {
+    httpReq.Header.Set("Content-Type", "application/json")
+  }
+  if req.token != "" {
+    httpReq.Header.Set("Authorization", "Bearer "+req.token)
+  }
+  if req.merchant != nil {
+    httpReq.Header.Set("X-API-Key", req.merchant.APIKey)
+    httpReq.Header.Set("X-Merchant-Name", req.merchant.Name)
+  }
+  // An empty value removes a header the helper would otherwise send.
+  for k, v := range req.header {
+    if v == "" {
+      httpReq.Header.Del(k)
+    } else {
+      httpReq.Header.Set(k, v)
+    }
+  }
+
+  resp, err := http.DefaultClient.Do(httpReq)
+  if err != nil {
+    s.t.Fatalf("%s %s: %v", req.method, req.path, err)
+  }
+  defer resp.Body.Close()
+  data, err := io.ReadAll(resp.Body)
+  if err != nil {
+    s.t.Fatalf("read body: %v", err)
+  }
+  return apiResponse{status: resp.StatusCode, header: resp.Header, body: data}
+}
+
+// adminToken logs in as the seeded admin and returns the bearer token.
+func (s *testServer) adminToken() string {
+  s.t.Helper()
+
+  resp := s.do(apiRequest{
+    method: http.MethodPost,
+    path:   "/admin/login",
+    body:   `{"username":"` + testAdminUsername + `","password":"` + testAdminPassword + `"}`,
+  })
+  if resp.status != http.StatusOK {
+    s.t.Fatalf("admin login: status %d: %s", resp.status, resp.body)
+  }
+  var out struct {
+    Token string `json:"token"`
+  }
+  if err := json.Unmarshal(resp.body, &out); err != nil || out.Token == "" {
+    s.t.Fatalf("admin login: no token in %s", resp.body)
+  }
+  return out.Token
+}
+
+// validOrderBody returns a createOrder payload accepted by the seeded
+// catalogue. txid distinguishes otherwise identical orders.
+func validOrderBody(txid string) string {
+  return `{
+    "transaction_network": "TRON",
+    "transaction_asset": "USDT",
+    "txid": "` + txid + `",
+    "amount": 1250.5,
+    "email": "payer@example.com",
+    "beneficiary_name": "Jane Doe",
+    "bank_country": "Canada",
+    "bank_name": "Royal Bank of Canada",
+    "iban": "1234567",
+    "swift": "000312345",
+    "reference_note": "invoice ` + txid + `"
+  }`
+}
+
+// createOrder creates an order through the API and returns its ID.
+func (s *testServer) createOrder(m testMerchant, txid string) int64 {
+  s.t.Helper()
+
+  resp := s.do(apiRequest{method: http.MethodPost, path: "/customer/orders", body: validOrderBody(txid), merchant: &m})
+  if resp.status != http.StatusCreated {
+    s.t.Fatalf("create order: status %d: %s", resp.status, resp.body)
+  }
+  var out struct {
+    ID int64 `json:"id"`
+  }
+  if err := json.Unmarshal(resp.body, &out); err != nil {
+    s.t.Fatalf("create order: %v", err)
+  }
+  return out.ID
+}
+
+// setStatus moves an order to status directly in the store.
+func (s *testServer) setStatus(id int64, status string) {
+  s.t.Helper()
+
+  if _, err := s.store.UpdateOrderStatus(context.Background(), id, status); err != nil {
+    s.t.Fatalf("set status: %v", err)
+  }
+}
+
+// setTimestamps pins created_at and updated_at so ordering by time is
+// deterministic within a test.
+func (s *testServer) setTimestamps(id int64, at time.Time) {
+  s.t.Helper()
+
+  if _, err := s.db.Exec(`UPDATE orders SET created_at = ?, updated_at = ? WHERE id = ?`, at.UTC(), at.UTC(), id); err != nil {
+    s.t.Fatalf("set timestamps: %v", err)
+  }
+}
+
+// assertGolden compares a JSON body with testdata/golden/<name>.json after
+// masking volatile fields. Run `go test -update` to rewrite the files.
+func assertGolden(t *testing.T, name string, body []byte) {
+  t.Helper()
+
+  got, err := normalizeJSON(body)
+  if err != nil {
+    t.Fatalf("golden %s: response is not JSON: %v\n%s", name, err, body)
+  }
+
+  path := filepath.Join("testdata", "golden", name+".json")
+  if *update {
+    if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+      t.Fatal(err)
+    }
+    if err := os.WriteFile(path, got, 0o644); err != nil {
+      t.Fatal(err)
+    }
+    return
+  }
+
+  want, err := os.ReadFile(path)
+  if err != nil {
+    t.Fatalf("golden %s: %v (run go test -update to create it)", name, err)
+  }
+  if !bytes.Equal(got, want) {
+    t.Errorf("golden %s mismatch\n--- want\n%s\n--- got\n%s", name, want, got)
+  }
+}
+
+func normalizeJSON(body []byte) ([]byte, error) {
+  dec := json.NewDecoder(bytes.NewReader(body))
+  dec.UseNumber()
+  var v any
+  if err := dec.Decode(&v); err != nil {
+    return nil, err
+  }
+  out, err := json.MarshalIndent(maskVolatile(v), "", "  ")
+  if err != nil {
+    return nil, err
+  }
+  return append(out, '\n'), nil
+}
+
+func maskVolatile(v any) any {
+  switch val := v.(type) {
+  case map[string]any:
+    for k, child := range val {
+      // The zero time is never a real value, so it is left in place for
+      // the golden file to catch.
+      if s, ok := child.(string); ok && volatileKeys[k] && s != "" && s != zeroTime {
+        val[k] = "<" + k + ">"
+        continue
+      }
+      val[k] = maskVolatile(child)
+    }
+  case []any:
+    for i, child := range val {
+      val[i] = maskVolatile(child)
+    }
+  }
+  return v
+}
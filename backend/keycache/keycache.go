@@ -0,0 +1,169 @@
+// Package keycache caches merchant API key lookups in process so polling
+// merchants do not cost a database round trip per request.
+package keycache
+
+import (
+  "container/list"
+  "context"
+  "crypto/sha256"
+  "errors"
+  "sync"
+  "time"
+
+  "sarah-project-backend/metrics"
+  "sarah-project-backend/store"
+)
+
+// Config sizes the cache. Size <= 0 or TTL <= 0 disables it.
+type Config struct {
+  // Size is the most lookups kept; the least recently used is evicted.
+  Size int
+  // TTL is how long a valid key is trusted without asking the store. It
+  // bounds how long another instance keeps accepting a deactivated key.
+  TTL time.Duration
+  // NegativeTTL is how long an unknown key is rejected without asking the
+  // store. 0 disables negative caching.
+  NegativeTTL time.Duration
+}
+
+// Cache is a store.MerchantStore that answers repeated MerchantByAPIKey
+// lookups from an LRU. Deactivating a key through the cache drops its
+// entries at once. It is safe for concurrent use.
+type Cache struct {
+  merchants store.MerchantStore
+  cfg       Config
+
+  mu      sync.Mutex
+  now     func() time.Time
+  lru     *list.List
+  entries map[[sha256.Size]byte]*list.Element
+  // version is bumped by every invalidation so a lookup that raced with it
+  // does not cache what it read before the change.
+  version uint64
+}
+
+type entry struct {
+  key      [sha256.Size]byte
+  merchant string
+  found    bool
+  expires  time.Time
+}
+
+// New returns a cache in front of merchants.
+func New(merchants store.MerchantStore, cfg Config) *Cache {
+  return &Cache{
+    merchants: merchants,
+    cfg:       cfg,
+    now:       time.Now,
+    lru:       list.New(),
+    entries:   make(map[[sha256.Size]byte]*list.Element),
+  }
+}
+
+// SetClock replaces the time source.
+func (c *Cache) SetClock(now func() time.Time) {
+  c.mu.Lock()
+  defer c.mu.Unlock()
+  c.now = now
+}
+
+func (c *Cache) enabled() bool {
+  return c.cfg.Size > 0 && c.cfg.TTL > 0
+}
+
+// MerchantByAPIKey implements store.MerchantStore. Store errors other than
+// ErrNotFound are returned without being cached.
+func (c *Cache) MerchantByAPIKey(ctx context.Context, apiKey string, merchantName string) (string, error) {
+  if !c.enabled() {
+    return c.merchants.MerchantByAPIKey(ctx, apiKey, merchantName)
+  }
+  // Keys are held hashed so a heap dump does not reveal them.
+  key := sha256.Sum256([]byte(merchantName + "\x00" + apiKey))
+
+  c.mu.Lock()
+  if el, ok := c.entries[key]; ok {
+    e := el.Value.(*entry)
+    if c.now().Before(e.expires) {
+      c.lru.MoveToFront(el)
+      c.mu.Unlock()
+      if !e.found {
+        metrics.APIKeyCache.WithLabelValues("negative_hit").Inc()
+        return "", store.ErrNotFound
+      }
+      metrics.APIKeyCache.WithLabelValues("hit").Inc()
+      return e.merchant, nil
+    }
+    c.remove(el)
+  }
+  version := c.version
+  c.mu.Unlock()
+  metrics.APIKeyCache.WithLabelValues("miss").Inc()
+
+  matched, err := c.merchants.MerchantByAPIKey(ctx, apiKey, merchantName)
+  switch {
+  case err == nil:
+    c.add(version, &entry{key: key, merchant: matched, found: true}, c.cfg.TTL)
+  case errors.Is(err, store.ErrNotFound):
+    c.add(version, &entry{key: key, merchant: merchantName}, c.cfg.NegativeTTL)
+  }
+  return matched, err
+}
+
+// DeactivateAPIKey implements store.MerchantStore and forgets every cached
+// lookup for merchantName, so the key stops working in this process
+// immediately.
+func (c *Cache) DeactivateAPIKey(ctx context.Context, merchantName string) error {
+  err := c.merchants.DeactivateAPIKey(ctx, merchantName)
+  c.Invalidate(merchantName)
+  return err
+}
+
+// Invalidate forgets every cached lookup for merchantName.
+func (c *Cache) Invalidate(merchantName string) {
+  c.mu.Lock()
+  defer c.mu.Unlock()
+
+  c.version++
+  for el := c.lru.Front(); el != nil; {
+    next := el.Next()
+    if el.Value.(*entry).merchant == merchantName {
+      c.remove(el)
+    }
+    el = next
+  }
+}
+
+// Len returns the number of cached lookups, including expired ones not yet
+// evicted.
+func (c *Cache) Len() int {
+  c.mu.Lock()
+  defer c.mu.Unlock()
+  return c.lru.Len()
+}
+
+func (c *Cache) add(version uint64, e *entry, ttl time.Duration) {
+  if ttl <= 0 {
+    return
+  }
+  c.mu.Lock()
+  defer c.mu.Unlock()
+
+  if version != c.version {
+    return
+  }
+  e.expires = c.now().Add(ttl)
+  if el, ok := c.entries[e.key]; ok {
+    el.Value = e
+    c.lru.MoveToFront(el)
+    return
+  }
+  c.entries[e.key] = c.lru.PushFront(e)
+  for c.lru.Len() > c.cfg.Size {
+    c.remove(c.lru.Back())
+  }
+}
+
+func (c *Cache) remove(el *list.Element) {
+  delete(c.entries, el.Value.(*entry).key)
+  c.lru.Remove(el)
+}
@@ -0,0 +1,150 @@
+package keycache
+
+import (
+  "context"
+  "errors"
+  "testing"
+  "time"
+
+  "sarah-project-backend/store"
+)
+
+// countingStore counts lookups that reach the store.
+type countingStore struct {
+  *store.Memory
+  lookups int
+  // during, when set, runs inside the next lookup.
+  during func()
+}
+
+func (s *countingStore) MerchantByAPIKey(ctx context.Context, apiKey string, merchantName string) (string, error) {
+  s.lookups++
+  if fn := s.during; fn != nil {
+    s.during = nil
+    fn()
+  }
+  return s.Memory.MerchantByAPIKey(ctx, apiKey, merchantName)
+}
+
+func newCache(t *testing.T, cfg Config) (*Cache, *countingStore, *time.Time) {
+  t.Helper()
+  mem := store.NewMemory()
+  mem.AddAPIKey("acme-key", "acme")
+  mem.AddAPIKey("globex-key", "globex")
+  st := &countingStore{Memory: mem}
+  now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+  c := New(st, cfg)
+  c.SetClock(func() time.Time { return now })
+  return c, st, &now
+}
+
+func TestCache(t *testing.T) {
+  ctx := context.Background()
+  c, st, now := newCache(t, Config{Size: 10, TTL: 30 * time.Second, NegativeTTL: 5 * time.Second})
+
+  lookup := func(key, merchant string) error {
+    t.Helper()
+    _, err := c.MerchantByAPIKey(ctx, key, merchant)
+    return err
+  }
+
+  for i := 0; i < 3; i++ {
+    if err := lookup("acme-key", "acme"); err != nil {
+      t.Fatal(err)
+    }
+  }
+  if st.lookups != 1 {
+    t.Fatalf("store lookups = %d, want 1", st.lookups)
+  }
+
+  // Unknown keys are remembered for the shorter negative TTL.
+  for i := 0; i < 3; i++ {
+    if err := lookup("wrong", "acme"); !errors.Is(err, store.ErrNotFound) {
+      t.Fatalf("wrong key: %v", err)
+    }
+  }
+  if st.lookups != 2 {
+    t.Fatalf("store lookups = %d, want 2", st.lookups)
+  }
+  *now = now.Add(6 * time.Second)
+  lookup("wrong", "acme")
+  lookup("acme-key", "acme")
+  if st.lookups != 3 {
+    t.Fatalf("store lookups after negative TTL = %d, want 3", st.lookups)
+  }
+
+  *now = now.Add(30 * time.Second)
+  lookup("acme-key", "acme")
+  if st.lookups != 4 {
+    t.Fatalf("store lookups after TTL = %d, want 4", st.lookups)
+  }
+}
+
+func TestCacheDeactivate(t *testing.T) {
+  ctx := context.Background()
+  c, _, _ := newCache(t, Config{Size: 10, TTL: time.Hour, NegativeTTL: time.Hour})
+
+  if _, err := c.MerchantByAPIKey(ctx, "acme-key", "acme"); err != nil {
+    t.Fatal(err)
+  }
+  if _, err := c.MerchantByAPIKey(ctx, "globex-key", "globex"); err != nil {
+    t.Fatal(err)
+  }
+  if err := c.DeactivateAPIKey(ctx, "acme"); err != nil {
+    t.Fatal(err)
+  }
+  if _, err := c.MerchantByAPIKey(ctx, "acme-key", "acme"); !errors.Is(err, store.ErrNotFound) {
+    t.Fatalf("deactivated key: err = %v, want ErrNotFound", err)
+  }
+  if c.Len() != 2 {
+    t.Fatalf("len = %d, want globex and the negative acme entry", c.Len())
+  }
+}
+
+func TestCacheInvalidateDuringLookup(t *testing.T) {
+  ctx := context.Background()
+  c, st, _ := newCache(t, Config{Size: 10, TTL: time.Hour})
+
+  // The lookup reads the key as valid, then the key is revoked before the
+  // result is cached. The stale result must not be cached.
+  st.during = func() { c.Invalidate("acme") }
+  if _, err := c.MerchantByAPIKey(ctx, "acme-key", "acme"); err != nil {
+    t.Fatal(err)
+  }
+  if c.Len() != 0 {
+    t.Fatalf("len = %d, want the raced lookup dropped", c.Len())
+  }
+}
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+  ctx := context.Background()
+  c, st, _ := newCache(t, Config{Size: 2, TTL: time.Hour, NegativeTTL: time.Hour})
+
+  c.MerchantByAPIKey(ctx, "acme-key", "acme")
+  c.MerchantByAPIKey(ctx, "globex-key", "globex")
+  c.MerchantByAPIKey(ctx, "acme-key", "acme")
+  c.MerchantByAPIKey(ctx, "other", "initech")
+  if c.Len() != 2 {
+    t.Fatalf("len = %d, want 2", c.Len())
+  }
+
+  before := st.lookups
+  c.MerchantByAPIKey(ctx, "acme-key", "acme")
+  if st.lookups != before {
+    t.Fatal("recently used entry was evicted")
+  }
+  c.MerchantByAPIKey(ctx, "globex-key", "globex")
+  if st.lookups != before+1 {
+    t.Fatal("least recently used entry was kept")
+  }
+}
+
+func TestCacheDisabled(t *testing.T) {
+  c, st, _ := newCache(t, Config{})
+  for i := 0; i < 2; i++ {
+    c.MerchantByAPIKey(context.Background(), "acme-key", "acme")
+  }
+  if st.lookups != 2 || c.Len() != 0 {
+    t.Fatalf("disabled cache: lookups = %d, len = %d", st.lookups, c.Len())
+  }
+}
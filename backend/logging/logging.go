@@ -0,0 +1,111 @@
+// Package logging configures the structured logger and carries per-request
+// values, such as the request ID, through the context.
+package logging
+
+import (
+  "context"
+  "fmt"
+  "io"
+  "log/slog"
+
+  "go.opentelemetry.io/otel/trace"
+)
+
+// RequestIDHeader is the header that carries the request ID in both
+// directions.
+const RequestIDHeader = "X-Request-ID"
+
+// New returns a logger writing to w. format is "json" or "text" and level is
+// debug, info, warn or error. Records logged with a request context carry
+// its request ID and, when traced, the trace and span IDs.
+func New(w io.Writer, format, level string) (*slog.Logger, error) {
+  var lvl slog.Level
+  if err := lvl.UnmarshalText([]byte(level)); err != nil {
+    return nil, fmt.Errorf("unknown level %q", level)
+  }
+  opts := &slog.HandlerOptions{Level: lvl}
+
+  var h slog.Handler
+  switch format {
+  case "json":
+    h = slog.NewJSONHandler(w, opts)
+  case "text":
+    h = slog.NewTextHandler(w, opts)
+  default:
+    return nil, fmt.Errorf("unknown format %q", format)
+  }
+  return slog.New(contextHandler{h}), nil
+}
+
+// contextHandler adds request and trace IDs from the record's context.
+type contextHandler struct {
+  slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+  if id := RequestID(ctx); id != "" {
+    r.AddAttrs(slog.String("request_id", id))
+  }
+  if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+    r.AddAttrs(slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
+  }
+  return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+  return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+  return contextHandler{h.Handler.WithGroup(name)}
+}
+
+type requestKey struct{}
+
+// request holds values filled in while a request is handled. Handlers run on
+// the request's goroutine, so it needs no locking.
+type request struct {
+  id       string
+  merchant string
+  adminID  string
+}
+
+// WithRequestID returns a context for a request with the given ID.
+func WithRequestID(ctx context.Context, id string) context.Context {
+  return context.WithValue(ctx, requestKey{}, &request{id: id})
+}
+
+func fromContext(ctx context.Context) *request {
+  req, _ := ctx.Value(requestKey{}).(*request)
+  return req
+}
+
+// RequestID returns the ID of the request ctx belongs to, or "".
+func RequestID(ctx context.Context) string {
+  if req := fromContext(ctx); req != nil {
+    return req.id
+  }
+  return ""
+}
+
+// SetMerchant records the authenticated merchant for the access log.
+func SetMerchant(ctx context.Context, name string) {
+  if req := fromContext(ctx); req != nil {
+    req.merchant = name
+  }
+}
+
+// SetAdmin records the authenticated admin for the access log.
+func SetAdmin(ctx context.Context, id string) {
+  if req := fromContext(ctx); req != nil {
+    req.adminID = id
+  }
+}
+
+// Principal returns the merchant or admin recorded for the request.
+func Principal(ctx context.Context) (merchant, adminID string) {
+  if req := fromContext(ctx); req != nil {
+    return req.merchant, req.adminID
+  }
+  return "", ""
+}
@@ -0,0 +1,52 @@
+package logging
+
+import (
+  "bytes"
+  "context"
+  "encoding/json"
+  "testing"
+)
+
+func TestNewAddsRequestID(t *testing.T) {
+  var buf bytes.Buffer
+  logger, err := New(&buf, "json", "info")
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  ctx := WithRequestID(context.Background(), "req-1")
+  logger.With("component", "test").InfoContext(ctx, "hello")
+  logger.DebugContext(ctx, "dropped")
+
+  var record map[string]any
+  if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+    t.Fatalf("want one JSON record, got %q: %v", buf.String(), err)
+  }
+  if record["msg"] != "hello" || record["request_id"] != "req-1" || record["component"] != "test" {
+    t.Errorf("record = %v", record)
+  }
+}
+
+func TestNewRejectsUnknownSettings(t *testing.T) {
+  if _, err := New(&bytes.Buffer{}, "xml", "info"); err == nil {
+    t.Error("unknown format accepted")
+  }
+  if _, err := New(&bytes.Buffer{}, "json", "loud"); err == nil {
+    t.Error("unknown level accepted")
+  }
+}
+
+func TestPrincipal(t *testing.T) {
+  ctx := WithRequestID(context.Background(), "req-1")
+  SetMerchant(ctx, "acme")
+  SetAdmin(ctx, "7")
+  if merchant, admin := Principal(ctx); merchant != "acme" || admin != "7" {
+    t.Errorf("Principal = %q, %q", merchant, admin)
+  }
+
+  // Without a request context the setters are no-ops.
+  SetMerchant(context.Background(), "acme")
+  if merchant, _ := Principal(context.Background()); merchant != "" {
+    t.Errorf("Principal without request = %q", merchant)
+  }
+}
@@ -2,218 +2,65 @@ package main
 
 import (
   "context"
-  "database/sql"
-  "fmt"
+  "flag"
   "log"
-  "net/http"
+  "log/slog"
   "os"
-  "strconv"
-  "strings"
   "time"
 
-  "github.com/joho/godotenv"
-  _ "github.com/go-sql-driver/mysql"
-  "sarah-project-backend/handler"
+  "sarah-project-backend/app"
+  "sarah-project-backend/config"
+  "sarah-project-backend/tracing"
 )
 
 func main() {
-  _ = godotenv.Load()
+  configPath := flag.String("config", os.Getenv("CONFIG_FILE"), "optional YAML or TOML config file")
+  printConfig := flag.Bool("print-config", false, "print the effective configuration with secrets redacted and exit")
+  flag.Parse()
 
-  db, err := openDB()
+  cfg, err := config.Load(*configPath)
   if err != nil {
-    log.Fatal(err)
-  }
-  defer db.Close()
-  if err := ensureTables(db); err != nil {
-    log.Fatal(err)
+    log.Fatalf("load configuration:\n%v", err)
   }
-
-  jwtConfig, err := loadJWTConfig()
-  if err != nil {
-    log.Fatal(err)
-  }
-
-  mux := http.NewServeMux()
-  mux.HandleFunc("/admin/login", handler.AdminLogin(db, jwtConfig))
-  mux.HandleFunc("/admin/stats", handler.AdminStats(db, jwtConfig))
-  mux.HandleFunc("/admin/ready-processing", handler.AdminReadyProcessing(db, jwtConfig))
-  mux.HandleFunc("/admin/recent-orders", handler.AdminRecentOrders(db, jwtConfig))
-  mux.HandleFunc("/admin/order", handler.AdminOrderDetail(db, jwtConfig))
-  mux.HandleFunc("/admin/order/status", handler.AdminUpdateOrderStatus(db, jwtConfig))
-  mux.HandleFunc("/customer/createOrder", handler.CreateOrder(db))
-  mux.HandleFunc("/customer/orders", handler.ListCustomerOrders(db))
-  mux.HandleFunc("/customer/order", handler.GetCustomerOrder(db))
-  mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-    w.Header().Set("Content-Type", "application/json")
-    w.WriteHeader(http.StatusOK)
-    _, _ = w.Write([]byte(`{"status":"ok"}`))
-  })
-
-  server := &http.Server{
-    Addr:         ":8080",
-    Handler:      withCORS(mux),
-    ReadTimeout:  5 * time.Second,
-    WriteTimeout: 10 * time.Second,
-    IdleTimeout:  60 * time.Second,
-  }
-
-  log.Println("API listening on :8080")
-  if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-    log.Fatal(err)
-  }
-}
-
-func withCORS(next http.Handler) http.Handler {
-  return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-    w.Header().Set("Access-Control-Allow-Origin", "*")
-    w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-    w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key, X-Merchant-Name")
-    if r.Method == http.MethodOptions {
-      w.WriteHeader(http.StatusNoContent)
-      return
+  if *printConfig {
+    if err := cfg.Print(os.Stdout); err != nil {
+      log.Fatal(err)
     }
-    next.ServeHTTP(w, r)
-  })
-}
-
-func openDB() (*sql.DB, error) {
-  host := os.Getenv("MYSQL_HOST")
-  port := os.Getenv("MYSQL_PORT")
-  user := os.Getenv("MYSQL_USER")
-  password := os.Getenv("MYSQL_PASSWORD")
-  dbName := os.Getenv("MYSQL_DB")
-  params := os.Getenv("MYSQL_PARAMS")
-  if params == "" {
-    params = "charset=utf8mb4&parseTime=True&loc=Local"
-  }
-
-  missing := make([]string, 0, 5)
-  if host == "" {
-    missing = append(missing, "MYSQL_HOST")
-  }
-  if port == "" {
-    missing = append(missing, "MYSQL_PORT")
-  }
-  if user == "" {
-    missing = append(missing, "MYSQL_USER")
   }
-  if password == "" {
-    missing = append(missing, "MYSQL_PASSWORD")
+  if err := cfg.Validate(); err != nil {
+    log.Fatalf("invalid configuration:\n%v", err)
   }
-  if dbName == "" {
-    missing = append(missing, "MYSQL_DB")
+  if *printConfig {
+    return
   }
-  if len(missing) > 0 {
-    return nil, fmt.Errorf("missing env vars: %s (see .env.example)", strings.Join(missing, ", "))
-  }
-
-  dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?%s", user, password, host, port, dbName, params)
 
-  db, err := sql.Open("mysql", dsn)
+  // The standard log package writes through the default logger from here
+  // on, so every line shares the configured format.
+  logger, err := cfg.Log.Logger(os.Stderr)
   if err != nil {
-    return nil, err
-  }
-
-  ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-  defer cancel()
-  if err := db.PingContext(ctx); err != nil {
-    return nil, err
-  }
-
-  return db, nil
-}
-
-func loadJWTConfig() (handler.AuthConfig, error) {
-  secret := os.Getenv("JWT_SECRET")
-  if secret == "" {
-    return handler.AuthConfig{}, fmt.Errorf("JWT_SECRET is required (see .env.example)")
+    log.Fatal(err)
   }
+  slog.SetDefault(logger)
 
-  issuer := os.Getenv("JWT_ISSUER")
-  if issuer == "" {
-    issuer = "sarah-project"
+  // Tracing is set up before the database is opened so its driver is
+  // instrumented with the configured provider.
+  shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config(cfg.Tracing), os.Stdout)
+  if err != nil {
+    log.Fatal(err)
   }
 
-  ttlMinutes := 60
-  if raw := os.Getenv("JWT_TTL_MINUTES"); raw != "" {
-    parsed, err := strconv.Atoi(raw)
-    if err != nil || parsed <= 0 {
-      return handler.AuthConfig{}, fmt.Errorf("JWT_TTL_MINUTES must be a positive integer")
-    }
-    ttlMinutes = parsed
+  a, err := app.New(cfg)
+  if err != nil {
+    log.Fatal(err)
   }
+  runErr := a.Run(context.Background())
 
-  return handler.AuthConfig{
-    JWTSecret: secret,
-    JWTIssuer: issuer,
-    JWTTTL:    time.Duration(ttlMinutes) * time.Minute,
-  }, nil
-}
-
-func ensureTables(db *sql.DB) error {
   ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
   defer cancel()
-
-  statements := []string{
-    `
-      CREATE TABLE IF NOT EXISTS admin_users (
-        id BIGINT AUTO_INCREMENT PRIMARY KEY,
-        username VARCHAR(64) NOT NULL UNIQUE,
-        email VARCHAR(128) NOT NULL,
-        password_hash VARCHAR(255) NOT NULL,
-        created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-        updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
-      ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
-    `,
-    `
-      CREATE TABLE IF NOT EXISTS customer_users (
-        id BIGINT AUTO_INCREMENT PRIMARY KEY,
-        name VARCHAR(128) NOT NULL,
-        email VARCHAR(128) NOT NULL UNIQUE,
-        password_hash VARCHAR(255) NOT NULL,
-        created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-        updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
-      ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
-    `,
-    `
-      CREATE TABLE IF NOT EXISTS customer_api_keys (
-        id BIGINT AUTO_INCREMENT PRIMARY KEY,
-        merchant_name VARCHAR(128) NOT NULL,
-        api_key VARCHAR(128) NOT NULL,
-        active TINYINT(1) NOT NULL DEFAULT 1,
-        created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-        updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
-        UNIQUE KEY uniq_api_key (api_key),
-        UNIQUE KEY uniq_merchant_name (merchant_name)
-      ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
-    `,
-    `
-      CREATE TABLE IF NOT EXISTS orders (
-        id BIGINT AUTO_INCREMENT PRIMARY KEY,
-        merchant_name VARCHAR(128) NOT NULL,
-        transaction_network ENUM('TRON', 'BSC', 'Ethereum') NOT NULL,
-        transaction_asset ENUM('USDT', 'USDC') NOT NULL,
-        txid VARCHAR(128) NOT NULL,
-        amount DECIMAL(18, 8) NULL,
-        email VARCHAR(128) NOT NULL,
-        beneficiary_name VARCHAR(128) NOT NULL,
-        bank_country ENUM('Canada', 'United States') NOT NULL,
-        bank_name VARCHAR(128) NOT NULL,
-        iban VARCHAR(64) NOT NULL,
-        swift VARCHAR(64) NOT NULL,
-        reference_note TEXT NULL,
-        status ENUM('Paid', 'Processing', 'Summitted', 'Failed', 'Funds Received') NOT NULL DEFAULT 'Processing',
-        created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-        updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
-      ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
-    `,
+  if err := shutdownTracing(ctx); err != nil {
+    slog.Error("flush traces failed", "error", err)
   }
-
-  for _, stmt := range statements {
-    if _, err := db.ExecContext(ctx, stmt); err != nil {
-      return err
-    }
+  if runErr != nil {
+    log.Fatal(runErr)
   }
-
-  return nil
 }
@@ -0,0 +1,100 @@
+// Package metrics defines the Prometheus metrics served on /metrics.
+package metrics
+
+import (
+  "database/sql"
+  "net/http"
+
+  "github.com/prometheus/client_golang/prometheus"
+  "github.com/prometheus/client_golang/prometheus/collectors"
+  "github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "sarah"
+
+// Registry holds every metric exported by the service.
+var Registry = prometheus.NewRegistry()
+
+var (
+  // HTTPRequests counts requests by mux route, method and status code.
+  HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+    Namespace: namespace,
+    Name:      "http_requests_total",
+    Help:      "HTTP requests by route, method and status code.",
+  }, []string{"route", "method", "status"})
+
+  // HTTPDuration observes request latency by mux route and method.
+  HTTPDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+    Namespace: namespace,
+    Name:      "http_request_duration_seconds",
+    Help:      "HTTP request latency by route and method.",
+    Buckets:   prometheus.DefBuckets,
+  }, []string{"route", "method"})
+
+  // OrdersCreated counts orders accepted through the customer API.
+  OrdersCreated = prometheus.NewCounterVec(prometheus.CounterOpts{
+    Namespace: namespace,
+    Name:      "orders_created_total",
+    Help:      "Orders created by transaction network and asset.",
+  }, []string{"network", "asset"})
+
+  // StatusTransitions counts order status changes.
+  StatusTransitions = prometheus.NewCounterVec(prometheus.CounterOpts{
+    Namespace: namespace,
+    Name:      "order_status_transitions_total",
+    Help:      "Order status changes by previous and new status.",
+  }, []string{"from", "to"})
+
+  // AuthFailures counts rejected credentials. method is jwt, api_key or
+  // password.
+  AuthFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+    Namespace: namespace,
+    Name:      "auth_failures_total",
+    Help:      "Rejected credentials by authentication method and reason.",
+  }, []string{"method", "reason"})
+
+  // RateLimited counts requests rejected with 429. scope is ip, merchant or
+  // login.
+  RateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+    Namespace: namespace,
+    Name:      "rate_limited_total",
+    Help:      "Requests rejected by the rate limiter by bucket scope.",
+  }, []string{"scope"})
+
+  // APIKeyCache counts merchant API key lookups by result: hit,
+  // negative_hit or miss.
+  APIKeyCache = prometheus.NewCounterVec(prometheus.CounterOpts{
+    Namespace: namespace,
+    Name:      "api_key_cache_lookups_total",
+    Help:      "Merchant API key lookups by cache result.",
+  }, []string{"result"})
+)
+
+func init() {
+  Registry.MustRegister(
+    collectors.NewGoCollector(),
+    collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+    HTTPRequests,
+    HTTPDuration,
+    OrdersCreated,
+    StatusTransitions,
+    AuthFailures,
+    RateLimited,
+    APIKeyCache,
+  )
+}
+
+// RegisterDB exports connection pool statistics for db, labelled with
+// name. The returned function unregisters them.
+func RegisterDB(db *sql.DB, name string) (func(), error) {
+  collector := collectors.NewDBStatsCollector(db, name)
+  if err := Registry.Register(collector); err != nil {
+    return nil, err
+  }
+  return func() { Registry.Unregister(collector) }, nil
+}
+
+// Handler serves the registry in the Prometheus text format.
+func Handler() http.Handler {
+  return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,422 @@
+package migrations
+
+import (
+  "context"
+  "crypto/sha256"
+  "database/sql"
+  "embed"
+  "encoding/hex"
+  "fmt"
+  "io/fs"
+  "os"
+  "path/filepath"
+  "regexp"
+  "sort"
+  "strconv"
+  "strings"
+  "time"
+
+  "sarah-project-backend/database"
+)
+
+// Each supported driver has its own directory of migrations under sql/.
+// Versions are kept in step across drivers.
+//
+//go:embed sql/*/*.sql
+var files embed.FS
+
+const (
+  lockName    = "sarah_project_schema_migrations"
+  lockTimeout = 60
+)
+
+var fileNamePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration is a single versioned schema change.
+type Migration struct {
+  Version  int64
+  Name     string
+  Up       string
+  Down     string
+  Checksum string
+}
+
+// Status describes whether a migration has been applied.
+type Status struct {
+  Version   int64      `json:"version"`
+  Name      string     `json:"name"`
+  Applied   bool       `json:"applied"`
+  AppliedAt *time.Time `json:"applied_at"`
+  // Modified is true when the embedded up SQL differs from what was applied.
+  Modified bool `json:"modified"`
+}
+
+// Load returns the embedded migrations for driver ordered by version.
+func Load(driver database.Driver) ([]Migration, error) {
+  return load(files, "sql/"+string(driver))
+}
+
+func load(fsys fs.FS, dir string) ([]Migration, error) {
+  entries, err := fs.ReadDir(fsys, dir)
+  if err != nil {
+    return nil, err
+  }
+
+  byVersion := make(map[int64]*Migration)
+  for _, entry := range entries {
+    match := fileNamePattern.FindStringSubmatch(entry.Name())
+    if match == nil {
+      return nil, fmt.Errorf("migration %s: file name must look like 0001_name.up.sql", entry.Name())
+    }
+    version, err := strconv.ParseInt(match[1], 10, 64)
+    if err != nil {
+      return nil, fmt.Errorf("migration %s: %w", entry.Name(), err)
+    }
+    body, err := fs.ReadFile(fsys, dir+"/"+entry.Name())
+    if err != nil {
+      return nil, err
+    }
+
+    m, ok := byVersion[version]
+    if !ok {
+      m = &Migration{Version: version, Name: match[2]}
+      byVersion[version] = m
+    }
+    if m.Name != match[2] {
+      return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, m.Name, match[2])
+    }
+    if match[3] == "up" {
+      m.Up = string(body)
+    } else {
+      m.Down = string(body)
+    }
+  }
+
+  result := make([]Migration, 0, len(byVersion))
+  for _, m := range byVersion {
+    if strings.TrimSpace(m.Up) == "" {
+      return nil, fmt.Errorf("migration %d_%s has no up SQL", m.Version, m.Name)
+    }
+    sum := sha256.Sum256([]byte(m.Up))
+    m.Checksum = hex.EncodeToString(sum[:])
+    result = append(result, *m)
+  }
+  sort.Slice(result, func(i, j int) bool { return result[i].Version < result[j].Version })
+  return result, nil
+}
+
+// Migrator applies migrations to a database.
+type Migrator struct {
+  db         *sql.DB
+  driver     database.Driver
+  migrations []Migration
+}
+
+// New returns a Migrator using the embedded migrations for driver.
+func New(db *sql.DB, driver database.Driver) (*Migrator, error) {
+  migrations, err := Load(driver)
+  if err != nil {
+    return nil, err
+  }
+  return &Migrator{db: db, driver: driver, migrations: migrations}, nil
+}
+
+// Up applies pending migrations in order. steps <= 0 applies all of them.
+// It returns the migrations that were applied.
+func (m *Migrator) Up(ctx context.Context, steps int) ([]Migration, error) {
+  var applied []Migration
+  err := m.withLock(ctx, func(conn *sql.Conn) error {
+    done, err := appliedVersions(ctx, conn)
+    if err != nil {
+      return err
+    }
+    if err := m.verifyChecksums(done); err != nil {
+      return err
+    }
+
+    for _, migration := range m.migrations {
+      if _, ok := done[migration.Version]; ok {
+        continue
+      }
+      if steps > 0 && len(applied) >= steps {
+        break
+      }
+      if err := execScript(ctx, conn, migration.Up); err != nil {
+        return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+      }
+      if _, err := conn.ExecContext(ctx, `
+        INSERT INTO schema_migrations (version, name, checksum)
+        VALUES (?, ?, ?)
+      `, migration.Version, migration.Name, migration.Checksum); err != nil {
+        return err
+      }
+      applied = append(applied, migration)
+    }
+    return nil
+  })
+  return applied, err
+}
+
+// Down rolls back the most recently applied migrations. steps <= 0 rolls back one.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+  if steps <= 0 {
+    steps = 1
+  }
+
+  var reverted []Migration
+  err := m.withLock(ctx, func(conn *sql.Conn) error {
+    done, err := appliedVersions(ctx, conn)
+    if err != nil {
+      return err
+    }
+
+    for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+      migration := m.migrations[i]
+      if _, ok := done[migration.Version]; !ok {
+        continue
+      }
+      if strings.TrimSpace(migration.Down) == "" {
+        return fmt.Errorf("migration %d_%s has no down SQL", migration.Version, migration.Name)
+      }
+      if err := execScript(ctx, conn, migration.Down); err != nil {
+        return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+      }
+      if _, err := conn.ExecContext(ctx, `
+        DELETE FROM schema_migrations WHERE version = ?
+      `, migration.Version); err != nil {
+        return err
+      }
+      reverted = append(reverted, migration)
+    }
+    return nil
+  })
+  return reverted, err
+}
+
+// Status lists every known migration and whether it has been applied.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+  conn, err := m.db.Conn(ctx)
+  if err != nil {
+    return nil, err
+  }
+  defer conn.Close()
+
+  if err := m.ensureTable(ctx, conn); err != nil {
+    return nil, err
+  }
+  done, err := appliedVersions(ctx, conn)
+  if err != nil {
+    return nil, err
+  }
+
+  result := make([]Status, 0, len(m.migrations))
+  for _, migration := range m.migrations {
+    status := Status{Version: migration.Version, Name: migration.Name}
+    if record, ok := done[migration.Version]; ok {
+      appliedAt := record.appliedAt
+      status.Applied = true
+      status.AppliedAt = &appliedAt
+      status.Modified = record.checksum != migration.Checksum
+    }
+    result = append(result, status)
+  }
+  return result, nil
+}
+
+// Pending reports how many embedded migrations have not been applied yet.
+func (m *Migrator) Pending(ctx context.Context) (int, error) {
+  statuses, err := m.Status(ctx)
+  if err != nil {
+    return 0, err
+  }
+  pending := 0
+  for _, status := range statuses {
+    if !status.Applied {
+      pending++
+    }
+  }
+  return pending, nil
+}
+
+func (m *Migrator) verifyChecksums(done map[int64]appliedRecord) error {
+  for _, migration := range m.migrations {
+    record, ok := done[migration.Version]
+    if ok && record.checksum != migration.Checksum {
+      return fmt.Errorf("migration %d_%s was modified after it was applied", migration.Version, migration.Name)
+    }
+  }
+  return nil
+}
+
+// withLock runs fn on a dedicated connection while holding an exclusive
+// lock so that replicas starting at the same time do not apply migrations
+// twice.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+  conn, err := m.db.Conn(ctx)
+  if err != nil {
+    return err
+  }
+  defer conn.Close()
+
+  if m.driver == database.SQLite {
+    return withSQLiteLock(ctx, conn, func() error {
+      if err := m.ensureTable(ctx, conn); err != nil {
+        return err
+      }
+      return fn(conn)
+    })
+  }
+
+  var acquired sql.NullInt64
+  if err := conn.QueryRowContext(ctx, `SELECT GET_LOCK(?, ?)`, lockName, lockTimeout).Scan(&acquired); err != nil {
+    return err
+  }
+  if !acquired.Valid || acquired.Int64 != 1 {
+    return fmt.Errorf("timed out waiting for migration lock")
+  }
+  defer func() {
+    _, _ = conn.ExecContext(context.Background(), `SELECT RELEASE_LOCK(?)`, lockName)
+  }()
+
+  if err := m.ensureTable(ctx, conn); err != nil {
+    return err
+  }
+  return fn(conn)
+}
+
+// withSQLiteLock runs fn inside a BEGIN IMMEDIATE transaction, which takes
+// the database write lock. SQLite has transactional DDL, so a failed run
+// leaves the schema untouched.
+func withSQLiteLock(ctx context.Context, conn *sql.Conn, fn func() error) error {
+  if _, err := conn.ExecContext(ctx, `BEGIN IMMEDIATE`); err != nil {
+    return err
+  }
+  if err := fn(); err != nil {
+    _, _ = conn.ExecContext(context.Background(), `ROLLBACK`)
+    return err
+  }
+  _, err := conn.ExecContext(ctx, `COMMIT`)
+  return err
+}
+
+func (m *Migrator) ensureTable(ctx context.Context, conn *sql.Conn) error {
+  options := " ENGINE=InnoDB DEFAULT CHARSET=utf8mb4"
+  if m.driver == database.SQLite {
+    options = ""
+  }
+  _, err := conn.ExecContext(ctx, `
+    CREATE TABLE IF NOT EXISTS schema_migrations (
+      version BIGINT NOT NULL PRIMARY KEY,
+      name VARCHAR(255) NOT NULL,
+      checksum CHAR(64) NOT NULL,
+      applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+    )`+options)
+  return err
+}
+
+type appliedRecord struct {
+  checksum  string
+  appliedAt time.Time
+}
+
+func appliedVersions(ctx context.Context, conn *sql.Conn) (map[int64]appliedRecord, error) {
+  rows, err := conn.QueryContext(ctx, `
+    SELECT version, checksum, applied_at
+    FROM schema_migrations
+  `)
+  if err != nil {
+    return nil, err
+  }
+  defer rows.Close()
+
+  done := make(map[int64]appliedRecord)
+  for rows.Next() {
+    var (
+      version int64
+      record  appliedRecord
+    )
+    if err := rows.Scan(&version, &record.checksum, &record.appliedAt); err != nil {
+      return nil, err
+    }
+    done[version] = record
+  }
+  return done, rows.Err()
+}
+
+// execScript runs each statement of a migration file in order.
+func execScript(ctx context.Context, conn *sql.Conn, script string) error {
+  for _, stmt := range splitStatements(script) {
+    if _, err := conn.ExecContext(ctx, stmt); err != nil {
+      return err
+    }
+  }
+  return nil
+}
+
+// splitStatements splits a script on semicolons that end a line. Lines
+// starting with "--" are treated as comments.
+func splitStatements(script string) []string {
+  var (
+    statements []string
+    current    strings.Builder
+  )
+  for _, line := range strings.Split(script, "\n") {
+    trimmed := strings.TrimSpace(line)
+    if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+      continue
+    }
+    current.WriteString(line)
+    current.WriteString("\n")
+    if strings.HasSuffix(trimmed, ";") {
+      statements = append(statements, strings.TrimSuffix(strings.TrimSpace(current.String()), ";"))
+      current.Reset()
+    }
+  }
+  if rest := strings.TrimSpace(current.String()); rest != "" {
+    statements = append(statements, rest)
+  }
+  return statements
+}
+
+// Create writes an empty up/down pair for a new migration into the driver
+// directories under dir and returns the created file paths.
+func Create(dir string, name string) ([]string, error) {
+  name = strings.ToLower(strings.TrimSpace(name))
+  name = regexp.MustCompile(`[^a-z0-9]+`).ReplaceAllString(name, "_")
+  name = strings.Trim(name, "_")
+  if name == "" {
+    return nil, fmt.Errorf("migration name is required")
+  }
+
+  drivers := []database.Driver{database.MySQL, database.SQLite}
+  var next int64 = 1
+  for _, driver := range drivers {
+    entries, err := os.ReadDir(filepath.Join(dir, string(driver)))
+    if err != nil {
+      return nil, err
+    }
+    for _, entry := range entries {
+      if match := fileNamePattern.FindStringSubmatch(entry.Name()); match != nil {
+        version, _ := strconv.ParseInt(match[1], 10, 64)
+        if version >= next {
+          next = version + 1
+        }
+      }
+    }
+  }
+
+  base := fmt.Sprintf("%04d_%s", next, name)
+  var paths []string
+  for _, driver := range drivers {
+    paths = append(paths,
+      filepath.Join(dir, string(driver), base+".up.sql"),
+      filepath.Join(dir, string(driver), base+".down.sql"),
+    )
+  }
+  for _, path := range paths {
+    if err := os.WriteFile(path, []byte("-- "+filepath.Base(path)+"\n"), 0o644); err != nil {
+      return nil, err
+    }
+  }
+  return paths, nil
+}
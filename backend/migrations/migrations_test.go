@@ -0,0 +1,135 @@
+package migrations
+
+import (
+  "context"
+  "database/sql"
+  "strings"
+  "testing"
+  "testing/fstest"
+
+  "sarah-project-backend/database"
+)
+
+func openSQLite(t *testing.T) *sql.DB {
+  t.Helper()
+  db, err := database.OpenSQLite(":memory:")
+  if err != nil {
+    t.Fatal(err)
+  }
+  t.Cleanup(func() { db.Close() })
+  return db
+}
+
+func tables(t *testing.T, db *sql.DB) []string {
+  t.Helper()
+  rows, err := db.Query(`SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name`)
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer rows.Close()
+  var names []string
+  for rows.Next() {
+    var name string
+    if err := rows.Scan(&name); err != nil {
+      t.Fatal(err)
+    }
+    names = append(names, name)
+  }
+  return names
+}
+
+func TestMigrator(t *testing.T) {
+  ctx := context.Background()
+  db := openSQLite(t)
+  m, err := New(db, database.SQLite)
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  applied, err := m.Up(ctx, 0)
+  if err != nil {
+    t.Fatal(err)
+  }
+  if len(applied) != len(m.migrations) {
+    t.Fatalf("applied %d migrations, want %d", len(applied), len(m.migrations))
+  }
+  statuses, err := m.Status(ctx)
+  if err != nil {
+    t.Fatal(err)
+  }
+  for _, status := range statuses {
+    if !status.Applied || status.AppliedAt == nil || status.Modified {
+      t.Errorf("status after up = %+v", status)
+    }
+  }
+
+  // A second run finds nothing to do.
+  applied, err = m.Up(ctx, 0)
+  if err != nil || len(applied) != 0 {
+    t.Fatalf("second up applied %d migrations, err = %v", len(applied), err)
+  }
+  if pending, err := m.Pending(ctx); err != nil || pending != 0 {
+    t.Fatalf("Pending = %d, %v", pending, err)
+  }
+
+  reverted, err := m.Down(ctx, len(m.migrations))
+  if err != nil {
+    t.Fatal(err)
+  }
+  if len(reverted) != len(m.migrations) || reverted[0].Version != m.migrations[len(m.migrations)-1].Version {
+    t.Fatalf("reverted %+v, want every migration newest first", reverted)
+  }
+  statuses, err = m.Status(ctx)
+  if err != nil {
+    t.Fatal(err)
+  }
+  for _, status := range statuses {
+    if status.Applied {
+      t.Errorf("status after down = %+v", status)
+    }
+  }
+  if got := strings.Join(tables(t, db), ","); got != "schema_migrations" {
+    t.Errorf("tables after down = %s", got)
+  }
+}
+
+func TestMigratorModified(t *testing.T) {
+  ctx := context.Background()
+  db := openSQLite(t)
+  fsys := fstest.MapFS{
+    "sql/0001_widgets.up.sql":   {Data: []byte("CREATE TABLE widgets (id INTEGER PRIMARY KEY);\n")},
+    "sql/0001_widgets.down.sql": {Data: []byte("DROP TABLE widgets;\n")},
+  }
+  migrator := func() *Migrator {
+    t.Helper()
+    migrations, err := load(fsys, "sql")
+    if err != nil {
+      t.Fatal(err)
+    }
+    return &Migrator{db: db, driver: database.SQLite, migrations: migrations}
+  }
+
+  if _, err := migrator().Up(ctx, 0); err != nil {
+    t.Fatal(err)
+  }
+
+  // Editing an applied migration and adding a new one must not apply the
+  // new one.
+  fsys["sql/0001_widgets.up.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE widgets (id INTEGER PRIMARY KEY, name TEXT);\n")}
+  fsys["sql/0002_gadgets.up.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE gadgets (id INTEGER PRIMARY KEY);\n")}
+  m := migrator()
+
+  statuses, err := m.Status(ctx)
+  if err != nil {
+    t.Fatal(err)
+  }
+  if len(statuses) != 2 || !statuses[0].Modified || statuses[1].Applied {
+    t.Fatalf("statuses = %+v", statuses)
+  }
+  if _, err := m.Up(ctx, 0); err == nil || !strings.Contains(err.Error(), "1_widgets was modified") {
+    t.Fatalf("up after edit: err = %v", err)
+  }
+  if got := strings.Join(tables(t, db), ","); got != "schema_migrations,widgets" {
+    t.Errorf("tables = %s", got)
+  }
+}
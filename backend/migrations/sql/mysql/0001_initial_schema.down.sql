@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS payout_countries;
+DROP TABLE IF EXISTS catalogue_assets;
+DROP TABLE IF EXISTS payout_batch_orders;
+DROP TABLE IF EXISTS payout_batches;
+DROP TABLE IF EXISTS orders;
+DROP TABLE IF EXISTS customer_api_keys;
+DROP TABLE IF EXISTS customer_users;
+DROP TABLE IF EXISTS admin_users;
@@ -0,0 +1,114 @@
+-- Baseline schema. Statements are idempotent so databases created by the
+-- former startup table bootstrap can adopt this migration in place.
+
+CREATE TABLE IF NOT EXISTS admin_users (
+  id BIGINT AUTO_INCREMENT PRIMARY KEY,
+  username VARCHAR(64) NOT NULL UNIQUE,
+  email VARCHAR(128) NOT NULL,
+  password_hash VARCHAR(255) NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS customer_users (
+  id BIGINT AUTO_INCREMENT PRIMARY KEY,
+  name VARCHAR(128) NOT NULL,
+  email VARCHAR(128) NOT NULL UNIQUE,
+  password_hash VARCHAR(255) NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS customer_api_keys (
+  id BIGINT AUTO_INCREMENT PRIMARY KEY,
+  merchant_name VARCHAR(128) NOT NULL,
+  api_key VARCHAR(128) NOT NULL,
+  active TINYINT(1) NOT NULL DEFAULT 1,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  UNIQUE KEY uniq_api_key (api_key),
+  UNIQUE KEY uniq_merchant_name (merchant_name)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS orders (
+  id BIGINT AUTO_INCREMENT PRIMARY KEY,
+  merchant_name VARCHAR(128) NOT NULL,
+  transaction_network VARCHAR(32) NOT NULL,
+  transaction_asset VARCHAR(32) NOT NULL,
+  txid VARCHAR(128) NOT NULL,
+  amount DECIMAL(18, 8) NULL,
+  email VARCHAR(128) NOT NULL,
+  beneficiary_name VARCHAR(128) NOT NULL,
+  bank_country VARCHAR(64) NOT NULL,
+  bank_name VARCHAR(128) NOT NULL,
+  iban VARCHAR(64) NOT NULL,
+  swift VARCHAR(64) NOT NULL,
+  reference_note TEXT NULL,
+  status ENUM('Paid', 'Processing', 'Summitted', 'Failed', 'Funds Received') NOT NULL DEFAULT 'Processing',
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS payout_batches (
+  id BIGINT AUTO_INCREMENT PRIMARY KEY,
+  batch_id VARCHAR(64) NOT NULL,
+  format VARCHAR(16) NOT NULL,
+  file_name VARCHAR(128) NOT NULL DEFAULT '',
+  order_count INT NOT NULL DEFAULT 0,
+  total_amount DECIMAL(18, 2) NOT NULL DEFAULT 0,
+  content MEDIUMTEXT NULL,
+  created_by BIGINT NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  UNIQUE KEY uniq_batch_id (batch_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS payout_batch_orders (
+  batch_id VARCHAR(64) NOT NULL,
+  order_id BIGINT NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (batch_id, order_id),
+  KEY idx_order_id (order_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+ALTER TABLE orders
+  MODIFY transaction_network VARCHAR(32) NOT NULL,
+  MODIFY transaction_asset VARCHAR(32) NOT NULL,
+  MODIFY bank_country VARCHAR(64) NOT NULL;
+
+CREATE TABLE IF NOT EXISTS catalogue_assets (
+  id BIGINT AUTO_INCREMENT PRIMARY KEY,
+  network VARCHAR(32) NOT NULL,
+  asset VARCHAR(32) NOT NULL,
+  contract_address VARCHAR(128) NOT NULL DEFAULT '',
+  decimals INT NOT NULL,
+  min_amount DECIMAL(18, 8) NULL,
+  max_amount DECIMAL(18, 8) NULL,
+  enabled TINYINT(1) NOT NULL DEFAULT 1,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  UNIQUE KEY uniq_network_asset (network, asset)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS payout_countries (
+  id BIGINT AUTO_INCREMENT PRIMARY KEY,
+  name VARCHAR(64) NOT NULL,
+  iso_code CHAR(2) NOT NULL,
+  currency CHAR(3) NOT NULL,
+  enabled TINYINT(1) NOT NULL DEFAULT 1,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  UNIQUE KEY uniq_name (name)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+INSERT IGNORE INTO catalogue_assets (network, asset, contract_address, decimals) VALUES
+  ('TRON', 'USDT', 'TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t', 6),
+  ('TRON', 'USDC', 'TEkxiTehnzSmSe2XqrBj4w32RUN966rdz8', 6),
+  ('BSC', 'USDT', '0x55d398326f99059fF775485246999027B3197955', 18),
+  ('BSC', 'USDC', '0x8AC76a51cc950d9822D68b83fE1Ad97B32Cd580d', 18),
+  ('Ethereum', 'USDT', '0xdAC17F958D2ee523a2206206994597C13D831ec7', 6),
+  ('Ethereum', 'USDC', '0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48', 6);
+
+INSERT IGNORE INTO payout_countries (name, iso_code, currency) VALUES
+  ('Canada', 'CA', 'CAD'),
+  ('United States', 'US', 'USD');
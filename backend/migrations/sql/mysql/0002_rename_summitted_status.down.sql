@@ -0,0 +1,7 @@
+ALTER TABLE orders
+  MODIFY status ENUM('Paid', 'Processing', 'Summitted', 'Submitted', 'Failed', 'Funds Received') NOT NULL DEFAULT 'Processing';
+
+UPDATE orders SET status = 'Summitted' WHERE status = 'Submitted';
+
+ALTER TABLE orders
+  MODIFY status ENUM('Paid', 'Processing', 'Summitted', 'Failed', 'Funds Received') NOT NULL DEFAULT 'Processing';
@@ -0,0 +1,10 @@
+-- Fix the misspelled 'Summitted' order status. The ENUM is widened first so
+-- existing rows can be rewritten before the old value is dropped.
+
+ALTER TABLE orders
+  MODIFY status ENUM('Paid', 'Processing', 'Summitted', 'Submitted', 'Failed', 'Funds Received') NOT NULL DEFAULT 'Processing';
+
+UPDATE orders SET status = 'Submitted' WHERE status = 'Summitted';
+
+ALTER TABLE orders
+  MODIFY status ENUM('Paid', 'Processing', 'Submitted', 'Failed', 'Funds Received') NOT NULL DEFAULT 'Processing';
@@ -0,0 +1,18 @@
+-- Per-merchant request quotas. Merchants without a row use the configured
+-- default.
+CREATE TABLE IF NOT EXISTS merchant_rate_limits (
+  merchant_name VARCHAR(128) PRIMARY KEY,
+  requests_per_minute INT NOT NULL,
+  burst INT NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Token buckets shared by every instance when RATE_LIMIT_STORE=database.
+-- updated_at_ms is a Unix time in milliseconds.
+CREATE TABLE IF NOT EXISTS rate_limit_buckets (
+  bucket_key VARCHAR(191) PRIMARY KEY,
+  tokens DOUBLE NOT NULL,
+  updated_at_ms BIGINT NOT NULL,
+  KEY idx_rate_limit_buckets_updated (updated_at_ms)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,6 @@
+-- Idempotency-Key sent with POST /customer/orders. A retried request with
+-- the same key returns the order created by the first attempt. NULLs do not
+-- collide, so orders created without a key are unaffected.
+ALTER TABLE orders
+  ADD COLUMN idempotency_key VARCHAR(128) NULL,
+  ADD UNIQUE KEY uq_orders_idempotency_key (merchant_name, idempotency_key);
@@ -0,0 +1,108 @@
+-- Baseline schema for SQLite. It matches the MySQL schema after 0002:
+-- ENUM columns become CHECK constraints and updated_at is maintained by the
+-- queries because SQLite has no ON UPDATE CURRENT_TIMESTAMP.
+
+CREATE TABLE IF NOT EXISTS admin_users (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  username VARCHAR(64) NOT NULL UNIQUE,
+  email VARCHAR(128) NOT NULL,
+  password_hash VARCHAR(255) NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS customer_users (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  name VARCHAR(128) NOT NULL,
+  email VARCHAR(128) NOT NULL UNIQUE,
+  password_hash VARCHAR(255) NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS customer_api_keys (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  merchant_name VARCHAR(128) NOT NULL UNIQUE,
+  api_key VARCHAR(128) NOT NULL UNIQUE,
+  active INTEGER NOT NULL DEFAULT 1,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS orders (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  merchant_name VARCHAR(128) NOT NULL,
+  transaction_network VARCHAR(32) NOT NULL,
+  transaction_asset VARCHAR(32) NOT NULL,
+  txid VARCHAR(128) NOT NULL,
+  amount DECIMAL(18, 8) NULL,
+  email VARCHAR(128) NOT NULL,
+  beneficiary_name VARCHAR(128) NOT NULL,
+  bank_country VARCHAR(64) NOT NULL,
+  bank_name VARCHAR(128) NOT NULL,
+  iban VARCHAR(64) NOT NULL,
+  swift VARCHAR(64) NOT NULL,
+  reference_note TEXT NULL,
+  status VARCHAR(32) NOT NULL DEFAULT 'Processing'
+    CHECK (status IN ('Paid', 'Processing', 'Submitted', 'Failed', 'Funds Received')),
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS payout_batches (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  batch_id VARCHAR(64) NOT NULL UNIQUE,
+  format VARCHAR(16) NOT NULL,
+  file_name VARCHAR(128) NOT NULL DEFAULT '',
+  order_count INTEGER NOT NULL DEFAULT 0,
+  total_amount DECIMAL(18, 2) NOT NULL DEFAULT 0,
+  content TEXT NULL,
+  created_by INTEGER NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS payout_batch_orders (
+  batch_id VARCHAR(64) NOT NULL,
+  order_id INTEGER NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (batch_id, order_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_payout_batch_orders_order_id ON payout_batch_orders (order_id);
+
+CREATE TABLE IF NOT EXISTS catalogue_assets (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  network VARCHAR(32) NOT NULL,
+  asset VARCHAR(32) NOT NULL,
+  contract_address VARCHAR(128) NOT NULL DEFAULT '',
+  decimals INTEGER NOT NULL,
+  min_amount DECIMAL(18, 8) NULL,
+  max_amount DECIMAL(18, 8) NULL,
+  enabled INTEGER NOT NULL DEFAULT 1,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (network, asset)
+);
+
+CREATE TABLE IF NOT EXISTS payout_countries (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  name VARCHAR(64) NOT NULL UNIQUE,
+  iso_code CHAR(2) NOT NULL,
+  currency CHAR(3) NOT NULL,
+  enabled INTEGER NOT NULL DEFAULT 1,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT OR IGNORE INTO catalogue_assets (network, asset, contract_address, decimals) VALUES
+  ('TRON', 'USDT', 'TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t', 6),
+  ('TRON', 'USDC', 'TEkxiTehnzSmSe2XqrBj4w32RUN966rdz8', 6),
+  ('BSC', 'USDT', '0x55d398326f99059fF775485246999027B3197955', 18),
+  ('BSC', 'USDC', '0x8AC76a51cc950d9822D68b83fE1Ad97B32Cd580d', 18),
+  ('Ethereum', 'USDT', '0xdAC17F958D2ee523a2206206994597C13D831ec7', 6),
+  ('Ethereum', 'USDC', '0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48', 6);
+
+INSERT OR IGNORE INTO payout_countries (name, iso_code, currency) VALUES
+  ('Canada', 'CA', 'CAD'),
+  ('United States', 'US', 'USD');
@@ -0,0 +1,2 @@
+-- No-op: the SQLite baseline already uses 'Submitted'. Kept so migration
+-- versions line up with the MySQL history.
@@ -0,0 +1,19 @@
+-- Per-merchant request quotas. Merchants without a row use the configured
+-- default.
+CREATE TABLE IF NOT EXISTS merchant_rate_limits (
+  merchant_name VARCHAR(128) PRIMARY KEY,
+  requests_per_minute INTEGER NOT NULL,
+  burst INTEGER NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Token buckets shared by every instance when RATE_LIMIT_STORE=database.
+-- updated_at_ms is a Unix time in milliseconds.
+CREATE TABLE IF NOT EXISTS rate_limit_buckets (
+  bucket_key VARCHAR(191) PRIMARY KEY,
+  tokens REAL NOT NULL,
+  updated_at_ms INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_rate_limit_buckets_updated ON rate_limit_buckets (updated_at_ms);
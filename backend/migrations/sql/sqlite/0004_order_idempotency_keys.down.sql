@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS uq_orders_idempotency_key;
+
+ALTER TABLE orders DROP COLUMN idempotency_key;
@@ -0,0 +1,52 @@
+// Package openapi serves the OpenAPI description of the API and a Swagger UI
+// for browsing it. openapi.json is maintained by hand; tests in the handler
+// and main packages fail when it drifts from the routes or handler types.
+package openapi
+
+import (
+  "embed"
+  "io/fs"
+  "net/http"
+
+  swaggerfiles "github.com/swaggo/files/v2"
+)
+
+// Spec is the OpenAPI 3.1 document.
+//
+//go:embed openapi.json
+var Spec []byte
+
+//go:embed ui
+var ui embed.FS
+
+// uiContentSecurityPolicy replaces the API's default policy on the docs
+// pages, which load their own scripts and styles and call /openapi.json.
+// Swagger UI sets inline styles, so style-src needs 'unsafe-inline'.
+const uiContentSecurityPolicy = "default-src 'none'; script-src 'self'; style-src 'self' 'unsafe-inline'; img-src 'self' data:; connect-src 'self'; frame-ancestors 'none'"
+
+// Handler serves Spec.
+func Handler() http.HandlerFunc {
+  return func(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "application/json")
+    w.Header().Set("Cache-Control", "public, max-age=300")
+    _, _ = w.Write(Spec)
+  }
+}
+
+// UI serves Swagger UI under prefix, which must end in a slash. The page
+// and its init script come from this package, the rest from the bundled
+// Swagger UI distribution.
+func UI(prefix string) http.Handler {
+  own, _ := fs.Sub(ui, "ui")
+  pages := http.FileServerFS(own)
+  assets := http.FileServerFS(swaggerfiles.FS)
+  return http.StripPrefix(prefix, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Security-Policy", uiContentSecurityPolicy)
+    switch r.URL.Path {
+    case "", "index.html", "init.js":
+      pages.ServeHTTP(w, r)
+    default:
+      assets.ServeHTTP(w, r)
+    }
+  }))
+}
@@ -0,0 +1,124 @@
+package payout
+
+import (
+  "fmt"
+  "strings"
+  "time"
+
+  "sarah-project-backend/validation"
+)
+
+const (
+  cpa005RecordSize  = 1464
+  cpa005SegmentSize = 240
+  cpa005Segments    = 6
+  // cpa005TransactionType is the Payments Canada code for miscellaneous payments.
+  cpa005TransactionType = "450"
+)
+
+// generateCPA005 renders a credit file for Canadian beneficiaries.
+func generateCPA005(cfg Config, batch Batch) (File, error) {
+  originatorID := padRight(asciiUpper(cfg.CPA005OriginatorID), 10)
+  fileNumber := numeric(batch.Sequence%10000, 4)
+  created := cpa005Date(batch.CreatedAt)
+  returnInstitution, ok := validation.CanadianRouting(cfg.CPA005ReturnInstitution)
+  if !ok {
+    return File{}, fmt.Errorf("PAYOUT_CPA005_RETURN_INSTITUTION must be institution and transit digits")
+  }
+
+  segments := make([]string, 0, len(batch.Payments))
+  var credit int64
+  for _, p := range batch.Payments {
+    if p.CountryCode != "CA" {
+      return File{}, PaymentError{OrderID: p.OrderID, Reason: "CPA-005 files only support Canadian beneficiaries"}
+    }
+    institution, ok := validation.CanadianRouting(p.Routing)
+    if !ok {
+      return File{}, PaymentError{OrderID: p.OrderID, Reason: "swift must contain institution and transit numbers"}
+    }
+    account := digitsOnly(p.Account)
+    if len(account) < 7 || len(account) > 12 {
+      return File{}, PaymentError{OrderID: p.OrderID, Reason: "account number must be 7-12 digits"}
+    }
+
+    cents := toCents(p.Amount)
+    credit += cents
+    segments = append(segments, strings.Join([]string{
+      cpa005TransactionType,
+      numeric(cents, 10),
+      created,
+      institution,
+      padRight(account, 12),
+      numeric(0, 22),
+      numeric(0, 3),
+      padRight(asciiUpper(cfg.OriginatorName), 15),
+      padRight(asciiUpper(p.BeneficiaryName), 30),
+      padRight(asciiUpper(cfg.OriginatorName), 30),
+      originatorID,
+      padRight(fmt.Sprintf("%s-%d", batch.ID, p.OrderID), 19),
+      returnInstitution,
+      padRight(digitsOnly(cfg.CPA005ReturnAccount), 12),
+      padRight(fmt.Sprintf("ORDER %d", p.OrderID), 15),
+      padRight("", 22),
+      "  ",
+      numeric(0, 11),
+    }, ""))
+  }
+
+  prefix := func(recordType string, count int) string {
+    return recordType + numeric(int64(count), 9) + originatorID + fileNumber
+  }
+
+  records := make([]string, 0, len(segments)/cpa005Segments+3)
+  records = append(records, padRight(strings.Join([]string{
+    prefix("A", 1),
+    created,
+    padRight(digitsOnly(cfg.CPA005DataCentre), 5),
+    padRight("", 20),
+    "CAD",
+  }, ""), cpa005RecordSize))
+
+  for start := 0; start < len(segments); start += cpa005Segments {
+    end := start + cpa005Segments
+    if end > len(segments) {
+      end = len(segments)
+    }
+    record := prefix("C", len(records)+1) + strings.Join(segments[start:end], "")
+    records = append(records, padRight(record, cpa005RecordSize))
+  }
+
+  records = append(records, padRight(strings.Join([]string{
+    prefix("Z", len(records)+1),
+    numeric(0, 14),
+    numeric(0, 8),
+    numeric(credit, 14),
+    numeric(int64(len(segments)), 8),
+    numeric(0, 14),
+    numeric(0, 8),
+    numeric(0, 14),
+    numeric(0, 8),
+  }, ""), cpa005RecordSize))
+
+  for _, record := range records {
+    if len(record) != cpa005RecordSize {
+      return File{}, fmt.Errorf("cpa005 record has length %d", len(record))
+    }
+  }
+  for _, segment := range segments {
+    if len(segment) != cpa005SegmentSize {
+      return File{}, fmt.Errorf("cpa005 segment has length %d", len(segment))
+    }
+  }
+
+  return File{
+    Name:        fmt.Sprintf("%s.cpa", batch.ID),
+    ContentType: "text/plain",
+    Content:     []byte(strings.Join(records, "\n") + "\n"),
+    Total:       float64(credit) / 100,
+  }, nil
+}
+
+// cpa005Date formats a date as 0YYDDD (Julian day of year).
+func cpa005Date(t time.Time) string {
+  return fmt.Sprintf("0%s%03d", t.Format("06"), t.YearDay())
+}
@@ -0,0 +1,141 @@
+package payout
+
+import (
+  "fmt"
+  "strconv"
+  "strings"
+
+  "sarah-project-backend/validation"
+)
+
+const nachaRecordSize = 94
+
+// generateNACHA renders a PPD credit batch for United States beneficiaries.
+func generateNACHA(cfg Config, batch Batch) (File, error) {
+  odfi := padLeftZero(digitsOnly(cfg.NACHAODFIRouting), 9)[:8]
+  companyID := padRight(asciiUpper(cfg.NACHACompanyID), 10)
+  created := batch.CreatedAt
+
+  records := make([]string, 0, len(batch.Payments)+4)
+  records = append(records, strings.Join([]string{
+    "1",
+    "01",
+    padLeftSpace(digitsOnly(cfg.NACHAImmediateDestination), 10),
+    padLeftSpace(digitsOnly(cfg.NACHAImmediateOrigin), 10),
+    created.Format("060102"),
+    created.Format("1504"),
+    string(rune('A' + batch.Sequence%26)),
+    "094",
+    "10",
+    "1",
+    padRight(asciiUpper(cfg.NACHADestinationName), 23),
+    padRight(asciiUpper(cfg.OriginatorName), 23),
+    padRight(asciiUpper(batch.ID), 8),
+  }, ""))
+
+  records = append(records, strings.Join([]string{
+    "5",
+    "220",
+    padRight(asciiUpper(cfg.OriginatorName), 16),
+    padRight("", 20),
+    companyID,
+    "PPD",
+    padRight("PAYOUT", 10),
+    created.Format("060102"),
+    created.Format("060102"),
+    "   ",
+    "1",
+    odfi,
+    numeric(1, 7),
+  }, ""))
+
+  var (
+    entryHash int64
+    credit    int64
+  )
+  for i, p := range batch.Payments {
+    if p.CountryCode != "US" {
+      return File{}, PaymentError{OrderID: p.OrderID, Reason: "NACHA files only support United States beneficiaries"}
+    }
+    routing := digitsOnly(p.Routing)
+    if !validation.IsABARouting(routing) {
+      return File{}, PaymentError{OrderID: p.OrderID, Reason: "swift is not a valid ABA routing number"}
+    }
+    account := strings.ReplaceAll(strings.TrimSpace(p.Account), " ", "")
+    if account == "" || len(account) > 17 {
+      return File{}, PaymentError{OrderID: p.OrderID, Reason: "account number must be 1-17 characters"}
+    }
+
+    cents := toCents(p.Amount)
+    credit += cents
+    rdfi, _ := strconv.ParseInt(routing[:8], 10, 64)
+    entryHash += rdfi
+
+    records = append(records, strings.Join([]string{
+      "6",
+      "22",
+      routing[:8],
+      routing[8:],
+      padRight(asciiUpper(account), 17),
+      numeric(cents, 10),
+      padRight(fmt.Sprintf("%d", p.OrderID), 15),
+      padRight(asciiUpper(p.BeneficiaryName), 22),
+      "  ",
+      "0",
+      odfi,
+      numeric(int64(i+1), 7),
+    }, ""))
+  }
+
+  entryCount := int64(len(batch.Payments))
+  hash := numeric(entryHash%10000000000, 10)
+  records = append(records, strings.Join([]string{
+    "8",
+    "220",
+    numeric(entryCount, 6),
+    hash,
+    numeric(0, 12),
+    numeric(credit, 12),
+    companyID,
+    padRight("", 19),
+    padRight("", 6),
+    odfi,
+    numeric(1, 7),
+  }, ""))
+
+  blocks := (int64(len(records)) + 1 + 9) / 10
+  records = append(records, strings.Join([]string{
+    "9",
+    numeric(1, 6),
+    numeric(blocks, 6),
+    numeric(entryCount, 8),
+    hash,
+    numeric(0, 12),
+    numeric(credit, 12),
+    padRight("", 39),
+  }, ""))
+
+  for len(records)%10 != 0 {
+    records = append(records, strings.Repeat("9", nachaRecordSize))
+  }
+
+  for _, record := range records {
+    if len(record) != nachaRecordSize {
+      return File{}, fmt.Errorf("nacha record has length %d", len(record))
+    }
+  }
+
+  return File{
+    Name:        fmt.Sprintf("%s.ach", batch.ID),
+    ContentType: "text/plain",
+    Content:     []byte(strings.Join(records, "\n") + "\n"),
+    Total:       float64(credit) / 100,
+  }, nil
+}
+
+func padLeftSpace(value string, width int) string {
+  if len(value) >= width {
+    return value[len(value)-width:]
+  }
+  return strings.Repeat(" ", width-len(value)) + value
+}
@@ -0,0 +1,217 @@
+package payout
+
+import (
+  "encoding/xml"
+  "fmt"
+  "sort"
+  "strings"
+
+  "sarah-project-backend/validation"
+)
+
+const pain001Namespace = "urn:iso:std:iso:20022:tech:xsd:pain.001.001.03"
+
+type pain001Document struct {
+  XMLName xml.Name          `xml:"Document"`
+  Xmlns   string            `xml:"xmlns,attr"`
+  Init    pain001Initiation `xml:"CstmrCdtTrfInitn"`
+}
+
+type pain001Initiation struct {
+  GroupHeader pain001GroupHeader   `xml:"GrpHdr"`
+  PaymentInfo []pain001PaymentInfo `xml:"PmtInf"`
+}
+
+type pain001GroupHeader struct {
+  MessageID       string       `xml:"MsgId"`
+  CreationTime    string       `xml:"CreDtTm"`
+  NumberOfTxs     int          `xml:"NbOfTxs"`
+  ControlSum      string       `xml:"CtrlSum"`
+  InitiatingParty pain001Party `xml:"InitgPty"`
+}
+
+type pain001Party struct {
+  Name    string          `xml:"Nm"`
+  Address *pain001Address `xml:"PstlAdr,omitempty"`
+}
+
+type pain001Address struct {
+  Country string `xml:"Ctry"`
+}
+
+type pain001PaymentInfo struct {
+  ID            string               `xml:"PmtInfId"`
+  Method        string               `xml:"PmtMtd"`
+  NumberOfTxs   int                  `xml:"NbOfTxs"`
+  ControlSum    string               `xml:"CtrlSum"`
+  ExecutionDate string               `xml:"ReqdExctnDt"`
+  Debtor        pain001Party         `xml:"Dbtr"`
+  DebtorAccount pain001Account       `xml:"DbtrAcct"`
+  DebtorAgent   pain001Agent         `xml:"DbtrAgt"`
+  ChargeBearer  string               `xml:"ChrgBr"`
+  Transactions  []pain001Transaction `xml:"CdtTrfTxInf"`
+}
+
+type pain001Account struct {
+  ID       pain001AccountID `xml:"Id"`
+  Currency string           `xml:"Ccy,omitempty"`
+}
+
+type pain001AccountID struct {
+  IBAN  string          `xml:"IBAN,omitempty"`
+  Other *pain001OtherID `xml:"Othr,omitempty"`
+}
+
+type pain001OtherID struct {
+  ID string `xml:"Id"`
+}
+
+type pain001Agent struct {
+  Institution pain001Institution `xml:"FinInstnId"`
+}
+
+type pain001Institution struct {
+  BIC  string `xml:"BIC,omitempty"`
+  Name string `xml:"Nm,omitempty"`
+}
+
+type pain001Transaction struct {
+  PaymentID       pain001PaymentID   `xml:"PmtId"`
+  Amount          pain001Amount      `xml:"Amt"`
+  CreditorAgent   pain001Agent       `xml:"CdtrAgt"`
+  Creditor        pain001Party       `xml:"Cdtr"`
+  CreditorAccount pain001Account     `xml:"CdtrAcct"`
+  Remittance      *pain001Remittance `xml:"RmtInf,omitempty"`
+}
+
+type pain001PaymentID struct {
+  InstructionID string `xml:"InstrId"`
+  EndToEndID    string `xml:"EndToEndId"`
+}
+
+type pain001Amount struct {
+  Instructed pain001InstructedAmount `xml:"InstdAmt"`
+}
+
+type pain001InstructedAmount struct {
+  Currency string `xml:"Ccy,attr"`
+  Value    string `xml:",chardata"`
+}
+
+type pain001Remittance struct {
+  Unstructured string `xml:"Ustrd"`
+}
+
+func generatePain001(cfg Config, batch Batch) (File, error) {
+  byCurrency := make(map[string][]Payment)
+  for _, p := range batch.Payments {
+    if !validation.IsBIC(p.Routing) {
+      return File{}, PaymentError{OrderID: p.OrderID, Reason: "swift is not a valid BIC"}
+    }
+    currency := p.Currency
+    byCurrency[currency] = append(byCurrency[currency], p)
+  }
+
+  currencies := make([]string, 0, len(byCurrency))
+  for currency := range byCurrency {
+    currencies = append(currencies, currency)
+  }
+  sort.Strings(currencies)
+
+  var total int64
+  infos := make([]pain001PaymentInfo, 0, len(currencies))
+  for i, currency := range currencies {
+    payments := byCurrency[currency]
+    var subtotal int64
+    txs := make([]pain001Transaction, 0, len(payments))
+    for _, p := range payments {
+      cents := toCents(p.Amount)
+      subtotal += cents
+      tx := pain001Transaction{
+        PaymentID: pain001PaymentID{
+          InstructionID: fmt.Sprintf("%s-%d", batch.ID, p.OrderID),
+          EndToEndID:    fmt.Sprintf("ORDER-%d", p.OrderID),
+        },
+        Amount: pain001Amount{Instructed: pain001InstructedAmount{
+          Currency: currency,
+          Value:    formatCents(cents),
+        }},
+        CreditorAgent: pain001Agent{Institution: pain001Institution{
+          BIC:  validation.Normalize(p.Routing),
+          Name: truncate(p.BankName, 140),
+        }},
+        Creditor: pain001Party{
+          Name:    truncate(p.BeneficiaryName, 140),
+          Address: &pain001Address{Country: p.CountryCode},
+        },
+        CreditorAccount: pain001Account{ID: accountID(p.Account)},
+      }
+      if ref := strings.TrimSpace(p.Reference); ref != "" {
+        tx.Remittance = &pain001Remittance{Unstructured: truncate(ref, 140)}
+      }
+      txs = append(txs, tx)
+    }
+    total += subtotal
+
+    infos = append(infos, pain001PaymentInfo{
+      ID:            fmt.Sprintf("%s-%d", batch.ID, i+1),
+      Method:        "TRF",
+      NumberOfTxs:   len(txs),
+      ControlSum:    formatCents(subtotal),
+      ExecutionDate: batch.CreatedAt.Format("2006-01-02"),
+      Debtor:        pain001Party{Name: truncate(cfg.OriginatorName, 140)},
+      DebtorAccount: pain001Account{ID: accountID(cfg.DebtorAccount), Currency: currency},
+      DebtorAgent:   pain001Agent{Institution: pain001Institution{BIC: strings.ToUpper(cfg.DebtorBIC)}},
+      ChargeBearer:  "SLEV",
+      Transactions:  txs,
+    })
+  }
+
+  doc := pain001Document{
+    Xmlns: pain001Namespace,
+    Init: pain001Initiation{
+      GroupHeader: pain001GroupHeader{
+        MessageID:       truncate(batch.ID, 35),
+        CreationTime:    batch.CreatedAt.Format("2006-01-02T15:04:05"),
+        NumberOfTxs:     len(batch.Payments),
+        ControlSum:      formatCents(total),
+        InitiatingParty: pain001Party{Name: truncate(cfg.OriginatorName, 140)},
+      },
+      PaymentInfo: infos,
+    },
+  }
+
+  body, err := xml.MarshalIndent(doc, "", "  ")
+  if err != nil {
+    return File{}, err
+  }
+  content := append([]byte(xml.Header), body...)
+  content = append(content, '\n')
+
+  return File{
+    Name:        fmt.Sprintf("%s.xml", batch.ID),
+    ContentType: "application/xml",
+    Content:     content,
+    Total:       float64(total) / 100,
+  }, nil
+}
+
+func accountID(account string) pain001AccountID {
+  if validation.LooksLikeIBAN(account) {
+    return pain001AccountID{IBAN: validation.Normalize(account)}
+  }
+  return pain001AccountID{Other: &pain001OtherID{ID: strings.TrimSpace(account)}}
+}
+
+func formatCents(cents int64) string {
+  return fmt.Sprintf("%d.%02d", cents/100, cents%100)
+}
+
+func truncate(value string, max int) string {
+  value = strings.TrimSpace(value)
+  runes := []rune(value)
+  if len(runes) > max {
+    return string(runes[:max])
+  }
+  return value
+}
@@ -0,0 +1,201 @@
+package payout
+
+import (
+  "fmt"
+  "math"
+  "strings"
+  "time"
+)
+
+// Format identifies a bank payment file layout.
+type Format string
+
+const (
+  FormatPain001 Format = "pain.001"
+  FormatNACHA   Format = "nacha"
+  FormatCPA005  Format = "cpa005"
+)
+
+// ParseFormat normalizes a user supplied format name.
+func ParseFormat(raw string) (Format, error) {
+  switch strings.ToLower(strings.TrimSpace(raw)) {
+  case "pain.001", "pain001", "iso20022":
+    return FormatPain001, nil
+  case "nacha", "ach":
+    return FormatNACHA, nil
+  case "cpa005", "cpa-005", "cpa":
+    return FormatCPA005, nil
+  default:
+    return "", fmt.Errorf("unsupported payout format %q", raw)
+  }
+}
+
+// Config holds originator details printed into generated files.
+type Config struct {
+  OriginatorName string
+  DebtorAccount  string
+  DebtorBIC      string
+
+  NACHAImmediateDestination string
+  NACHAImmediateOrigin      string
+  NACHADestinationName      string
+  NACHACompanyID            string
+  NACHAODFIRouting          string
+
+  CPA005OriginatorID      string
+  CPA005DataCentre        string
+  CPA005ReturnInstitution string
+  CPA005ReturnAccount     string
+}
+
+// Validate reports the settings missing for the given format.
+func (c Config) Validate(format Format) error {
+  missing := make([]string, 0)
+  require := func(value string, name string) {
+    if strings.TrimSpace(value) == "" {
+      missing = append(missing, name)
+    }
+  }
+
+  require(c.OriginatorName, "PAYOUT_ORIGINATOR_NAME")
+  switch format {
+  case FormatPain001:
+    require(c.DebtorAccount, "PAYOUT_DEBTOR_ACCOUNT")
+    require(c.DebtorBIC, "PAYOUT_DEBTOR_BIC")
+  case FormatNACHA:
+    require(c.NACHAImmediateDestination, "PAYOUT_NACHA_IMMEDIATE_DESTINATION")
+    require(c.NACHAImmediateOrigin, "PAYOUT_NACHA_IMMEDIATE_ORIGIN")
+    require(c.NACHACompanyID, "PAYOUT_NACHA_COMPANY_ID")
+    require(c.NACHAODFIRouting, "PAYOUT_NACHA_ODFI_ROUTING")
+  case FormatCPA005:
+    require(c.CPA005OriginatorID, "PAYOUT_CPA005_ORIGINATOR_ID")
+    require(c.CPA005DataCentre, "PAYOUT_CPA005_DATA_CENTRE")
+    require(c.CPA005ReturnInstitution, "PAYOUT_CPA005_RETURN_INSTITUTION")
+    require(c.CPA005ReturnAccount, "PAYOUT_CPA005_RETURN_ACCOUNT")
+  }
+
+  if len(missing) > 0 {
+    return fmt.Errorf("payout %s not configured: missing %s", format, strings.Join(missing, ", "))
+  }
+  return nil
+}
+
+// Payment is a single beneficiary credit taken from an order.
+type Payment struct {
+  OrderID         int64
+  Amount          float64
+  BeneficiaryName string
+  BankCountry     string
+  // CountryCode and Currency come from the payout country catalogue.
+  CountryCode string
+  Currency    string
+  BankName    string
+  // Account is the IBAN or domestic account number (the order's iban field).
+  Account string
+  // Routing is the BIC, ABA routing number or Canadian institution+transit
+  // (the order's swift field).
+  Routing   string
+  Reference string
+}
+
+// Batch groups payments exported into a single file.
+type Batch struct {
+  ID string
+  // Sequence is a small increasing number used where a format requires a
+  // file creation number.
+  Sequence  int64
+  CreatedAt time.Time
+  Payments  []Payment
+}
+
+// File is a generated payment file.
+type File struct {
+  Name        string
+  ContentType string
+  Content     []byte
+  Total       float64
+}
+
+// PaymentError reports an order that cannot be exported in the requested format.
+type PaymentError struct {
+  OrderID int64
+  Reason  string
+}
+
+func (e PaymentError) Error() string {
+  return fmt.Sprintf("order %d: %s", e.OrderID, e.Reason)
+}
+
+// Generate renders the batch in the requested format.
+func Generate(format Format, cfg Config, batch Batch) (File, error) {
+  if err := cfg.Validate(format); err != nil {
+    return File{}, err
+  }
+  if len(batch.Payments) == 0 {
+    return File{}, fmt.Errorf("batch has no payments")
+  }
+  for _, p := range batch.Payments {
+    if p.Amount <= 0 {
+      return File{}, PaymentError{OrderID: p.OrderID, Reason: "amount must be positive"}
+    }
+    if p.CountryCode == "" || p.Currency == "" {
+      return File{}, PaymentError{OrderID: p.OrderID, Reason: "bank country is not in the payout catalogue"}
+    }
+  }
+
+  switch format {
+  case FormatPain001:
+    return generatePain001(cfg, batch)
+  case FormatNACHA:
+    return generateNACHA(cfg, batch)
+  case FormatCPA005:
+    return generateCPA005(cfg, batch)
+  default:
+    return File{}, fmt.Errorf("unsupported payout format %q", format)
+  }
+}
+
+func toCents(amount float64) int64 {
+  return int64(math.Round(amount * 100))
+}
+
+func digitsOnly(value string) string {
+  var b strings.Builder
+  for _, r := range value {
+    if r >= '0' && r <= '9' {
+      b.WriteRune(r)
+    }
+  }
+  return b.String()
+}
+
+// asciiUpper strips characters that fixed-width bank formats reject.
+func asciiUpper(value string) string {
+  var b strings.Builder
+  for _, r := range strings.ToUpper(value) {
+    if r >= 0x20 && r < 0x7f {
+      b.WriteRune(r)
+    } else {
+      b.WriteByte(' ')
+    }
+  }
+  return b.String()
+}
+
+func padRight(value string, width int) string {
+  if len(value) >= width {
+    return value[:width]
+  }
+  return value + strings.Repeat(" ", width-len(value))
+}
+
+func padLeftZero(value string, width int) string {
+  if len(value) >= width {
+    return value[len(value)-width:]
+  }
+  return strings.Repeat("0", width-len(value)) + value
+}
+
+func numeric(value int64, width int) string {
+  return padLeftZero(fmt.Sprintf("%d", value), width)
+}
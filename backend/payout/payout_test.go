@@ -0,0 +1,226 @@
+package payout
+
+import (
+  "bytes"
+  "encoding/xml"
+  "errors"
+  "flag"
+  "os"
+  "path/filepath"
+  "strings"
+  "testing"
+  "time"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files in testdata")
+
+var testConfig = Config{
+  OriginatorName: "Sarah Payouts Ltd",
+  DebtorAccount:  "DE89370400440532013000",
+  DebtorBIC:      "DEUTDEFF",
+
+  NACHAImmediateDestination: "021000021",
+  NACHAImmediateOrigin:      "1234567890",
+  NACHADestinationName:      "JPMORGAN CHASE",
+  NACHACompanyID:            "1234567890",
+  NACHAODFIRouting:          "021000021",
+
+  CPA005OriginatorID:      "SARAHPAY01",
+  CPA005DataCentre:        "00120",
+  CPA005ReturnInstitution: "003-12345",
+  CPA005ReturnAccount:     "1234567",
+}
+
+func testBatch(payments ...Payment) Batch {
+  return Batch{
+    ID:        "PB20240305143000TEST",
+    Sequence:  7,
+    CreatedAt: time.Date(2024, 3, 5, 14, 30, 0, 0, time.UTC),
+    Payments:  payments,
+  }
+}
+
+func usPayment(id int64, amount float64) Payment {
+  return Payment{
+    OrderID: id, Amount: amount, BeneficiaryName: "Jane Doe",
+    BankCountry: "United States", CountryCode: "US", Currency: "USD",
+    BankName: "Chase", Account: "123456789", Routing: "021000021",
+  }
+}
+
+func caPayment(id int64, amount float64) Payment {
+  return Payment{
+    OrderID: id, Amount: amount, BeneficiaryName: "John Roe",
+    BankCountry: "Canada", CountryCode: "CA", Currency: "CAD",
+    BankName: "Royal Bank of Canada", Account: "7654321", Routing: "003-12345",
+  }
+}
+
+// assertGolden compares got with testdata/<name>. Run `go test -update` to
+// rewrite the files.
+func assertGolden(t *testing.T, name string, got []byte) {
+  t.Helper()
+  path := filepath.Join("testdata", name)
+  if *update {
+    if err := os.WriteFile(path, got, 0o644); err != nil {
+      t.Fatal(err)
+    }
+    return
+  }
+  want, err := os.ReadFile(path)
+  if err != nil {
+    t.Fatalf("golden %s: %v (run go test -update)", name, err)
+  }
+  if !bytes.Equal(got, want) {
+    t.Errorf("%s differs from golden file:\n%s", name, got)
+  }
+}
+
+func lines(content []byte) []string {
+  return strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+}
+
+func TestNACHA(t *testing.T) {
+  file, err := Generate(FormatNACHA, testConfig, testBatch(usPayment(1, 1250.5), usPayment(2, 99.99)))
+  if err != nil {
+    t.Fatal(err)
+  }
+  assertGolden(t, "nacha.ach", file.Content)
+
+  records := lines(file.Content)
+  for i, record := range records {
+    if len(record) != 94 {
+      t.Errorf("record %d has %d characters, want 94", i+1, len(record))
+    }
+  }
+  // Header, batch header, 2 entries, batch control, file control and
+  // 9-filled padding up to a block of 10.
+  if len(records) != 10 || records[9] != strings.Repeat("9", 94) {
+    t.Errorf("got %d records, want one block of 10 ending in filler", len(records))
+  }
+  if file.Total != 1350.49 || file.Name != "PB20240305143000TEST.ach" {
+    t.Errorf("total = %v, name = %q", file.Total, file.Name)
+  }
+
+  ca := caPayment(3, 10)
+  if _, err := Generate(FormatNACHA, testConfig, testBatch(ca)); !isPaymentError(err, 3) {
+    t.Errorf("Canadian payment: err = %v", err)
+  }
+  bad := usPayment(4, 10)
+  bad.Routing = "021000022"
+  if _, err := Generate(FormatNACHA, testConfig, testBatch(bad)); !isPaymentError(err, 4) {
+    t.Errorf("bad routing number: err = %v", err)
+  }
+}
+
+func TestCPA005(t *testing.T) {
+  payments := make([]Payment, 0, 7)
+  for i := int64(1); i <= 7; i++ {
+    payments = append(payments, caPayment(i, float64(i)*100.25))
+  }
+  file, err := Generate(FormatCPA005, testConfig, testBatch(payments...))
+  if err != nil {
+    t.Fatal(err)
+  }
+  assertGolden(t, "cpa005.cpa", file.Content)
+
+  records := lines(file.Content)
+  // A header, two C records (6 + 1 segments) and a trailer.
+  if len(records) != 4 {
+    t.Fatalf("got %d records, want 4", len(records))
+  }
+  for i, record := range records {
+    if len(record) != 1464 {
+      t.Errorf("record %d has %d characters, want 1464", i+1, len(record))
+    }
+  }
+  wantTypes := "ACCZ"
+  for i, record := range records {
+    if record[0] != wantTypes[i] {
+      t.Errorf("record %d type = %c, want %c", i+1, record[0], wantTypes[i])
+    }
+  }
+  // Segments follow the 24 character logical record prefix.
+  for i, record := range records[1:3] {
+    body := strings.TrimRight(record[24:], " ")
+    segments := (len(body) + 239) / 240
+    if want := []int{6, 1}[i]; segments != want {
+      t.Errorf("C record %d has %d segments, want %d", i+1, segments, want)
+    }
+    for s := 0; s < segments; s++ {
+      segment := record[24+s*240 : 24+(s+1)*240]
+      if !strings.HasPrefix(segment, "450") {
+        t.Errorf("C record %d segment %d does not start with the transaction type: %q", i+1, s+1, segment[:10])
+      }
+    }
+  }
+
+  us := usPayment(8, 10)
+  if _, err := Generate(FormatCPA005, testConfig, testBatch(us)); !isPaymentError(err, 8) {
+    t.Errorf("US payment: err = %v", err)
+  }
+}
+
+func TestPain001(t *testing.T) {
+  eu := Payment{
+    OrderID: 1, Amount: 1000, BeneficiaryName: "Max Mustermann",
+    BankCountry: "Germany", CountryCode: "DE", Currency: "EUR",
+    BankName: "Deutsche Bank", Account: "DE89 3704 0044 0532 0130 00", Routing: "DEUTDEFF", Reference: "Invoice 42",
+  }
+  gb := Payment{
+    OrderID: 2, Amount: 250.75, BeneficiaryName: "Jane Smith",
+    BankCountry: "United Kingdom", CountryCode: "GB", Currency: "GBP",
+    BankName: "Barclays", Account: "GB82WEST12345698765432", Routing: "BARCGB22XXX",
+  }
+  file, err := Generate(FormatPain001, testConfig, testBatch(eu, gb))
+  if err != nil {
+    t.Fatal(err)
+  }
+  assertGolden(t, "pain001.xml", file.Content)
+
+  var doc pain001Document
+  if err := xml.Unmarshal(file.Content, &doc); err != nil {
+    t.Fatalf("decode: %v", err)
+  }
+  if doc.Xmlns != pain001Namespace || doc.Init.GroupHeader.NumberOfTxs != 2 || doc.Init.GroupHeader.ControlSum != "1250.75" {
+    t.Errorf("group header = %+v", doc.Init.GroupHeader)
+  }
+  // One payment information block per currency, sorted.
+  if len(doc.Init.PaymentInfo) != 2 || doc.Init.PaymentInfo[0].DebtorAccount.Currency != "EUR" {
+    t.Fatalf("payment info = %+v", doc.Init.PaymentInfo)
+  }
+  tx := doc.Init.PaymentInfo[0].Transactions[0]
+  if tx.CreditorAccount.ID.IBAN != "DE89370400440532013000" || tx.Amount.Instructed.Value != "1000.00" || tx.Remittance.Unstructured != "Invoice 42" {
+    t.Errorf("transaction = %+v", tx)
+  }
+
+  reencoded, err := xml.MarshalIndent(doc, "", "  ")
+  if err != nil {
+    t.Fatal(err)
+  }
+  if want := xml.Header + string(reencoded) + "\n"; want != string(file.Content) {
+    t.Errorf("document does not round-trip:\n%s", reencoded)
+  }
+
+  gb.Routing = "BARC"
+  if _, err := Generate(FormatPain001, testConfig, testBatch(gb)); !isPaymentError(err, 2) {
+    t.Errorf("bad BIC: err = %v", err)
+  }
+}
+
+func TestGenerateRejects(t *testing.T) {
+  if _, err := Generate(FormatNACHA, Config{OriginatorName: "x"}, testBatch(usPayment(1, 1))); err == nil || !strings.Contains(err.Error(), "PAYOUT_NACHA_COMPANY_ID") {
+    t.Errorf("missing config: err = %v", err)
+  }
+  if _, err := Generate(FormatNACHA, testConfig, testBatch()); err == nil {
+    t.Error("empty batch: no error")
+  }
+  if _, err := Generate(FormatNACHA, testConfig, testBatch(usPayment(5, 0))); !isPaymentError(err, 5) {
+    t.Errorf("zero amount: err = %v", err)
+  }
+}
+
+func isPaymentError(err error, orderID int64) bool {
+  var pe PaymentError
+  return errors.As(err, &pe) && pe.OrderID == orderID
+}
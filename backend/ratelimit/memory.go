@@ -0,0 +1,64 @@
+package ratelimit
+
+import (
+  "context"
+  "sync"
+  "time"
+)
+
+// pruneEvery is how many takes pass between sweeps for full buckets.
+const pruneEvery = 1024
+
+// Memory keeps buckets in process memory. Limits are not shared between
+// instances. It is safe for concurrent use.
+type Memory struct {
+  mu      sync.Mutex
+  now     func() time.Time
+  buckets map[string]*bucket
+  takes   int
+}
+
+type bucket struct {
+  tokens float64
+  last   time.Time
+  // full is when the bucket will have refilled, after which it can be
+  // dropped without changing any result.
+  full time.Time
+}
+
+// NewMemory returns an empty in-memory bucket store.
+func NewMemory() *Memory {
+  return &Memory{now: time.Now, buckets: make(map[string]*bucket)}
+}
+
+// SetClock replaces the time source.
+func (m *Memory) SetClock(now func() time.Time) {
+  m.mu.Lock()
+  defer m.mu.Unlock()
+  m.now = now
+}
+
+// Take implements Store.
+func (m *Memory) Take(_ context.Context, key string, limit Limit) (Result, error) {
+  m.mu.Lock()
+  defer m.mu.Unlock()
+
+  now := m.now()
+  m.takes++
+  if m.takes%pruneEvery == 0 {
+    for k, b := range m.buckets {
+      if !now.Before(b.full) {
+        delete(m.buckets, k)
+      }
+    }
+  }
+
+  b, ok := m.buckets[key]
+  if !ok {
+    b = &bucket{tokens: limit.burst(), last: now}
+    m.buckets[key] = b
+  }
+  tokens, res := Take(b.tokens, b.last, now, limit)
+  b.tokens, b.last, b.full = tokens, now, now.Add(res.Reset)
+  return res, nil
+}
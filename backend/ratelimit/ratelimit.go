@@ -0,0 +1,84 @@
+// Package ratelimit implements token bucket rate limiting.
+package ratelimit
+
+import (
+  "context"
+  "math"
+  "time"
+)
+
+// Limit allows PerMinute requests per minute on average with bursts of up
+// to Burst requests. A Limit with PerMinute <= 0 is unlimited.
+type Limit struct {
+  PerMinute int
+  Burst     int
+}
+
+// Unlimited reports whether l places no limit.
+func (l Limit) Unlimited() bool {
+  return l.PerMinute <= 0
+}
+
+// perSecond is the refill rate in tokens per second.
+func (l Limit) perSecond() float64 {
+  return float64(l.PerMinute) / 60
+}
+
+func (l Limit) burst() float64 {
+  return float64(max(l.Burst, 1))
+}
+
+// Result describes a bucket after a Take.
+type Result struct {
+  Allowed bool
+  // Limit is the bucket size.
+  Limit int
+  // Remaining is the number of whole tokens left.
+  Remaining int
+  // RetryAfter is how long until a token is available. It is zero when
+  // Allowed is true.
+  RetryAfter time.Duration
+  // Reset is how long until the bucket is full again.
+  Reset time.Duration
+}
+
+// Store keeps token buckets.
+type Store interface {
+  // Take refills the bucket for key and tries to remove one token.
+  Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// StoreFunc adapts a function to the Store interface.
+type StoreFunc func(ctx context.Context, key string, limit Limit) (Result, error)
+
+// Take calls f.
+func (f StoreFunc) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+  return f(ctx, key, limit)
+}
+
+// Take refills a bucket that held tokens when it was last updated and tries
+// to remove one token at now. It returns the bucket's new token count for
+// the caller to store. A bucket seen for the first time should be passed
+// limit.Burst tokens.
+func Take(tokens float64, last, now time.Time, limit Limit) (float64, Result) {
+  rate, burst := limit.perSecond(), limit.burst()
+  if elapsed := now.Sub(last); elapsed > 0 {
+    tokens += elapsed.Seconds() * rate
+  }
+  tokens = math.Min(tokens, burst)
+
+  res := Result{Limit: int(burst)}
+  if tokens >= 1 {
+    tokens--
+    res.Allowed = true
+  } else {
+    res.RetryAfter = seconds((1 - tokens) / rate)
+  }
+  res.Remaining = int(tokens)
+  res.Reset = seconds((burst - tokens) / rate)
+  return tokens, res
+}
+
+func seconds(s float64) time.Duration {
+  return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,54 @@
+package ratelimit
+
+import (
+  "context"
+  "testing"
+  "time"
+)
+
+func TestMemoryTake(t *testing.T) {
+  now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+  m := NewMemory()
+  m.SetClock(func() time.Time { return now })
+  limit := Limit{PerMinute: 60, Burst: 3}
+
+  take := func() Result {
+    t.Helper()
+    res, err := m.Take(context.Background(), "k", limit)
+    if err != nil {
+      t.Fatal(err)
+    }
+    return res
+  }
+
+  for i := 2; i >= 0; i-- {
+    res := take()
+    if !res.Allowed || res.Remaining != i || res.Limit != 3 {
+      t.Fatalf("take with %d left = %+v", i, res)
+    }
+  }
+  res := take()
+  if res.Allowed || res.RetryAfter != time.Second || res.Reset != 3*time.Second {
+    t.Fatalf("take from empty bucket = %+v, want denied with 1s retry", res)
+  }
+
+  // One token refills per second.
+  now = now.Add(1500 * time.Millisecond)
+  if res := take(); !res.Allowed || res.Remaining != 0 {
+    t.Fatalf("take after refill = %+v", res)
+  }
+  if res := take(); res.Allowed || res.RetryAfter != 500*time.Millisecond {
+    t.Fatalf("take with half a token = %+v, want 500ms retry", res)
+  }
+
+  // A long pause refills only up to the burst.
+  now = now.Add(time.Hour)
+  if res := take(); res.Remaining != 2 {
+    t.Fatalf("take after long pause = %+v, want 2 remaining", res)
+  }
+
+  // Keys are independent.
+  if res, _ := m.Take(context.Background(), "other", limit); !res.Allowed || res.Remaining != 2 {
+    t.Fatalf("take on new key = %+v", res)
+  }
+}
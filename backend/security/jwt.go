@@ -0,0 +1,202 @@
+package security
+
+import (
+  "crypto"
+  "crypto/ed25519"
+  "crypto/rsa"
+  "crypto/x509"
+  "encoding/base64"
+  "encoding/pem"
+  "errors"
+  "fmt"
+  "math/big"
+  "sort"
+
+  "github.com/golang-jwt/jwt/v5"
+)
+
+// Key is a JWT key identified by the kid header.
+type Key struct {
+  ID     string
+  Method jwt.SigningMethod
+  // sign is nil for verify-only keys, i.e. PEM public keys.
+  sign   crypto.PrivateKey
+  verify crypto.PublicKey
+}
+
+// ParseKey builds a key from material. PEM-encoded RSA and Ed25519 keys
+// select RS256 and EdDSA; a PEM public key can only verify. Anything else is
+// used as an HS256 secret.
+func ParseKey(id string, material []byte) (Key, error) {
+  if id == "" {
+    return Key{}, errors.New("key id is required")
+  }
+
+  block, _ := pem.Decode(material)
+  if block == nil {
+    if len(material) == 0 {
+      return Key{}, fmt.Errorf("key %s: empty secret", id)
+    }
+    return Key{ID: id, Method: jwt.SigningMethodHS256, sign: material, verify: material}, nil
+  }
+
+  var (
+    parsed any
+    err    error
+  )
+  switch block.Type {
+  case "RSA PRIVATE KEY":
+    parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+  case "PRIVATE KEY":
+    parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+  case "RSA PUBLIC KEY":
+    parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+  case "PUBLIC KEY":
+    parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+  default:
+    return Key{}, fmt.Errorf("key %s: unsupported PEM block %q", id, block.Type)
+  }
+  if err != nil {
+    return Key{}, fmt.Errorf("key %s: %w", id, err)
+  }
+
+  switch k := parsed.(type) {
+  case *rsa.PrivateKey:
+    return Key{ID: id, Method: jwt.SigningMethodRS256, sign: k, verify: &k.PublicKey}, nil
+  case *rsa.PublicKey:
+    return Key{ID: id, Method: jwt.SigningMethodRS256, verify: k}, nil
+  case ed25519.PrivateKey:
+    return Key{ID: id, Method: jwt.SigningMethodEdDSA, sign: k, verify: k.Public()}, nil
+  case ed25519.PublicKey:
+    return Key{ID: id, Method: jwt.SigningMethodEdDSA, verify: k}, nil
+  default:
+    return Key{}, fmt.Errorf("key %s: unsupported key type %T (use RSA or Ed25519)", id, parsed)
+  }
+}
+
+// KeySet signs tokens with one key and verifies tokens signed by any of its
+// keys, so old tokens stay valid while keys are rotated.
+type KeySet struct {
+  signing Key
+  keys    map[string]Key
+}
+
+// NewKeySet returns a set that signs with the key named signingID.
+func NewKeySet(signingID string, keys ...Key) (*KeySet, error) {
+  set := &KeySet{keys: make(map[string]Key, len(keys))}
+  for _, key := range keys {
+    if _, dup := set.keys[key.ID]; dup {
+      return nil, fmt.Errorf("duplicate key id %q", key.ID)
+    }
+    set.keys[key.ID] = key
+  }
+
+  signing, ok := set.keys[signingID]
+  if !ok {
+    return nil, fmt.Errorf("signing key %q not found", signingID)
+  }
+  if signing.sign == nil {
+    return nil, fmt.Errorf("signing key %q is a public key", signingID)
+  }
+  set.signing = signing
+  return set, nil
+}
+
+// Sign returns a token for claims with the signing key's kid in its header.
+func (s *KeySet) Sign(claims jwt.Claims) (string, error) {
+  token := jwt.NewWithClaims(s.signing.Method, claims)
+  token.Header["kid"] = s.signing.ID
+  return token.SignedString(s.signing.sign)
+}
+
+// Keyfunc resolves the verification key for token. Tokens without a kid
+// predate key rotation and are checked against every HS256 key.
+func (s *KeySet) Keyfunc(token *jwt.Token) (any, error) {
+  kid, _ := token.Header["kid"].(string)
+  if kid == "" {
+    if token.Method != jwt.SigningMethodHS256 {
+      return nil, errors.New("token without kid must use HS256")
+    }
+    var set jwt.VerificationKeySet
+    for _, key := range s.keys {
+      if key.Method == jwt.SigningMethodHS256 {
+        set.Keys = append(set.Keys, key.verify)
+      }
+    }
+    return set, nil
+  }
+
+  key, ok := s.keys[kid]
+  if !ok {
+    return nil, fmt.Errorf("unknown key id %q", kid)
+  }
+  // The header alg must match the key, otherwise an RSA public key could be
+  // used as an HMAC secret.
+  if token.Method.Alg() != key.Method.Alg() {
+    return nil, fmt.Errorf("key %q does not use %s", kid, token.Method.Alg())
+  }
+  return key.verify, nil
+}
+
+// Methods lists the algorithms accepted by Keyfunc.
+func (s *KeySet) Methods() []string {
+  seen := make(map[string]bool)
+  methods := make([]string, 0, 3)
+  for _, key := range s.keys {
+    if alg := key.Method.Alg(); !seen[alg] {
+      seen[alg] = true
+      methods = append(methods, alg)
+    }
+  }
+  sort.Strings(methods)
+  return methods
+}
+
+// JWK is a public key in JSON Web Key format.
+type JWK struct {
+  KeyType string `json:"kty"`
+  KeyID   string `json:"kid"`
+  Use     string `json:"use"`
+  Alg     string `json:"alg"`
+  // RSA
+  N string `json:"n,omitempty"`
+  E string `json:"e,omitempty"`
+  // Ed25519
+  Curve string `json:"crv,omitempty"`
+  X     string `json:"x,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set.
+type JWKS struct {
+  Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public RS256 and EdDSA keys, sorted by kid. HMAC
+// secrets are never published.
+func (s *KeySet) JWKS() JWKS {
+  out := JWKS{Keys: make([]JWK, 0, len(s.keys))}
+  for _, key := range s.keys {
+    switch pub := key.verify.(type) {
+    case *rsa.PublicKey:
+      out.Keys = append(out.Keys, JWK{
+        KeyType: "RSA",
+        KeyID:   key.ID,
+        Use:     "sig",
+        Alg:     key.Method.Alg(),
+        N:       base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+        E:       base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+      })
+    case ed25519.PublicKey:
+      out.Keys = append(out.Keys, JWK{
+        KeyType: "OKP",
+        KeyID:   key.ID,
+        Use:     "sig",
+        Alg:     key.Method.Alg(),
+        Curve:   "Ed25519",
+        X:       base64.RawURLEncoding.EncodeToString(pub),
+      })
+    }
+  }
+  sort.Slice(out.Keys, func(i, j int) bool { return out.Keys[i].KeyID < out.Keys[j].KeyID })
+  return out
+}
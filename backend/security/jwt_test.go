@@ -0,0 +1,214 @@
+package security
+
+import (
+  "crypto/ed25519"
+  "crypto/rand"
+  "crypto/rsa"
+  "crypto/x509"
+  "encoding/pem"
+  "testing"
+  "time"
+
+  "github.com/golang-jwt/jwt/v5"
+)
+
+func pemKey(blockType string, der []byte) []byte {
+  return pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+}
+
+func mustParseKey(t *testing.T, id string, material []byte) Key {
+  t.Helper()
+
+  key, err := ParseKey(id, material)
+  if err != nil {
+    t.Fatalf("ParseKey(%s): %v", id, err)
+  }
+  return key
+}
+
+func testClaims() jwt.RegisteredClaims {
+  return jwt.RegisteredClaims{
+    Subject:   "1",
+    ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+  }
+}
+
+func verify(set *KeySet, token string) error {
+  _, err := jwt.ParseWithClaims(token, &jwt.RegisteredClaims{}, set.Keyfunc, jwt.WithValidMethods(set.Methods()))
+  return err
+}
+
+func TestKeySetRotation(t *testing.T) {
+  rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+  if err != nil {
+    t.Fatal(err)
+  }
+  _, edKey, err := ed25519.GenerateKey(rand.Reader)
+  if err != nil {
+    t.Fatal(err)
+  }
+  edDER, err := x509.MarshalPKCS8PrivateKey(edKey)
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  keys := map[string]Key{
+    "hmac-old": mustParseKey(t, "hmac-old", []byte("old-secret")),
+    "hmac-new": mustParseKey(t, "hmac-new", []byte("new-secret")),
+    "rsa":      mustParseKey(t, "rsa", pemKey("RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey))),
+    "ed":       mustParseKey(t, "ed", pemKey("PRIVATE KEY", edDER)),
+  }
+  wantMethods := map[string]string{"hmac-old": "HS256", "hmac-new": "HS256", "rsa": "RS256", "ed": "EdDSA"}
+  for id, key := range keys {
+    if key.Method.Alg() != wantMethods[id] {
+      t.Errorf("key %s method = %s, want %s", id, key.Method.Alg(), wantMethods[id])
+    }
+  }
+
+  all := []Key{keys["hmac-old"], keys["hmac-new"], keys["rsa"], keys["ed"]}
+  for signing := range keys {
+    t.Run(signing, func(t *testing.T) {
+      signer, err := NewKeySet(signing, keys[signing])
+      if err != nil {
+        t.Fatal(err)
+      }
+      token, err := signer.Sign(testClaims())
+      if err != nil {
+        t.Fatalf("Sign: %v", err)
+      }
+
+      // Every set that still holds the key accepts the token.
+      verifier, err := NewKeySet("hmac-new", all...)
+      if err != nil {
+        t.Fatal(err)
+      }
+      if err := verify(verifier, token); err != nil {
+        t.Errorf("token signed by %s rejected: %v", signing, err)
+      }
+
+      // Once the key is retired, its tokens are rejected.
+      var remaining []Key
+      for _, key := range all {
+        if key.ID != signing && key.ID != "hmac-new" {
+          remaining = append(remaining, key)
+        }
+      }
+      retired, err := NewKeySet(remaining[0].ID, remaining...)
+      if err != nil {
+        t.Fatal(err)
+      }
+      if err := verify(retired, token); err == nil {
+        t.Errorf("token signed by retired key %s accepted", signing)
+      }
+    })
+  }
+}
+
+func TestKeySetLegacyTokenWithoutKID(t *testing.T) {
+  set, err := NewKeySet("new", mustParseKey(t, "default", []byte("legacy-secret")), mustParseKey(t, "new", []byte("new-secret")))
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  legacy, err := jwt.NewWithClaims(jwt.SigningMethodHS256, testClaims()).SignedString([]byte("legacy-secret"))
+  if err != nil {
+    t.Fatal(err)
+  }
+  if err := verify(set, legacy); err != nil {
+    t.Errorf("legacy token rejected: %v", err)
+  }
+
+  forged, err := jwt.NewWithClaims(jwt.SigningMethodHS256, testClaims()).SignedString([]byte("other-secret"))
+  if err != nil {
+    t.Fatal(err)
+  }
+  if err := verify(set, forged); err == nil {
+    t.Error("token signed with unknown secret accepted")
+  }
+}
+
+func TestKeySetRejectsAlgorithmConfusion(t *testing.T) {
+  rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+  if err != nil {
+    t.Fatal(err)
+  }
+  pubPEM := pemKey("RSA PUBLIC KEY", x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey))
+  set, err := NewKeySet("hmac", mustParseKey(t, "hmac", []byte("secret")), mustParseKey(t, "rsa", pubPEM))
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  // An attacker signs with HS256 using the published RSA public key as the
+  // secret and points kid at the RSA key.
+  token := jwt.NewWithClaims(jwt.SigningMethodHS256, testClaims())
+  token.Header["kid"] = "rsa"
+  forged, err := token.SignedString(pubPEM)
+  if err != nil {
+    t.Fatal(err)
+  }
+  if err := verify(set, forged); err == nil {
+    t.Error("HS256 token accepted for RS256 key")
+  }
+}
+
+func TestNewKeySetErrors(t *testing.T) {
+  rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+  if err != nil {
+    t.Fatal(err)
+  }
+  public := mustParseKey(t, "pub", pemKey("RSA PUBLIC KEY", x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey)))
+  secret := mustParseKey(t, "a", []byte("secret"))
+
+  tests := []struct {
+    name    string
+    signing string
+    keys    []Key
+  }{
+    {"missing signing key", "b", []Key{secret}},
+    {"duplicate kid", "a", []Key{secret, secret}},
+    {"public signing key", "pub", []Key{public}},
+  }
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      if _, err := NewKeySet(tt.signing, tt.keys...); err == nil {
+        t.Error("NewKeySet succeeded, want error")
+      }
+    })
+  }
+}
+
+func TestJWKS(t *testing.T) {
+  rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+  if err != nil {
+    t.Fatal(err)
+  }
+  edPub, _, err := ed25519.GenerateKey(rand.Reader)
+  if err != nil {
+    t.Fatal(err)
+  }
+  edDER, err := x509.MarshalPKIXPublicKey(edPub)
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  set, err := NewKeySet("rsa",
+    mustParseKey(t, "hmac", []byte("secret")),
+    mustParseKey(t, "rsa", pemKey("RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey))),
+    mustParseKey(t, "ed", pemKey("PUBLIC KEY", edDER)),
+  )
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  jwks := set.JWKS()
+  if len(jwks.Keys) != 2 {
+    t.Fatalf("JWKS has %d keys, want 2 (HMAC excluded): %+v", len(jwks.Keys), jwks.Keys)
+  }
+  ed, rsaJWK := jwks.Keys[0], jwks.Keys[1]
+  if ed.KeyID != "ed" || ed.KeyType != "OKP" || ed.Curve != "Ed25519" || ed.Alg != "EdDSA" || ed.X == "" {
+    t.Errorf("ed25519 JWK = %+v", ed)
+  }
+  if rsaJWK.KeyID != "rsa" || rsaJWK.KeyType != "RSA" || rsaJWK.Alg != "RS256" || rsaJWK.N == "" || rsaJWK.E != "AQAB" {
+    t.Errorf("RSA JWK = %+v", rsaJWK)
+  }
+}
@@ -0,0 +1,461 @@
+package store
+
+import (
+  "context"
+  "fmt"
+  "sort"
+  "sync"
+  "time"
+
+  "sarah-project-backend/dto"
+  "sarah-project-backend/payout"
+  "sarah-project-backend/ratelimit"
+)
+
+// Memory is an in-process store for tests and local development. It
+// implements every store interface and is safe for concurrent use.
+type Memory struct {
+  mu sync.Mutex
+
+  now func() time.Time
+
+  admins    map[string]dto.AdminDTO
+  apiKeys   map[string]string
+  orders    map[int64]dto.OrderDTO
+  assets    []dto.CatalogueAssetDTO
+  countries []dto.PayoutCountryDTO
+  batches   map[string]dto.PayoutBatchDTO
+  quotas    map[string]ratelimit.Limit
+  buckets   *ratelimit.Memory
+
+  nextOrderID int64
+  nextBatchID int64
+  nextAdminID int64
+  nextAssetID int64
+  nextCountry int64
+}
+
+// NewMemory returns an empty in-memory store.
+func NewMemory() *Memory {
+  return &Memory{
+    now:     time.Now,
+    admins:  make(map[string]dto.AdminDTO),
+    apiKeys: make(map[string]string),
+    orders:  make(map[int64]dto.OrderDTO),
+    batches: make(map[string]dto.PayoutBatchDTO),
+    quotas:  make(map[string]ratelimit.Limit),
+    buckets: ratelimit.NewMemory(),
+  }
+}
+
+// SetClock replaces the time source used for created_at and updated_at.
+func (m *Memory) SetClock(now func() time.Time) {
+  m.mu.Lock()
+  defer m.mu.Unlock()
+  m.now = now
+  m.buckets.SetClock(now)
+}
+
+// AddAdmin seeds an admin account and returns its ID.
+func (m *Memory) AddAdmin(admin dto.AdminDTO) int64 {
+  m.mu.Lock()
+  defer m.mu.Unlock()
+
+  m.nextAdminID++
+  now := m.now()
+  admin.ID = m.nextAdminID
+  admin.CreatedAt = now
+  admin.UpdatedAt = now
+  m.admins[admin.Username] = admin
+  return admin.ID
+}
+
+// AddAPIKey seeds an active API key for merchantName.
+func (m *Memory) AddAPIKey(apiKey string, merchantName string) {
+  m.mu.Lock()
+  defer m.mu.Unlock()
+  m.apiKeys[apiKey] = merchantName
+}
+
+// SetMerchantRateLimit seeds a quota for merchantName.
+func (m *Memory) SetMerchantRateLimit(merchantName string, limit ratelimit.Limit) {
+  m.mu.Lock()
+  defer m.mu.Unlock()
+  m.quotas[merchantName] = limit
+}
+
+// CreateOrder stores a new order and returns its ID.
+func (m *Memory) CreateOrder(_ context.Context, order dto.OrderDTO) (int64, error) {
+  m.mu.Lock()
+  defer m.mu.Unlock()
+
+  if key := order.IdempotencyKey; key.Valid {
+    for _, o := range m.orders {
+      if o.MerchantName == order.MerchantName && o.IdempotencyKey == key {
+        return 0, fmt.Errorf("idempotency key %q already used", key.String)
+      }
+    }
+  }
+  m.nextOrderID++
+  now := m.now()
+  order.ID = m.nextOrderID
+  order.CreatedAt = now
+  order.UpdatedAt = now
+  m.orders[order.ID] = order
+  return order.ID, nil
+}
+
+// ListMerchantOrders returns one page of a merchant's orders, newest first.
+func (m *Memory) ListMerchantOrders(_ context.Context, merchantName string, page int, pageSize int) (int64, []dto.OrderDTO, error) {
+  m.mu.Lock()
+  defer m.mu.Unlock()
+
+  orders := m.filterOrders(func(o dto.OrderDTO) bool { return o.MerchantName == merchantName })
+  sort.Slice(orders, func(i, j int) bool { return orders[i].ID > orders[j].ID })
+  return int64(len(orders)), paginate(orders, page, pageSize), nil
+}
+
+// GetMerchantOrder returns an order owned by merchantName.
+func (m *Memory) GetMerchantOrder(_ context.Context, merchantName string, orderID int64) (dto.OrderDTO, error) {
+  m.mu.Lock()
+  defer m.mu.Unlock()
+
+  order, ok := m.orders[orderID]
+  if !ok || order.MerchantName != merchantName {
+    return dto.OrderDTO{}, ErrNotFound
+  }
+  return order, nil
+}
+
+// GetOrderByIdempotencyKey returns the merchant's order created with key.
+func (m *Memory) GetOrderByIdempotencyKey(_ context.Context, merchantName string, key string) (dto.OrderDTO, error) {
+  m.mu.Lock()
+  defer m.mu.Unlock()
+
+  for _, order := range m.orders {
+    if order.MerchantName == merchantName && order.IdempotencyKey.Valid && order.IdempotencyKey.String == key {
+      return order, nil
+    }
+  }
+  return dto.OrderDTO{}, ErrNotFound
+}
+
+// GetOrder returns any order by ID.
+func (m *Memory) GetOrder(_ context.Context, orderID int64) (dto.OrderDTO, error) {
+  m.mu.Lock()
+  defer m.mu.Unlock()
+
+  order, ok := m.orders[orderID]
+  if !ok {
+    return dto.OrderDTO{}, ErrNotFound
+  }
+  return order, nil
+}
+
+// ListOrdersByStatus returns one page of orders in status, newest first.
+func (m *Memory) ListOrdersByStatus(_ context.Context, status string, page int, pageSize int) (int64, []dto.OrderDTO, error) {
+  m.mu.Lock()
+  defer m.mu.Unlock()
+
+  orders := m.filterOrders(func(o dto.OrderDTO) bool { return o.Status == status })
+  sort.Slice(orders, func(i, j int) bool {
+    if orders[i].CreatedAt.Equal(orders[j].CreatedAt) {
+      return orders[i].ID > orders[j].ID
+    }
+    return orders[i].CreatedAt.After(orders[j].CreatedAt)
+  })
+  return int64(len(orders)), paginate(orders, page, pageSize), nil
+}
+
+// ListRecentOrders returns one page of orders, most recently updated first.
+func (m *Memory) ListRecentOrders(_ context.Context, page int, pageSize int) (int64, []dto.OrderDTO, error) {
+  m.mu.Lock()
+  defer m.mu.Unlock()
+
+  orders := m.filterOrders(func(dto.OrderDTO) bool { return true })
+  sort.Slice(orders, func(i, j int) bool {
+    if orders[i].UpdatedAt.Equal(orders[j].UpdatedAt) {
+      return orders[i].ID > orders[j].ID
+    }
+    return orders[i].UpdatedAt.After(orders[j].UpdatedAt)
+  })
+  return int64(len(orders)), paginate(orders, page, pageSize), nil
+}
+
+// UpdateOrderStatus sets an order's status and returns the updated order.
+func (m *Memory) UpdateOrderStatus(_ context.Context, orderID int64, status string) (dto.OrderDTO, error) {
+  m.mu.Lock()
+  defer m.mu.Unlock()
+
+  order, ok := m.orders[orderID]
+  if !ok {
+    return dto.OrderDTO{}, ErrNotFound
+  }
+  if order.Status != status {
+    order.Status = status
+    order.UpdatedAt = m.now()
+    m.orders[orderID] = order
+  }
+  return order, nil
+}
+
+// OrderStats counts orders per dashboard bucket.
+func (m *Memory) OrderStats(_ context.Context, day time.Time) (OrderStats, error) {
+  m.mu.Lock()
+  defer m.mu.Unlock()
+
+  var stats OrderStats
+  y, mo, d := day.Date()
+  for _, o := range m.orders {
+    switch o.Status {
+    case dto.StatusFundsReceived:
+      stats.FundsReceived++
+    case dto.StatusProcessing:
+      stats.Processing++
+    case dto.StatusFailed:
+      stats.ActionRequired++
+    case dto.StatusSubmitted:
+      stats.Awaiting++
+    case dto.StatusPaid:
+      if oy, om, od := o.CreatedAt.In(day.Location()).Date(); oy == y && om == mo && od == d {
+        stats.CompletedToday++
+      }
+    }
+  }
+  return stats, nil
+}
+
+// MerchantByAPIKey returns the merchant name for a seeded API key.
+func (m *Memory) MerchantByAPIKey(_ context.Context, apiKey string, merchantName string) (string, error) {
+  m.mu.Lock()
+  defer m.mu.Unlock()
+
+  matched, ok := m.apiKeys[apiKey]
+  if !ok || matched != merchantName {
+    return "", ErrNotFound
+  }
+  return matched, nil
+}
+
+// DeactivateAPIKey removes the merchant's seeded API key.
+func (m *Memory) DeactivateAPIKey(_ context.Context, merchantName string) error {
+  m.mu.Lock()
+  defer m.mu.Unlock()
+
+  for key, name := range m.apiKeys {
+    if name == merchantName {
+      delete(m.apiKeys, key)
+      return nil
+    }
+  }
+  return ErrNotFound
+}
+
+// AdminByUsername returns a seeded admin account.
+func (m *Memory) AdminByUsername(_ context.Context, username string) (dto.AdminDTO, error) {
+  m.mu.Lock()
+  defer m.mu.Unlock()
+
+  admin, ok := m.admins[username]
+  if !ok {
+    return dto.AdminDTO{}, ErrNotFound
+  }
+  return admin, nil
+}
+
+// LoadCatalogue returns configured assets and payout countries.
+func (m *Memory) LoadCatalogue(_ context.Context, enabledOnly bool) (Catalogue, error) {
+  m.mu.Lock()
+  defer m.mu.Unlock()
+
+  cat := Catalogue{
+    Assets:    make([]dto.CatalogueAssetDTO, 0, len(m.assets)),
+    Countries: make([]dto.PayoutCountryDTO, 0, len(m.countries)),
+  }
+  for _, a := range m.assets {
+    if a.Enabled || !enabledOnly {
+      cat.Assets = append(cat.Assets, a)
+    }
+  }
+  for _, c := range m.countries {
+    if c.Enabled || !enabledOnly {
+      cat.Countries = append(cat.Countries, c)
+    }
+  }
+  sort.Slice(cat.Assets, func(i, j int) bool {
+    if cat.Assets[i].Network != cat.Assets[j].Network {
+      return cat.Assets[i].Network < cat.Assets[j].Network
+    }
+    return cat.Assets[i].Asset < cat.Assets[j].Asset
+  })
+  sort.Slice(cat.Countries, func(i, j int) bool { return cat.Countries[i].Name < cat.Countries[j].Name })
+  return cat, nil
+}
+
+// UpsertCatalogueAsset creates or updates a (network, asset) pair.
+func (m *Memory) UpsertCatalogueAsset(_ context.Context, asset dto.CatalogueAssetDTO) error {
+  m.mu.Lock()
+  defer m.mu.Unlock()
+
+  now := m.now()
+  for i, existing := range m.assets {
+    if existing.Network == asset.Network && existing.Asset == asset.Asset {
+      asset.ID = existing.ID
+      asset.CreatedAt = existing.CreatedAt
+      asset.UpdatedAt = now
+      m.assets[i] = asset
+      return nil
+    }
+  }
+  m.nextAssetID++
+  asset.ID = m.nextAssetID
+  asset.CreatedAt = now
+  asset.UpdatedAt = now
+  m.assets = append(m.assets, asset)
+  return nil
+}
+
+// UpsertPayoutCountry creates or updates a payout country.
+func (m *Memory) UpsertPayoutCountry(_ context.Context, country dto.PayoutCountryDTO) error {
+  m.mu.Lock()
+  defer m.mu.Unlock()
+
+  now := m.now()
+  for i, existing := range m.countries {
+    if existing.Name == country.Name {
+      country.ID = existing.ID
+      country.CreatedAt = existing.CreatedAt
+      country.UpdatedAt = now
+      m.countries[i] = country
+      return nil
+    }
+  }
+  m.nextCountry++
+  country.ID = m.nextCountry
+  country.CreatedAt = now
+  country.UpdatedAt = now
+  m.countries = append(m.countries, country)
+  return nil
+}
+
+// CreatePayoutBatch builds and records a batch. Nothing is stored when any
+// order is missing, in the wrong status or rejected by build.
+func (m *Memory) CreatePayoutBatch(_ context.Context, batch dto.PayoutBatchDTO, orderIDs []int64, build PayoutFileBuilder) (dto.PayoutBatchDTO, error) {
+  m.mu.Lock()
+  defer m.mu.Unlock()
+
+  ids := append([]int64(nil), orderIDs...)
+  sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+  payments := make([]payout.Payment, 0, len(ids))
+  for _, id := range ids {
+    order, ok := m.orders[id]
+    if !ok {
+      return dto.PayoutBatchDTO{}, ConflictError{Message: fmt.Sprintf("order %d not found", id)}
+    }
+    if order.Status != dto.StatusFundsReceived {
+      return dto.PayoutBatchDTO{}, ConflictError{Message: fmt.Sprintf("order %d is not in Funds Received status", id)}
+    }
+    if !order.Amount.Valid {
+      return dto.PayoutBatchDTO{}, payout.PaymentError{OrderID: id, Reason: "amount is missing"}
+    }
+    p := payout.Payment{
+      OrderID:         order.ID,
+      Amount:          order.Amount.Float64,
+      BeneficiaryName: order.BeneficiaryName,
+      BankCountry:     order.BankCountry,
+      BankName:        order.BankName,
+      Account:         order.IBAN,
+      Routing:         order.SWIFT,
+      Reference:       order.ReferenceNote.String,
+    }
+    for _, c := range m.countries {
+      if c.Name == order.BankCountry {
+        p.CountryCode = c.ISOCode
+        p.Currency = c.Currency
+      }
+    }
+    payments = append(payments, p)
+  }
+
+  sequence := m.nextBatchID + 1
+  file, err := build(sequence, payments)
+  if err != nil {
+    return dto.PayoutBatchDTO{}, err
+  }
+
+  m.nextBatchID = sequence
+  now := m.now()
+  batch.ID = sequence
+  batch.FileName = file.Name
+  batch.OrderCount = len(payments)
+  batch.TotalAmount = file.Total
+  batch.Content = string(file.Content)
+  batch.CreatedAt = now
+  batch.UpdatedAt = now
+  m.batches[batch.BatchID] = batch
+
+  for _, id := range ids {
+    order := m.orders[id]
+    order.Status = dto.StatusSubmitted
+    order.UpdatedAt = now
+    m.orders[id] = order
+  }
+  return batch, nil
+}
+
+// GetPayoutBatch returns a generated batch by its public batch ID.
+func (m *Memory) GetPayoutBatch(_ context.Context, batchID string) (dto.PayoutBatchDTO, error) {
+  m.mu.Lock()
+  defer m.mu.Unlock()
+
+  batch, ok := m.batches[batchID]
+  if !ok {
+    return dto.PayoutBatchDTO{}, ErrNotFound
+  }
+  return batch, nil
+}
+
+func (m *Memory) filterOrders(keep func(dto.OrderDTO) bool) []dto.OrderDTO {
+  orders := make([]dto.OrderDTO, 0)
+  for _, o := range m.orders {
+    if keep(o) {
+      orders = append(orders, o)
+    }
+  }
+  return orders
+}
+
+func paginate(orders []dto.OrderDTO, page int, pageSize int) []dto.OrderDTO {
+  start := (page - 1) * pageSize
+  if start >= len(orders) {
+    return make([]dto.OrderDTO, 0)
+  }
+  end := start + pageSize
+  if end > len(orders) {
+    end = len(orders)
+  }
+  return orders[start:end]
+}
+
+// MerchantRateLimit returns a seeded quota.
+func (m *Memory) MerchantRateLimit(_ context.Context, merchantName string) (ratelimit.Limit, error) {
+  m.mu.Lock()
+  defer m.mu.Unlock()
+
+  limit, ok := m.quotas[merchantName]
+  if !ok {
+    return ratelimit.Limit{}, ErrNotFound
+  }
+  return limit, nil
+}
+
+// TakeRateLimitToken takes a token from an in-process bucket.
+func (m *Memory) TakeRateLimitToken(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+  return m.buckets.Take(ctx, key, limit)
+}
+
+// PruneRateLimitBuckets is a no-op; in-process buckets prune themselves.
+func (m *Memory) PruneRateLimitBuckets(context.Context, time.Time) (int64, error) {
+  return 0, nil
+}
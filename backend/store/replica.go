@@ -0,0 +1,54 @@
+package store
+
+import (
+  "context"
+  "database/sql"
+  "errors"
+  "log/slog"
+  "time"
+)
+
+// replicaRetryAfter is how long reads stay on the primary after the replica
+// fails.
+const replicaRetryAfter = 30 * time.Second
+
+type staleKey struct{}
+
+// AllowStale marks reads made with the returned context as tolerant of
+// replication lag, so they may be served by the read replica.
+func AllowStale(ctx context.Context) context.Context {
+  return context.WithValue(ctx, staleKey{}, true)
+}
+
+func allowsStale(ctx context.Context) bool {
+  ok, _ := ctx.Value(staleKey{}).(bool)
+  return ok
+}
+
+// SetReplica routes reads made with an AllowStale context to replica. When
+// the replica fails, the read is retried on the primary and the replica is
+// skipped for a while.
+func (s *SQL) SetReplica(replica *sql.DB) {
+  s.replica = replica
+}
+
+// read runs fn against the replica when ctx allows stale reads and the
+// replica is healthy, falling back to the primary. Each attempt gets its own
+// query timeout so a hung replica does not use up the primary's budget.
+func (s *SQL) read(ctx context.Context, fn func(ctx context.Context, db *sql.DB) error) error {
+  attempt := func(db *sql.DB) error {
+    ctx, cancel := context.WithTimeout(ctx, s.timeouts.Query)
+    defer cancel()
+    return fn(ctx, db)
+  }
+
+  if s.replica != nil && allowsStale(ctx) && time.Now().UnixNano() >= s.replicaDownUntil.Load() {
+    err := attempt(s.replica)
+    if err == nil || errors.Is(err, ErrNotFound) || ctx.Err() != nil {
+      return err
+    }
+    slog.WarnContext(ctx, "read replica failed, using primary", "retry_after", replicaRetryAfter.String(), "error", err)
+    s.replicaDownUntil.Store(time.Now().Add(replicaRetryAfter).UnixNano())
+  }
+  return attempt(s.db)
+}
@@ -0,0 +1,76 @@
+package store
+
+import (
+  "context"
+  "database/sql"
+  "testing"
+
+  "sarah-project-backend/database"
+  "sarah-project-backend/migrations"
+)
+
+func openMigrated(t *testing.T) *sql.DB {
+  t.Helper()
+
+  db, err := database.OpenSQLite(":memory:")
+  if err != nil {
+    t.Fatal(err)
+  }
+  t.Cleanup(func() { db.Close() })
+  migrator, err := migrations.New(db, database.SQLite)
+  if err != nil {
+    t.Fatal(err)
+  }
+  if _, err := migrator.Up(context.Background(), 0); err != nil {
+    t.Fatal(err)
+  }
+  return db
+}
+
+func insertOrders(t *testing.T, db *sql.DB, n int) {
+  t.Helper()
+
+  for i := 0; i < n; i++ {
+    if _, err := db.Exec(`
+      INSERT INTO orders (merchant_name, transaction_network, transaction_asset, txid, amount, email,
+        beneficiary_name, bank_country, bank_name, iban, swift, reference_note, status)
+      VALUES ('acme', 'TRON', 'USDT', 'tx', 1, 'a@example.com', 'Jane', 'Canada', 'RBC', '1', '2', '', 'Processing')
+    `); err != nil {
+      t.Fatal(err)
+    }
+  }
+}
+
+func TestReplicaRouting(t *testing.T) {
+  primary, replica := openMigrated(t), openMigrated(t)
+  // Different row counts tell which database answered.
+  insertOrders(t, primary, 2)
+  insertOrders(t, replica, 1)
+
+  s := New(primary, database.SQLite)
+  s.SetReplica(replica)
+
+  total := func(ctx context.Context) int64 {
+    t.Helper()
+    n, _, err := s.ListRecentOrders(ctx, 1, 10)
+    if err != nil {
+      t.Fatalf("ListRecentOrders: %v", err)
+    }
+    return n
+  }
+
+  if got := total(context.Background()); got != 2 {
+    t.Errorf("fresh read total = %d, want 2 from primary", got)
+  }
+  if got := total(AllowStale(context.Background())); got != 1 {
+    t.Errorf("stale read total = %d, want 1 from replica", got)
+  }
+
+  replica.Close()
+  if got := total(AllowStale(context.Background())); got != 2 {
+    t.Errorf("stale read with replica down total = %d, want 2 from primary", got)
+  }
+  if s.replicaDownUntil.Load() == 0 {
+    t.Error("replica not marked down after failure")
+  }
+}
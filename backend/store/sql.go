@@ -0,0 +1,408 @@
+package store
+
+import (
+  "context"
+  "database/sql"
+  "errors"
+  "sync/atomic"
+  "time"
+
+  "sarah-project-backend/database"
+  "sarah-project-backend/dto"
+)
+
+const orderColumns = `
+  id, merchant_name, transaction_network, transaction_asset, txid, amount, email,
+  beneficiary_name, bank_country, bank_name, iban, swift, reference_note, status,
+  idempotency_key, created_at, updated_at
+`
+
+// SQL implements every store interface on top of database/sql. Queries are
+// portable between MySQL and SQLite except where noted per driver.
+type SQL struct {
+  db       *sql.DB
+  driver   database.Driver
+  timeouts Timeouts
+
+  // replica serves reads made with AllowStale; see replica.go.
+  replica          *sql.DB
+  replicaDownUntil atomic.Int64
+}
+
+// Timeouts bounds how long each kind of query may run.
+type Timeouts struct {
+  // Query applies to order reads and writes.
+  Query time.Duration
+  // Auth applies to API key, admin and catalogue lookups.
+  Auth time.Duration
+  // Payout applies to the payout batch transaction.
+  Payout time.Duration
+}
+
+// DefaultTimeouts are used until SetTimeouts is called.
+var DefaultTimeouts = Timeouts{
+  Query:  5 * time.Second,
+  Auth:   3 * time.Second,
+  Payout: 10 * time.Second,
+}
+
+// New returns a store using db, which must be opened with driver.
+func New(db *sql.DB, driver database.Driver) *SQL {
+  return &SQL{db: db, driver: driver, timeouts: DefaultTimeouts}
+}
+
+// SetTimeouts replaces the query timeouts.
+func (s *SQL) SetTimeouts(t Timeouts) {
+  s.timeouts = t
+}
+
+// CreateOrder inserts a new order and returns its ID.
+func (s *SQL) CreateOrder(ctx context.Context, order dto.OrderDTO) (int64, error) {
+  ctx, cancel := context.WithTimeout(ctx, s.timeouts.Query)
+  defer cancel()
+
+  result, err := s.db.ExecContext(ctx, `
+    INSERT INTO orders (
+      merchant_name,
+      transaction_network,
+      transaction_asset,
+      txid,
+      amount,
+      email,
+      beneficiary_name,
+      bank_country,
+      bank_name,
+      iban,
+      swift,
+      reference_note,
+      status,
+      idempotency_key
+    ) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+  `,
+    order.MerchantName,
+    order.TransactionNetwork,
+    order.TransactionAsset,
+    order.TXID,
+    order.Amount,
+    order.Email,
+    order.BeneficiaryName,
+    order.BankCountry,
+    order.BankName,
+    order.IBAN,
+    order.SWIFT,
+    order.ReferenceNote,
+    order.Status,
+    order.IdempotencyKey,
+  )
+  if err != nil {
+    return 0, err
+  }
+
+  return result.LastInsertId()
+}
+
+// ListMerchantOrders returns one page of a merchant's orders, newest first.
+func (s *SQL) ListMerchantOrders(ctx context.Context, merchantName string, page int, pageSize int) (int64, []dto.OrderDTO, error) {
+  var (
+    total  int64
+    orders []dto.OrderDTO
+  )
+  err := s.read(ctx, func(ctx context.Context, db *sql.DB) error {
+    if err := db.QueryRowContext(ctx, `
+      SELECT COUNT(*)
+      FROM orders
+      WHERE merchant_name = ?
+    `, merchantName).Scan(&total); err != nil {
+      return err
+    }
+
+    var err error
+    orders, err = queryOrders(ctx, db, `
+      SELECT `+orderColumns+`
+      FROM orders
+      WHERE merchant_name = ?
+      ORDER BY id DESC
+      LIMIT ? OFFSET ?
+    `, merchantName, pageSize, (page-1)*pageSize)
+    return err
+  })
+  if err != nil {
+    return 0, nil, err
+  }
+  return total, orders, nil
+}
+
+// GetMerchantOrder returns an order owned by merchantName.
+func (s *SQL) GetMerchantOrder(ctx context.Context, merchantName string, orderID int64) (dto.OrderDTO, error) {
+  ctx, cancel := context.WithTimeout(ctx, s.timeouts.Query)
+  defer cancel()
+
+  return scanOrder(s.db.QueryRowContext(ctx, `
+    SELECT `+orderColumns+`
+    FROM orders
+    WHERE merchant_name = ? AND id = ?
+    LIMIT 1
+  `, merchantName, orderID))
+}
+
+// GetOrderByIdempotencyKey returns the merchant's order created with key.
+func (s *SQL) GetOrderByIdempotencyKey(ctx context.Context, merchantName string, key string) (dto.OrderDTO, error) {
+  ctx, cancel := context.WithTimeout(ctx, s.timeouts.Query)
+  defer cancel()
+
+  return scanOrder(s.db.QueryRowContext(ctx, `
+    SELECT `+orderColumns+`
+    FROM orders
+    WHERE merchant_name = ? AND idempotency_key = ?
+    LIMIT 1
+  `, merchantName, key))
+}
+
+// GetOrder returns any order by ID.
+func (s *SQL) GetOrder(ctx context.Context, orderID int64) (dto.OrderDTO, error) {
+  ctx, cancel := context.WithTimeout(ctx, s.timeouts.Query)
+  defer cancel()
+
+  return scanOrder(s.db.QueryRowContext(ctx, `
+    SELECT `+orderColumns+`
+    FROM orders
+    WHERE id = ?
+    LIMIT 1
+  `, orderID))
+}
+
+// ListOrdersByStatus returns one page of orders in status, newest first.
+func (s *SQL) ListOrdersByStatus(ctx context.Context, status string, page int, pageSize int) (int64, []dto.OrderDTO, error) {
+  var (
+    total  int64
+    orders []dto.OrderDTO
+  )
+  err := s.read(ctx, func(ctx context.Context, db *sql.DB) error {
+    if err := db.QueryRowContext(ctx, `
+      SELECT COUNT(*) FROM orders WHERE status = ?
+    `, status).Scan(&total); err != nil {
+      return err
+    }
+
+    var err error
+    orders, err = queryOrders(ctx, db, `
+      SELECT `+orderColumns+`
+      FROM orders
+      WHERE status = ?
+      ORDER BY created_at DESC, id DESC
+      LIMIT ? OFFSET ?
+    `, status, pageSize, (page-1)*pageSize)
+    return err
+  })
+  if err != nil {
+    return 0, nil, err
+  }
+  return total, orders, nil
+}
+
+// ListRecentOrders returns one page of orders, most recently updated first.
+func (s *SQL) ListRecentOrders(ctx context.Context, page int, pageSize int) (int64, []dto.OrderDTO, error) {
+  var (
+    total  int64
+    orders []dto.OrderDTO
+  )
+  err := s.read(ctx, func(ctx context.Context, db *sql.DB) error {
+    if err := db.QueryRowContext(ctx, `
+      SELECT COUNT(*) FROM orders
+    `).Scan(&total); err != nil {
+      return err
+    }
+
+    var err error
+    orders, err = queryOrders(ctx, db, `
+      SELECT `+orderColumns+`
+      FROM orders
+      ORDER BY updated_at DESC, id DESC
+      LIMIT ? OFFSET ?
+    `, pageSize, (page-1)*pageSize)
+    return err
+  })
+  if err != nil {
+    return 0, nil, err
+  }
+  return total, orders, nil
+}
+
+// UpdateOrderStatus sets an order's status and returns the updated order.
+func (s *SQL) UpdateOrderStatus(ctx context.Context, orderID int64, status string) (dto.OrderDTO, error) {
+  ctx, cancel := context.WithTimeout(ctx, s.timeouts.Query)
+  defer cancel()
+
+  if _, err := s.db.ExecContext(ctx, `
+    UPDATE orders
+    SET status = ?, updated_at = CURRENT_TIMESTAMP
+    WHERE id = ?
+  `, status, orderID); err != nil {
+    return dto.OrderDTO{}, err
+  }
+
+  // MySQL reports zero affected rows when nothing changed, so a
+  // missing order is detected by the read instead.
+  return s.GetOrder(ctx, orderID)
+}
+
+// OrderStats counts orders per dashboard bucket. CompletedToday counts paid
+// orders created on day.
+func (s *SQL) OrderStats(ctx context.Context, day time.Time) (OrderStats, error) {
+  // SQLite stores CURRENT_TIMESTAMP in UTC; MySQL uses the session time zone.
+  createdDate := "DATE(created_at)"
+  if s.driver == database.SQLite {
+    createdDate = "DATE(created_at, 'localtime')"
+  }
+
+  stats := OrderStats{}
+  queries := []struct {
+    sql    string
+    args   []any
+    target *int64
+  }{
+    {`SELECT COUNT(*) FROM orders WHERE status = ?`, []any{dto.StatusFundsReceived}, &stats.FundsReceived},
+    {`SELECT COUNT(*) FROM orders WHERE status = ?`, []any{dto.StatusProcessing}, &stats.Processing},
+    {`SELECT COUNT(*) FROM orders WHERE status = ?`, []any{dto.StatusFailed}, &stats.ActionRequired},
+    {`SELECT COUNT(*) FROM orders WHERE status = ?`, []any{dto.StatusSubmitted}, &stats.Awaiting},
+    {`SELECT COUNT(*) FROM orders WHERE status = ? AND ` + createdDate + ` = ?`, []any{dto.StatusPaid, day.Format("2006-01-02")}, &stats.CompletedToday},
+  }
+
+  err := s.read(ctx, func(ctx context.Context, db *sql.DB) error {
+    for _, q := range queries {
+      if err := db.QueryRowContext(ctx, q.sql, q.args...).Scan(q.target); err != nil {
+        return err
+      }
+    }
+    return nil
+  })
+  if err != nil {
+    return OrderStats{}, err
+  }
+  return stats, nil
+}
+
+// MerchantByAPIKey returns the merchant name for an active API key.
+func (s *SQL) MerchantByAPIKey(ctx context.Context, apiKey string, merchantName string) (string, error) {
+  ctx, cancel := context.WithTimeout(ctx, s.timeouts.Auth)
+  defer cancel()
+
+  var matched string
+  err := s.db.QueryRowContext(ctx, `
+    SELECT merchant_name
+    FROM customer_api_keys
+    WHERE api_key = ? AND merchant_name = ? AND active = 1
+    LIMIT 1
+  `, apiKey, merchantName).Scan(&matched)
+  if err != nil {
+    return "", notFound(err)
+  }
+  return matched, nil
+}
+
+// DeactivateAPIKey revokes the merchant's active API key.
+func (s *SQL) DeactivateAPIKey(ctx context.Context, merchantName string) error {
+  ctx, cancel := context.WithTimeout(ctx, s.timeouts.Query)
+  defer cancel()
+
+  res, err := s.db.ExecContext(ctx, `
+    UPDATE customer_api_keys
+    SET active = 0, updated_at = CURRENT_TIMESTAMP
+    WHERE merchant_name = ? AND active = 1
+  `, merchantName)
+  if err != nil {
+    return err
+  }
+  n, err := res.RowsAffected()
+  if err != nil {
+    return err
+  }
+  if n == 0 {
+    return ErrNotFound
+  }
+  return nil
+}
+
+// AdminByUsername returns the admin account with the given username.
+func (s *SQL) AdminByUsername(ctx context.Context, username string) (dto.AdminDTO, error) {
+  ctx, cancel := context.WithTimeout(ctx, s.timeouts.Auth)
+  defer cancel()
+
+  var admin dto.AdminDTO
+  row := s.db.QueryRowContext(ctx, `
+    SELECT id, username, email, password_hash, created_at, updated_at
+    FROM admin_users
+    WHERE username = ?
+    LIMIT 1
+  `, username)
+  if err := row.Scan(
+    &admin.ID,
+    &admin.Username,
+    &admin.Email,
+    &admin.PasswordHash,
+    &admin.CreatedAt,
+    &admin.UpdatedAt,
+  ); err != nil {
+    return dto.AdminDTO{}, notFound(err)
+  }
+  return admin, nil
+}
+
+type rowScanner interface {
+  Scan(dest ...any) error
+}
+
+func scanOrder(row rowScanner) (dto.OrderDTO, error) {
+  var order dto.OrderDTO
+  if err := row.Scan(
+    &order.ID,
+    &order.MerchantName,
+    &order.TransactionNetwork,
+    &order.TransactionAsset,
+    &order.TXID,
+    &order.Amount,
+    &order.Email,
+    &order.BeneficiaryName,
+    &order.BankCountry,
+    &order.BankName,
+    &order.IBAN,
+    &order.SWIFT,
+    &order.ReferenceNote,
+    &order.Status,
+    &order.IdempotencyKey,
+    &order.CreatedAt,
+    &order.UpdatedAt,
+  ); err != nil {
+    return dto.OrderDTO{}, notFound(err)
+  }
+  return order, nil
+}
+
+func queryOrders(ctx context.Context, db *sql.DB, query string, args ...any) ([]dto.OrderDTO, error) {
+  rows, err := db.QueryContext(ctx, query, args...)
+  if err != nil {
+    return nil, err
+  }
+  defer rows.Close()
+
+  orders := make([]dto.OrderDTO, 0)
+  for rows.Next() {
+    order, err := scanOrder(rows)
+    if err != nil {
+      return nil, err
+    }
+    orders = append(orders, order)
+  }
+  if err := rows.Err(); err != nil {
+    return nil, err
+  }
+  return orders, nil
+}
+
+// notFound maps sql.ErrNoRows to ErrNotFound.
+func notFound(err error) error {
+  if errors.Is(err, sql.ErrNoRows) {
+    return ErrNotFound
+  }
+  return err
+}
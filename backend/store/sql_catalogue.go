@@ -0,0 +1,158 @@
+package store
+
+import (
+  "context"
+
+  "sarah-project-backend/database"
+  "sarah-project-backend/dto"
+)
+
+// LoadCatalogue returns configured assets and payout countries.
+func (s *SQL) LoadCatalogue(ctx context.Context, enabledOnly bool) (Catalogue, error) {
+  ctx, cancel := context.WithTimeout(ctx, s.timeouts.Auth)
+  defer cancel()
+
+  filter := ""
+  if enabledOnly {
+    filter = "WHERE enabled = 1"
+  }
+
+  rows, err := s.db.QueryContext(ctx, `
+    SELECT id, network, asset, contract_address, decimals, min_amount, max_amount, enabled, created_at, updated_at
+    FROM catalogue_assets
+    `+filter+`
+    ORDER BY network, asset
+  `)
+  if err != nil {
+    return Catalogue{}, err
+  }
+  defer rows.Close()
+
+  cat := Catalogue{
+    Assets:    make([]dto.CatalogueAssetDTO, 0),
+    Countries: make([]dto.PayoutCountryDTO, 0),
+  }
+  for rows.Next() {
+    var asset dto.CatalogueAssetDTO
+    if err := rows.Scan(
+      &asset.ID,
+      &asset.Network,
+      &asset.Asset,
+      &asset.ContractAddress,
+      &asset.Decimals,
+      &asset.MinAmount,
+      &asset.MaxAmount,
+      &asset.Enabled,
+      &asset.CreatedAt,
+      &asset.UpdatedAt,
+    ); err != nil {
+      return Catalogue{}, err
+    }
+    cat.Assets = append(cat.Assets, asset)
+  }
+  if err := rows.Err(); err != nil {
+    return Catalogue{}, err
+  }
+
+  countryRows, err := s.db.QueryContext(ctx, `
+    SELECT id, name, iso_code, currency, enabled, created_at, updated_at
+    FROM payout_countries
+    `+filter+`
+    ORDER BY name
+  `)
+  if err != nil {
+    return Catalogue{}, err
+  }
+  defer countryRows.Close()
+
+  for countryRows.Next() {
+    var country dto.PayoutCountryDTO
+    if err := countryRows.Scan(
+      &country.ID,
+      &country.Name,
+      &country.ISOCode,
+      &country.Currency,
+      &country.Enabled,
+      &country.CreatedAt,
+      &country.UpdatedAt,
+    ); err != nil {
+      return Catalogue{}, err
+    }
+    cat.Countries = append(cat.Countries, country)
+  }
+  if err := countryRows.Err(); err != nil {
+    return Catalogue{}, err
+  }
+
+  return cat, nil
+}
+
+// UpsertCatalogueAsset creates or updates a (network, asset) pair.
+func (s *SQL) UpsertCatalogueAsset(ctx context.Context, asset dto.CatalogueAssetDTO) error {
+  ctx, cancel := context.WithTimeout(ctx, s.timeouts.Auth)
+  defer cancel()
+
+  query := `
+    INSERT INTO catalogue_assets (network, asset, contract_address, decimals, min_amount, max_amount, enabled)
+    VALUES (?, ?, ?, ?, ?, ?, ?)
+    ON DUPLICATE KEY UPDATE
+      contract_address = VALUES(contract_address),
+      decimals = VALUES(decimals),
+      min_amount = VALUES(min_amount),
+      max_amount = VALUES(max_amount),
+      enabled = VALUES(enabled)
+  `
+  if s.driver == database.SQLite {
+    query = `
+      INSERT INTO catalogue_assets (network, asset, contract_address, decimals, min_amount, max_amount, enabled)
+      VALUES (?, ?, ?, ?, ?, ?, ?)
+      ON CONFLICT (network, asset) DO UPDATE SET
+        contract_address = excluded.contract_address,
+        decimals = excluded.decimals,
+        min_amount = excluded.min_amount,
+        max_amount = excluded.max_amount,
+        enabled = excluded.enabled,
+        updated_at = CURRENT_TIMESTAMP
+    `
+  }
+
+  _, err := s.db.ExecContext(ctx, query,
+    asset.Network,
+    asset.Asset,
+    asset.ContractAddress,
+    asset.Decimals,
+    asset.MinAmount,
+    asset.MaxAmount,
+    asset.Enabled,
+  )
+  return err
+}
+
+// UpsertPayoutCountry creates or updates a payout country.
+func (s *SQL) UpsertPayoutCountry(ctx context.Context, country dto.PayoutCountryDTO) error {
+  ctx, cancel := context.WithTimeout(ctx, s.timeouts.Auth)
+  defer cancel()
+
+  query := `
+    INSERT INTO payout_countries (name, iso_code, currency, enabled)
+    VALUES (?, ?, ?, ?)
+    ON DUPLICATE KEY UPDATE
+      iso_code = VALUES(iso_code),
+      currency = VALUES(currency),
+      enabled = VALUES(enabled)
+  `
+  if s.driver == database.SQLite {
+    query = `
+      INSERT INTO payout_countries (name, iso_code, currency, enabled)
+      VALUES (?, ?, ?, ?)
+      ON CONFLICT (name) DO UPDATE SET
+        iso_code = excluded.iso_code,
+        currency = excluded.currency,
+        enabled = excluded.enabled,
+        updated_at = CURRENT_TIMESTAMP
+    `
+  }
+
+  _, err := s.db.ExecContext(ctx, query, country.Name, country.ISOCode, country.Currency, country.Enabled)
+  return err
+}
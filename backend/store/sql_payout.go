@@ -0,0 +1,210 @@
+package store
+
+import (
+  "context"
+  "database/sql"
+  "fmt"
+  "strings"
+
+  "sarah-project-backend/database"
+  "sarah-project-backend/dto"
+  "sarah-project-backend/payout"
+)
+
+// CreatePayoutBatch locks the orders, renders the file through build and
+// records the batch, all in a single transaction.
+func (s *SQL) CreatePayoutBatch(ctx context.Context, batch dto.PayoutBatchDTO, orderIDs []int64, build PayoutFileBuilder) (dto.PayoutBatchDTO, error) {
+  ctx, cancel := context.WithTimeout(ctx, s.timeouts.Payout)
+  defer cancel()
+
+  tx, err := s.db.BeginTx(ctx, nil)
+  if err != nil {
+    return dto.PayoutBatchDTO{}, err
+  }
+  defer tx.Rollback()
+
+  placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(orderIDs)), ", ")
+  args := make([]any, 0, len(orderIDs))
+  for _, id := range orderIDs {
+    args = append(args, id)
+  }
+
+  payments, err := s.lockPayoutOrders(ctx, tx, placeholders, args)
+  if err != nil {
+    return dto.PayoutBatchDTO{}, err
+  }
+  if err := checkAllFound(orderIDs, payments); err != nil {
+    return dto.PayoutBatchDTO{}, err
+  }
+
+  res, err := tx.ExecContext(ctx, `
+    INSERT INTO payout_batches (batch_id, format, created_by)
+    VALUES (?, ?, ?)
+  `, batch.BatchID, batch.Format, batch.CreatedBy)
+  if err != nil {
+    return dto.PayoutBatchDTO{}, err
+  }
+  sequence, err := res.LastInsertId()
+  if err != nil {
+    return dto.PayoutBatchDTO{}, err
+  }
+
+  file, err := build(sequence, payments)
+  if err != nil {
+    return dto.PayoutBatchDTO{}, err
+  }
+
+  if _, err := tx.ExecContext(ctx, `
+    UPDATE payout_batches
+    SET file_name = ?, order_count = ?, total_amount = ?, content = ?, updated_at = CURRENT_TIMESTAMP
+    WHERE id = ?
+  `, file.Name, len(payments), file.Total, string(file.Content), sequence); err != nil {
+    return dto.PayoutBatchDTO{}, err
+  }
+
+  for _, p := range payments {
+    if _, err := tx.ExecContext(ctx, `
+      INSERT INTO payout_batch_orders (batch_id, order_id)
+      VALUES (?, ?)
+    `, batch.BatchID, p.OrderID); err != nil {
+      return dto.PayoutBatchDTO{}, err
+    }
+  }
+
+  updateArgs := append([]any{dto.StatusSubmitted}, args...)
+  if _, err := tx.ExecContext(ctx, `
+    UPDATE orders
+    SET status = ?, updated_at = CURRENT_TIMESTAMP
+    WHERE id IN (`+placeholders+`)
+  `, updateArgs...); err != nil {
+    return dto.PayoutBatchDTO{}, err
+  }
+
+  if err := tx.Commit(); err != nil {
+    return dto.PayoutBatchDTO{}, err
+  }
+
+  batch.ID = sequence
+  batch.FileName = file.Name
+  batch.OrderCount = len(payments)
+  batch.TotalAmount = file.Total
+  batch.Content = string(file.Content)
+  return batch, nil
+}
+
+// GetPayoutBatch returns a generated batch by its public batch ID.
+func (s *SQL) GetPayoutBatch(ctx context.Context, batchID string) (dto.PayoutBatchDTO, error) {
+  ctx, cancel := context.WithTimeout(ctx, s.timeouts.Query)
+  defer cancel()
+
+  var (
+    batch   dto.PayoutBatchDTO
+    content sql.NullString
+  )
+  row := s.db.QueryRowContext(ctx, `
+    SELECT id, batch_id, format, file_name, order_count, total_amount, content, created_by, created_at, updated_at
+    FROM payout_batches
+    WHERE batch_id = ?
+    LIMIT 1
+  `, batchID)
+  if err := row.Scan(
+    &batch.ID,
+    &batch.BatchID,
+    &batch.Format,
+    &batch.FileName,
+    &batch.OrderCount,
+    &batch.TotalAmount,
+    &content,
+    &batch.CreatedBy,
+    &batch.CreatedAt,
+    &batch.UpdatedAt,
+  ); err != nil {
+    return dto.PayoutBatchDTO{}, notFound(err)
+  }
+  batch.Content = content.String
+  return batch, nil
+}
+
+// lockPayoutOrders reads the requested orders. MySQL locks the rows with
+// FOR UPDATE; SQLite transactions already hold the database write lock.
+func (s *SQL) lockPayoutOrders(ctx context.Context, tx *sql.Tx, placeholders string, args []any) ([]payout.Payment, error) {
+  lock := "FOR UPDATE"
+  if s.driver == database.SQLite {
+    lock = ""
+  }
+
+  rows, err := tx.QueryContext(ctx, `
+    SELECT o.id, o.amount, o.beneficiary_name, o.bank_country, c.iso_code, c.currency,
+           o.bank_name, o.iban, o.swift, o.reference_note, o.status
+    FROM orders o
+    LEFT JOIN payout_countries c ON c.name = o.bank_country
+    WHERE o.id IN (`+placeholders+`)
+    ORDER BY o.id
+    `+lock+`
+  `, args...)
+  if err != nil {
+    return nil, err
+  }
+  defer rows.Close()
+
+  payments := make([]payout.Payment, 0, len(args))
+  for rows.Next() {
+    var (
+      amount      sql.NullFloat64
+      countryCode sql.NullString
+      currency    sql.NullString
+      note        sql.NullString
+      status      string
+      p           payout.Payment
+    )
+    if err := rows.Scan(
+      &p.OrderID,
+      &amount,
+      &p.BeneficiaryName,
+      &p.BankCountry,
+      &countryCode,
+      &currency,
+      &p.BankName,
+      &p.Account,
+      &p.Routing,
+      &note,
+      &status,
+    ); err != nil {
+      return nil, err
+    }
+    if status != dto.StatusFundsReceived {
+      return nil, ConflictError{Message: fmt.Sprintf("order %d is not in Funds Received status", p.OrderID)}
+    }
+    if !amount.Valid {
+      return nil, payout.PaymentError{OrderID: p.OrderID, Reason: "amount is missing"}
+    }
+    p.Amount = amount.Float64
+    p.CountryCode = countryCode.String
+    p.Currency = currency.String
+    if note.Valid {
+      p.Reference = note.String
+    }
+    payments = append(payments, p)
+  }
+  if err := rows.Err(); err != nil {
+    return nil, err
+  }
+  return payments, nil
+}
+
+// checkAllFound reports the first requested order missing from payments.
+func checkAllFound(orderIDs []int64, payments []payout.Payment) error {
+  if len(payments) == len(orderIDs) {
+    return nil
+  }
+  found := make(map[int64]bool, len(payments))
+  for _, p := range payments {
+    found[p.OrderID] = true
+  }
+  for _, id := range orderIDs {
+    if !found[id] {
+      return ConflictError{Message: fmt.Sprintf("order %d not found", id)}
+    }
+  }
+  return nil
+}
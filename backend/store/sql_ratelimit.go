@@ -0,0 +1,95 @@
+package store
+
+import (
+  "context"
+  "time"
+
+  "sarah-project-backend/database"
+  "sarah-project-backend/ratelimit"
+)
+
+// MerchantRateLimit returns the quota configured for merchantName.
+func (s *SQL) MerchantRateLimit(ctx context.Context, merchantName string) (ratelimit.Limit, error) {
+  ctx, cancel := context.WithTimeout(ctx, s.timeouts.Auth)
+  defer cancel()
+
+  var limit ratelimit.Limit
+  err := s.db.QueryRowContext(ctx, `
+    SELECT requests_per_minute, burst
+    FROM merchant_rate_limits
+    WHERE merchant_name = ?
+  `, merchantName).Scan(&limit.PerMinute, &limit.Burst)
+  if err != nil {
+    return ratelimit.Limit{}, notFound(err)
+  }
+  return limit, nil
+}
+
+// TakeRateLimitToken updates the bucket for key in one transaction. The row
+// is upserted first so concurrent callers serialise on its lock rather than
+// racing to create it. On MySQL the no-op ON DUPLICATE KEY UPDATE takes an
+// exclusive lock on an existing row; INSERT IGNORE would only take a shared
+// one, and two callers upgrading it for the SELECT deadlock.
+func (s *SQL) TakeRateLimitToken(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+  ctx, cancel := context.WithTimeout(ctx, s.timeouts.Auth)
+  defer cancel()
+
+  upsert := `
+    INSERT INTO rate_limit_buckets (bucket_key, tokens, updated_at_ms)
+    VALUES (?, ?, ?)
+    ON DUPLICATE KEY UPDATE bucket_key = bucket_key
+  `
+  lock := `FOR UPDATE`
+  if s.driver == database.SQLite {
+    upsert = `
+      INSERT INTO rate_limit_buckets (bucket_key, tokens, updated_at_ms)
+      VALUES (?, ?, ?)
+      ON CONFLICT (bucket_key) DO NOTHING
+    `
+    lock = ``
+  }
+
+  tx, err := s.db.BeginTx(ctx, nil)
+  if err != nil {
+    return ratelimit.Result{}, err
+  }
+  defer tx.Rollback()
+
+  now := time.Now()
+  if _, err := tx.ExecContext(ctx, upsert, key, max(limit.Burst, 1), now.UnixMilli()); err != nil {
+    return ratelimit.Result{}, err
+  }
+
+  var tokens float64
+  var updatedMS int64
+  if err := tx.QueryRowContext(ctx, `
+    SELECT tokens, updated_at_ms
+    FROM rate_limit_buckets
+    WHERE bucket_key = ?
+    `+lock, key).Scan(&tokens, &updatedMS); err != nil {
+    return ratelimit.Result{}, err
+  }
+
+  tokens, res := ratelimit.Take(tokens, time.UnixMilli(updatedMS), now, limit)
+  if _, err := tx.ExecContext(ctx, `
+    UPDATE rate_limit_buckets SET tokens = ?, updated_at_ms = ? WHERE bucket_key = ?
+  `, tokens, now.UnixMilli(), key); err != nil {
+    return ratelimit.Result{}, err
+  }
+  if err := tx.Commit(); err != nil {
+    return ratelimit.Result{}, err
+  }
+  return res, nil
+}
+
+// PruneRateLimitBuckets deletes buckets not updated since before.
+func (s *SQL) PruneRateLimitBuckets(ctx context.Context, before time.Time) (int64, error) {
+  ctx, cancel := context.WithTimeout(ctx, s.timeouts.Query)
+  defer cancel()
+
+  res, err := s.db.ExecContext(ctx, `DELETE FROM rate_limit_buckets WHERE updated_at_ms < ?`, before.UnixMilli())
+  if err != nil {
+    return 0, err
+  }
+  return res.RowsAffected()
+}
@@ -0,0 +1,82 @@
+package store
+
+import (
+  "context"
+  "errors"
+  "sync"
+  "sync/atomic"
+  "testing"
+  "time"
+
+  "sarah-project-backend/database"
+  "sarah-project-backend/ratelimit"
+)
+
+func TestSQLRateLimit(t *testing.T) {
+  db := openMigrated(t)
+  s := New(db, database.SQLite)
+  ctx := context.Background()
+
+  if _, err := s.MerchantRateLimit(ctx, "acme"); !errors.Is(err, ErrNotFound) {
+    t.Fatalf("MerchantRateLimit without a row: err = %v, want ErrNotFound", err)
+  }
+  if _, err := db.Exec(`INSERT INTO merchant_rate_limits (merchant_name, requests_per_minute, burst) VALUES ('acme', 30, 2)`); err != nil {
+    t.Fatal(err)
+  }
+  limit, err := s.MerchantRateLimit(ctx, "acme")
+  if err != nil || limit != (ratelimit.Limit{PerMinute: 30, Burst: 2}) {
+    t.Fatalf("MerchantRateLimit = %+v, %v", limit, err)
+  }
+
+  for i, wantAllowed := range []bool{true, true, false} {
+    res, err := s.TakeRateLimitToken(ctx, "merchant:acme", limit)
+    if err != nil {
+      t.Fatal(err)
+    }
+    if res.Allowed != wantAllowed {
+      t.Fatalf("take %d = %+v, want allowed %v", i, res, wantAllowed)
+    }
+  }
+
+  n, err := s.PruneRateLimitBuckets(ctx, time.Now().Add(time.Minute))
+  if err != nil || n != 1 {
+    t.Fatalf("PruneRateLimitBuckets = %d, %v, want 1 bucket removed", n, err)
+  }
+  if res, err := s.TakeRateLimitToken(ctx, "merchant:acme", limit); err != nil || !res.Allowed {
+    t.Fatalf("take after prune = %+v, %v, want a fresh bucket", res, err)
+  }
+}
+
+func TestSQLRateLimitConcurrent(t *testing.T) {
+  s := New(openMigrated(t), database.SQLite)
+  ctx := context.Background()
+  limit := ratelimit.Limit{PerMinute: 1, Burst: 5}
+
+  var (
+    wg      sync.WaitGroup
+    allowed atomic.Int64
+    errs    = make(chan error, 50)
+  )
+  for i := 0; i < 50; i++ {
+    wg.Add(1)
+    go func() {
+      defer wg.Done()
+      res, err := s.TakeRateLimitToken(ctx, "merchant:busy", limit)
+      if err != nil {
+        errs <- err
+        return
+      }
+      if res.Allowed {
+        allowed.Add(1)
+      }
+    }()
+  }
+  wg.Wait()
+  close(errs)
+  for err := range errs {
+    t.Errorf("TakeRateLimitToken: %v", err)
+  }
+  if got := allowed.Load(); got != int64(limit.Burst) {
+    t.Fatalf("%d of 50 concurrent requests allowed, want the burst of %d", got, limit.Burst)
+  }
+}
@@ -0,0 +1,159 @@
+package store
+
+import (
+  "context"
+  "errors"
+  "time"
+
+  "sarah-project-backend/dto"
+  "sarah-project-backend/payout"
+  "sarah-project-backend/ratelimit"
+)
+
+// ErrNotFound is returned when a requested record does not exist.
+var ErrNotFound = errors.New("not found")
+
+// ConflictError reports a request that cannot be applied to the current
+// state of a record, such as exporting an order that was already paid out.
+type ConflictError struct {
+  Message string
+}
+
+func (e ConflictError) Error() string {
+  return e.Message
+}
+
+// OrderStats holds the order counters shown on the admin dashboard.
+type OrderStats struct {
+  FundsReceived  int64
+  Processing     int64
+  ActionRequired int64
+  Awaiting       int64
+  CompletedToday int64
+}
+
+// Catalogue lists configured assets and payout countries.
+type Catalogue struct {
+  Assets    []dto.CatalogueAssetDTO
+  Countries []dto.PayoutCountryDTO
+}
+
+// FindAsset returns the enabled entry for a network and asset.
+func (c Catalogue) FindAsset(network string, asset string) (dto.CatalogueAssetDTO, bool) {
+  for _, a := range c.Assets {
+    if a.Enabled && a.Network == network && a.Asset == asset {
+      return a, true
+    }
+  }
+  return dto.CatalogueAssetDTO{}, false
+}
+
+// HasNetwork reports whether any enabled asset uses network.
+func (c Catalogue) HasNetwork(network string) bool {
+  for _, a := range c.Assets {
+    if a.Enabled && a.Network == network {
+      return true
+    }
+  }
+  return false
+}
+
+// HasAsset reports whether asset is enabled on any network.
+func (c Catalogue) HasAsset(asset string) bool {
+  for _, a := range c.Assets {
+    if a.Enabled && a.Asset == asset {
+      return true
+    }
+  }
+  return false
+}
+
+// FindCountry returns the enabled payout country with the given name.
+func (c Catalogue) FindCountry(name string) (dto.PayoutCountryDTO, bool) {
+  for _, country := range c.Countries {
+    if country.Enabled && country.Name == name {
+      return country, true
+    }
+  }
+  return dto.PayoutCountryDTO{}, false
+}
+
+// OrderStore reads and writes orders.
+type OrderStore interface {
+  CreateOrder(ctx context.Context, order dto.OrderDTO) (int64, error)
+  ListMerchantOrders(ctx context.Context, merchantName string, page int, pageSize int) (int64, []dto.OrderDTO, error)
+  GetMerchantOrder(ctx context.Context, merchantName string, orderID int64) (dto.OrderDTO, error)
+  // GetOrderByIdempotencyKey returns the merchant's order created with key,
+  // or ErrNotFound.
+  GetOrderByIdempotencyKey(ctx context.Context, merchantName string, key string) (dto.OrderDTO, error)
+  GetOrder(ctx context.Context, orderID int64) (dto.OrderDTO, error)
+  // ListOrdersByStatus returns orders in status, newest created first.
+  ListOrdersByStatus(ctx context.Context, status string, page int, pageSize int) (int64, []dto.OrderDTO, error)
+  // ListRecentOrders returns all orders, most recently updated first.
+  ListRecentOrders(ctx context.Context, page int, pageSize int) (int64, []dto.OrderDTO, error)
+  UpdateOrderStatus(ctx context.Context, orderID int64, status string) (dto.OrderDTO, error)
+  OrderStats(ctx context.Context, day time.Time) (OrderStats, error)
+}
+
+// MerchantStore authenticates and revokes merchant API keys.
+type MerchantStore interface {
+  // MerchantByAPIKey returns the merchant name for an active key, or ErrNotFound.
+  MerchantByAPIKey(ctx context.Context, apiKey string, merchantName string) (string, error)
+  // DeactivateAPIKey revokes the merchant's active key, or returns
+  // ErrNotFound when it has none.
+  DeactivateAPIKey(ctx context.Context, merchantName string) error
+}
+
+// AdminStore reads admin accounts.
+type AdminStore interface {
+  AdminByUsername(ctx context.Context, username string) (dto.AdminDTO, error)
+}
+
+// CatalogueStore manages supported assets and payout countries.
+type CatalogueStore interface {
+  LoadCatalogue(ctx context.Context, enabledOnly bool) (Catalogue, error)
+  UpsertCatalogueAsset(ctx context.Context, asset dto.CatalogueAssetDTO) error
+  UpsertPayoutCountry(ctx context.Context, country dto.PayoutCountryDTO) error
+}
+
+// PayoutFileBuilder renders the payment file for a batch once its sequence
+// number is known.
+type PayoutFileBuilder func(sequence int64, payments []payout.Payment) (payout.File, error)
+
+// PayoutStore records payout batches.
+type PayoutStore interface {
+  // CreatePayoutBatch locks the given Funds Received orders, builds the file,
+  // stores the batch and moves the orders to Submitted in one transaction.
+  CreatePayoutBatch(ctx context.Context, batch dto.PayoutBatchDTO, orderIDs []int64, build PayoutFileBuilder) (dto.PayoutBatchDTO, error)
+  GetPayoutBatch(ctx context.Context, batchID string) (dto.PayoutBatchDTO, error)
+}
+
+// RateLimitStore holds per-merchant quotas and token buckets shared between
+// instances.
+type RateLimitStore interface {
+  // MerchantRateLimit returns the merchant's quota, or ErrNotFound when the
+  // default applies.
+  MerchantRateLimit(ctx context.Context, merchantName string) (ratelimit.Limit, error)
+  // TakeRateLimitToken takes a token from the bucket for key, creating it
+  // full if needed.
+  TakeRateLimitToken(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error)
+  // PruneRateLimitBuckets deletes buckets untouched since before and
+  // returns how many were removed.
+  PruneRateLimitBuckets(ctx context.Context, before time.Time) (int64, error)
+}
+
+// Store combines every store interface. Handlers depend on the narrower
+// interfaces; Store is what the application wires up.
+type Store interface {
+  OrderStore
+  MerchantStore
+  AdminStore
+  CatalogueStore
+  PayoutStore
+  RateLimitStore
+}
+
+var (
+  _ Store = (*SQL)(nil)
+  _ Store = (*Memory)(nil)
+)
@@ -0,0 +1,85 @@
+// Package tracing configures OpenTelemetry trace export and W3C trace
+// context propagation.
+package tracing
+
+import (
+  "context"
+  "fmt"
+  "io"
+
+  "go.opentelemetry.io/otel"
+  "go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+  "go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+  "go.opentelemetry.io/otel/propagation"
+  "go.opentelemetry.io/otel/sdk/resource"
+  sdktrace "go.opentelemetry.io/otel/sdk/trace"
+  semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// Exporters accepted in Config.Exporter.
+const (
+  ExporterNone   = "none"
+  ExporterOTLP   = "otlp"
+  ExporterStdout = "stdout"
+)
+
+// Config selects where spans go.
+type Config struct {
+  // Exporter is none, otlp or stdout.
+  Exporter string
+  // Endpoint is the OTLP/HTTP traces URL, e.g.
+  // http://otel-collector:4318/v1/traces. When empty the exporter falls back
+  // to OTEL_EXPORTER_OTLP_* variables and then localhost:4318.
+  Endpoint string
+  // SampleRatio is the fraction of new traces recorded. Requests that arrive
+  // with a trace context follow the caller's sampling decision.
+  SampleRatio float64
+  ServiceName string
+}
+
+// Setup installs the global tracer provider and propagators. The returned
+// function flushes buffered spans and must be called before exit. Spans are
+// written to stdout when the stdout exporter is selected.
+func Setup(ctx context.Context, cfg Config, stdout io.Writer) (func(context.Context) error, error) {
+  otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+    propagation.TraceContext{},
+    propagation.Baggage{},
+  ))
+
+  var exporter sdktrace.SpanExporter
+  var err error
+  switch cfg.Exporter {
+  case ExporterNone, "":
+    return func(context.Context) error { return nil }, nil
+  case ExporterOTLP:
+    var opts []otlptracehttp.Option
+    if cfg.Endpoint != "" {
+      opts = append(opts, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+    }
+    exporter, err = otlptracehttp.New(ctx, opts...)
+  case ExporterStdout:
+    exporter, err = stdouttrace.New(stdouttrace.WithWriter(stdout))
+  default:
+    return nil, fmt.Errorf("unknown exporter %q", cfg.Exporter)
+  }
+  if err != nil {
+    return nil, fmt.Errorf("create %s exporter: %w", cfg.Exporter, err)
+  }
+
+  res, err := resource.New(ctx,
+    resource.WithFromEnv(),
+    resource.WithTelemetrySDK(),
+    resource.WithAttributes(semconv.ServiceName(cfg.ServiceName)),
+  )
+  if err != nil {
+    return nil, fmt.Errorf("tracing resource: %w", err)
+  }
+
+  provider := sdktrace.NewTracerProvider(
+    sdktrace.WithBatcher(exporter),
+    sdktrace.WithResource(res),
+    sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+  )
+  otel.SetTracerProvider(provider)
+  return provider.Shutdown, nil
+}
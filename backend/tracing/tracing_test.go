@@ -0,0 +1,36 @@
+package tracing
+
+import (
+  "bytes"
+  "context"
+  "strings"
+  "testing"
+
+  "go.opentelemetry.io/otel"
+)
+
+func TestSetupStdout(t *testing.T) {
+  var buf bytes.Buffer
+  shutdown, err := Setup(context.Background(), Config{Exporter: ExporterStdout, SampleRatio: 1, ServiceName: "test"}, &buf)
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  _, span := otel.Tracer("test").Start(context.Background(), "unit-of-work")
+  span.End()
+  if err := shutdown(context.Background()); err != nil {
+    t.Fatal(err)
+  }
+
+  for _, want := range []string{`"Name":"unit-of-work"`, `"Value":"test"`} {
+    if !strings.Contains(buf.String(), want) {
+      t.Errorf("exported spans missing %s:\n%s", want, buf.String())
+    }
+  }
+}
+
+func TestSetupRejectsUnknownExporter(t *testing.T) {
+  if _, err := Setup(context.Background(), Config{Exporter: "zipkin"}, nil); err == nil {
+    t.Error("unknown exporter accepted")
+  }
+}
@@ -0,0 +1,152 @@
+package validation
+
+import (
+  "math/big"
+  "regexp"
+  "strings"
+)
+
+var (
+  bicPattern  = regexp.MustCompile(`^[A-Z]{4}[A-Z]{2}[A-Z0-9]{2}([A-Z0-9]{3})?$`)
+  ibanPattern = regexp.MustCompile(`^[A-Z]{2}[0-9]{2}[A-Z0-9]{11,30}$`)
+)
+
+// ibanLengths lists the registered IBAN length per country code.
+var ibanLengths = map[string]int{
+  "AD": 24, "AE": 23, "AT": 20, "BE": 16, "BG": 22, "BH": 22, "BR": 29,
+  "CH": 21, "CY": 28, "CZ": 24, "DE": 22, "DK": 18, "EE": 20, "ES": 24,
+  "FI": 18, "FR": 27, "GB": 22, "GI": 23, "GR": 27, "HR": 21, "HU": 28,
+  "IE": 22, "IL": 23, "IS": 26, "IT": 27, "LI": 21, "LT": 20, "LU": 20,
+  "LV": 21, "MC": 27, "MT": 31, "NL": 18, "NO": 15, "PL": 28, "PT": 25,
+  "QA": 29, "RO": 24, "SA": 24, "SE": 24, "SI": 19, "SK": 24, "SM": 27,
+  "TR": 26, "UA": 29,
+}
+
+// Normalize removes spaces and dashes and upper-cases bank identifiers.
+func Normalize(value string) string {
+  value = strings.ToUpper(strings.TrimSpace(value))
+  return strings.NewReplacer(" ", "", "-", "").Replace(value)
+}
+
+// IsBIC reports whether value is a well-formed SWIFT/BIC code (8 or 11 characters).
+func IsBIC(value string) bool {
+  return bicPattern.MatchString(Normalize(value))
+}
+
+// LooksLikeIBAN reports whether value has the shape of an IBAN, without
+// checking its checksum.
+func LooksLikeIBAN(value string) bool {
+  return ibanPattern.MatchString(Normalize(value))
+}
+
+// IsIBAN reports whether value is an IBAN with a valid length and mod-97 checksum.
+func IsIBAN(value string) bool {
+  iban := Normalize(value)
+  if !ibanPattern.MatchString(iban) {
+    return false
+  }
+  if length, ok := ibanLengths[iban[:2]]; ok && len(iban) != length {
+    return false
+  }
+
+  rearranged := iban[4:] + iban[:4]
+  var digits strings.Builder
+  for _, r := range rearranged {
+    switch {
+    case r >= '0' && r <= '9':
+      digits.WriteRune(r)
+    case r >= 'A' && r <= 'Z':
+      digits.WriteString(big.NewInt(int64(r - 'A' + 10)).String())
+    default:
+      return false
+    }
+  }
+
+  n, ok := new(big.Int).SetString(digits.String(), 10)
+  if !ok {
+    return false
+  }
+  return new(big.Int).Mod(n, big.NewInt(97)).Int64() == 1
+}
+
+// IsABARouting reports whether value is a 9 digit United States routing
+// number with a valid checksum. All zeros passes the checksum but is not
+// a routing number.
+func IsABARouting(value string) bool {
+  routing := strings.TrimSpace(value)
+  if len(routing) != 9 || !isDigits(routing) || isZeros(routing) {
+    return false
+  }
+  weights := []int{3, 7, 1, 3, 7, 1, 3, 7, 1}
+  sum := 0
+  for i, r := range routing {
+    sum += int(r-'0') * weights[i]
+  }
+  return sum%10 == 0
+}
+
+// CanadianRouting parses a Canadian institution (3 digits) and transit
+// (5 digits) pair written as "III-TTTTT", "TTTTT-III", "IIITTTTT" or the
+// electronic "0IIITTTTT" form, and returns the electronic form. An all-zero
+// institution or transit is rejected.
+func CanadianRouting(value string) (string, bool) {
+  routing, ok := parseCanadianRouting(value)
+  if !ok || isZeros(routing[1:4]) || isZeros(routing[4:]) {
+    return "", false
+  }
+  return routing, true
+}
+
+func parseCanadianRouting(value string) (string, bool) {
+  value = strings.TrimSpace(value)
+  if parts := strings.FieldsFunc(value, func(r rune) bool { return r == '-' || r == ' ' || r == '.' }); len(parts) == 2 {
+    switch {
+    case len(parts[0]) == 3 && len(parts[1]) == 5 && isDigits(parts[0]) && isDigits(parts[1]):
+      return "0" + parts[0] + parts[1], true
+    case len(parts[0]) == 5 && len(parts[1]) == 3 && isDigits(parts[0]) && isDigits(parts[1]):
+      return "0" + parts[1] + parts[0], true
+    case len(parts[0]) == 4 && parts[0][0] == '0' && len(parts[1]) == 5 && isDigits(parts[0]) && isDigits(parts[1]):
+      return parts[0] + parts[1], true
+    default:
+      return "", false
+    }
+  }
+
+  digits := Normalize(value)
+  switch {
+  case len(digits) == 8 && isDigits(digits):
+    return "0" + digits, true
+  case len(digits) == 9 && digits[0] == '0' && isDigits(digits):
+    return digits, true
+  default:
+    return "", false
+  }
+}
+
+// IsCanadianAccount reports whether value is a 7-12 digit Canadian account number.
+func IsCanadianAccount(value string) bool {
+  account := Normalize(value)
+  return len(account) >= 7 && len(account) <= 12 && isDigits(account)
+}
+
+// IsUSAccount reports whether value is a 4-17 digit United States account number.
+func IsUSAccount(value string) bool {
+  account := Normalize(value)
+  return len(account) >= 4 && len(account) <= 17 && isDigits(account)
+}
+
+func isZeros(value string) bool {
+  return strings.Trim(value, "0") == ""
+}
+
+func isDigits(value string) bool {
+  if value == "" {
+    return false
+  }
+  for _, r := range value {
+    if r < '0' || r > '9' {
+      return false
+    }
+  }
+  return true
+}
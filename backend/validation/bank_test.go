@@ -0,0 +1,102 @@
+package validation
+
+import "testing"
+
+func TestIsABARouting(t *testing.T) {
+  tests := []struct {
+    value string
+    want  bool
+  }{
+    {"021000021", true},
+    {"011000015", true},
+    {" 021000021 ", true},
+    {"021000022", false},
+    {"000000000", false},
+    {"02100002", false},
+    {"0210000210", false},
+    {"02100002A", false},
+    {"", false},
+  }
+  for _, tc := range tests {
+    if got := IsABARouting(tc.value); got != tc.want {
+      t.Errorf("IsABARouting(%q) = %v, want %v", tc.value, got, tc.want)
+    }
+  }
+}
+
+func TestIsIBAN(t *testing.T) {
+  tests := []struct {
+    value string
+    want  bool
+  }{
+    {"DE89370400440532013000", true},
+    {"GB82WEST12345698765432", true},
+    {"de89370400440532013000", true},
+    {"DE89 3704 0044 0532 0130 00", true},
+    {"gb82 west 1234 5698 7654 32", true},
+    {"DE88370400440532013000", false},
+    {"GB83WEST12345698765432", false},
+    {"DE8937040044053201300", false},
+    {"DE89370400440532013000X", false},
+    {"1234567", false},
+    {"", false},
+  }
+  for _, tc := range tests {
+    if got := IsIBAN(tc.value); got != tc.want {
+      t.Errorf("IsIBAN(%q) = %v, want %v", tc.value, got, tc.want)
+    }
+  }
+}
+
+func TestIsBIC(t *testing.T) {
+  tests := []struct {
+    value string
+    want  bool
+  }{
+    {"DEUTDEFF", true},
+    {"BARCGB22XXX", true},
+    {"deutdeff500", true},
+    {"CHAS US 33", true},
+    {"DEUTDEF", false},
+    {"DEUTDEFF5", false},
+    {"DEUTDEFF50", false},
+    {"DEUTDEFF5000", false},
+    {"1EUTDEFF", false},
+    {"DEUT12FF", false},
+    {"", false},
+  }
+  for _, tc := range tests {
+    if got := IsBIC(tc.value); got != tc.want {
+      t.Errorf("IsBIC(%q) = %v, want %v", tc.value, got, tc.want)
+    }
+  }
+}
+
+func TestCanadianRouting(t *testing.T) {
+  tests := []struct {
+    value string
+    want  string
+    ok    bool
+  }{
+    {"003-12345", "000312345", true},
+    {"12345-003", "000312345", true},
+    {"0003-12345", "000312345", true},
+    {"003 12345", "000312345", true},
+    {"00312345", "000312345", true},
+    {"000312345", "000312345", true},
+    {"000-12345", "", false},
+    {"003-00000", "", false},
+    {"000000000", "", false},
+    {"03-12345", "", false},
+    {"003-1234", "", false},
+    {"100312345", "", false},
+    {"ABC-12345", "", false},
+    {"", "", false},
+  }
+  for _, tc := range tests {
+    got, ok := CanadianRouting(tc.value)
+    if got != tc.want || ok != tc.ok {
+      t.Errorf("CanadianRouting(%q) = %q, %v; want %q, %v", tc.value, got, ok, tc.want, tc.ok)
+    }
+  }
+}
@@ -0,0 +1,99 @@
+package validation
+
+import (
+  "strings"
+)
+
+// Reason codes returned in FieldError.Code.
+const (
+  CodeRequired       = "required"
+  CodeInvalidValue   = "invalid_value"
+  CodeInvalidFormat  = "invalid_format"
+  CodeOutOfRange     = "out_of_range"
+  CodeUnsupported    = "unsupported_value"
+  CodeInvalidType    = "invalid_type"
+  CodeUnknownField   = "unknown_field"
+  CodeInvalidBIC     = "invalid_bic"
+  CodeInvalidIBAN    = "invalid_iban"
+  CodeInvalidRouting = "invalid_routing_number"
+  CodeInvalidAccount = "invalid_account_number"
+)
+
+// FieldError describes a single invalid request field.
+type FieldError struct {
+  Field   string `json:"field"`
+  Code    string `json:"code"`
+  Message string `json:"message"`
+  // Line and Column locate the error in the request body when known.
+  Line   int `json:"line,omitempty"`
+  Column int `json:"column,omitempty"`
+}
+
+// Errors is a list of field errors. A nil or empty list means the input is valid.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+  messages := make([]string, 0, len(e))
+  for _, fe := range e {
+    messages = append(messages, fe.Message)
+  }
+  return strings.Join(messages, "; ")
+}
+
+// Add appends a field error.
+func (e *Errors) Add(field string, code string, message string) {
+  *e = append(*e, FieldError{Field: field, Code: code, Message: message})
+}
+
+// Err returns nil when no errors were collected so callers can return it
+// directly as an error value.
+func (e Errors) Err() error {
+  if len(e) == 0 {
+    return nil
+  }
+  return e
+}
+
+// Beneficiary validates the payout account of an order. isoCode is the
+// catalogue's ISO 3166 code for the bank country, account is the order's
+// iban field (IBAN or domestic account number) and routing is the swift
+// field (SWIFT/BIC, ABA routing number or Canadian institution+transit).
+func Beneficiary(isoCode string, account string, routing string) Errors {
+  var errs Errors
+
+  isoCode = strings.ToUpper(isoCode)
+  switch isoCode {
+  case "US":
+    if !IsBIC(routing) && !IsABARouting(Normalize(routing)) {
+      errs.Add("swift", CodeInvalidRouting, "swift must be a SWIFT/BIC code or a 9 digit ABA routing number")
+    }
+    if !IsUSAccount(account) {
+      errs.Add("iban", CodeInvalidAccount, "iban must be a 4-17 digit account number for United States banks")
+    }
+  case "CA":
+    if _, ok := CanadianRouting(routing); !ok && !IsBIC(routing) {
+      errs.Add("swift", CodeInvalidRouting, "swift must be a SWIFT/BIC code or institution and transit numbers")
+    }
+    if !IsCanadianAccount(account) {
+      errs.Add("iban", CodeInvalidAccount, "iban must be a 7-12 digit account number for Canadian banks")
+    }
+  default:
+    if !IsBIC(routing) {
+      errs.Add("swift", CodeInvalidBIC, "swift must be an 8 or 11 character SWIFT/BIC code")
+    }
+    // Countries with a registered IBAN format only accept their own IBANs.
+    // Elsewhere a domestic account number is allowed.
+    _, ibanCountry := ibanLengths[isoCode]
+    iban := Normalize(account)
+    switch {
+    case ibanCountry && !LooksLikeIBAN(iban):
+      errs.Add("iban", CodeInvalidIBAN, "iban must be an IBAN for "+isoCode+" banks")
+    case LooksLikeIBAN(iban) && !IsIBAN(iban):
+      errs.Add("iban", CodeInvalidIBAN, "iban checksum is invalid")
+    case ibanCountry && iban[:2] != isoCode:
+      errs.Add("iban", CodeInvalidIBAN, "iban must be an IBAN for "+isoCode+" banks, not "+iban[:2])
+    }
+  }
+
+  return errs
+}
@@ -0,0 +1,78 @@
+package validation
+
+import (
+  "reflect"
+  "testing"
+)
+
+func TestBeneficiary(t *testing.T) {
+  tests := []struct {
+    name    string
+    country string
+    account string
+    routing string
+    want    Errors
+  }{
+    {name: "US with ABA", country: "US", account: "123456789", routing: "021000021"},
+    {name: "US with BIC", country: "US", account: "123456789", routing: "CHASUS33"},
+    {
+      name: "US bad routing and account", country: "US", account: "12", routing: "021000022",
+      want: Errors{
+        {Field: "swift", Code: CodeInvalidRouting, Message: "swift must be a SWIFT/BIC code or a 9 digit ABA routing number"},
+        {Field: "iban", Code: CodeInvalidAccount, Message: "iban must be a 4-17 digit account number for United States banks"},
+      },
+    },
+    {
+      name: "US zero routing", country: "US", account: "123456789", routing: "000000000",
+      want: Errors{{Field: "swift", Code: CodeInvalidRouting, Message: "swift must be a SWIFT/BIC code or a 9 digit ABA routing number"}},
+    },
+    {name: "Canada", country: "CA", account: "1234567", routing: "003-12345"},
+    {
+      name: "Canada zero transit", country: "CA", account: "1234567", routing: "003-00000",
+      want: Errors{{Field: "swift", Code: CodeInvalidRouting, Message: "swift must be a SWIFT/BIC code or institution and transit numbers"}},
+    },
+    {
+      name: "Canada short account", country: "CA", account: "123456", routing: "000312345",
+      want: Errors{{Field: "iban", Code: CodeInvalidAccount, Message: "iban must be a 7-12 digit account number for Canadian banks"}},
+    },
+    {name: "lowercase code", country: "us", account: "123456789", routing: "021000021"},
+    {name: "IBAN country", country: "DE", account: "DE89 3704 0044 0532 0130 00", routing: "DEUTDEFF"},
+    {
+      name: "IBAN with bad check digits", country: "DE", account: "DE88370400440532013000", routing: "DEUTDEFF",
+      want: Errors{{Field: "iban", Code: CodeInvalidIBAN, Message: "iban checksum is invalid"}},
+    },
+    {
+      name: "IBAN country with a domestic account", country: "DE", account: "123", routing: "DEUTDEFF",
+      want: Errors{{Field: "iban", Code: CodeInvalidIBAN, Message: "iban must be an IBAN for DE banks"}},
+    },
+    {
+      name: "IBAN from another country", country: "GB", account: "DE89370400440532013000", routing: "BARCGB22",
+      want: Errors{{Field: "iban", Code: CodeInvalidIBAN, Message: "iban must be an IBAN for GB banks, not DE"}},
+    },
+    {name: "non-IBAN country", country: "JP", account: "1234567", routing: "MHCBJPJT"},
+    {
+      name: "bad BIC", country: "DE", account: "DE89370400440532013000", routing: "DEUT",
+      want: Errors{{Field: "swift", Code: CodeInvalidBIC, Message: "swift must be an 8 or 11 character SWIFT/BIC code"}},
+    },
+  }
+  for _, tc := range tests {
+    t.Run(tc.name, func(t *testing.T) {
+      got := Beneficiary(tc.country, tc.account, tc.routing)
+      if !reflect.DeepEqual(got, tc.want) {
+        t.Errorf("Beneficiary = %#v, want %#v", got, tc.want)
+      }
+    })
+  }
+}
+
+func TestErrors(t *testing.T) {
+  var errs Errors
+  if errs.Err() != nil {
+    t.Fatal("empty Errors is an error")
+  }
+  errs.Add("email", CodeRequired, "email is required")
+  errs.Add("iban", CodeInvalidIBAN, "iban checksum is invalid")
+  if err := errs.Err(); err == nil || err.Error() != "email is required; iban checksum is invalid" {
+    t.Fatalf("Err() = %v", err)
+  }
+}